@@ -9,7 +9,6 @@ import (
 	geom "github.com/flywave/go-geom"
 	gen "github.com/flywave/go-geom/general"
 
-	"github.com/flywave/go-vector-tiler/maths/webmercator"
 	"github.com/flywave/go-vector-tiler/util"
 )
 
@@ -143,26 +142,14 @@ func CloneGeometry(geometry geom.Geometry) (geom.Geometry, error) {
 	}
 }
 
+// ToWebMercator 将SRID坐标系下的几何转换为Web墨卡托，WGS84和Web墨卡托以外的坐标系通过PROJ转换
 func ToWebMercator(SRID uint64, geometry geom.Geometry) (geom.Geometry, error) {
-	switch SRID {
-	default:
-		return nil, fmt.Errorf("don't know how to convert from %v to %v", util.WebMercator, SRID)
-	case util.WebMercator:
-		return CloneGeometry(geometry)
-	case util.WGS84:
-		return ApplyToPoints(geometry, webmercator.PToXY)
-	}
+	return Reproject(SRID, util.WebMercator, geometry)
 }
 
+// FromWebMercator 将Web墨卡托几何转换到SRID坐标系
 func FromWebMercator(SRID uint64, geometry geom.Geometry) (geom.Geometry, error) {
-	switch SRID {
-	default:
-		return nil, fmt.Errorf("don't know how to convert from %v to %v", SRID, util.WebMercator)
-	case util.WebMercator:
-		return CloneGeometry(geometry)
-	case util.WGS84:
-		return ApplyToPoints(geometry, webmercator.PToLonLat)
-	}
+	return Reproject(util.WebMercator, SRID, geometry)
 }
 
 func interfaceAsFloatslice(v interface{}) (vals []float64, err error) {
@@ -129,7 +129,7 @@ func unmarshalBasicGeometry(bgeo basicGeometry) (geo Geometry, err error) {
 		}
 		return mpoly, nil
 
-	case "GeometeryCollection":
+	case "GeometryCollection", "GeometeryCollection":
 		var c Collection
 		for _, basicgeo := range bgeo.Geometries {
 
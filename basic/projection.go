@@ -0,0 +1,126 @@
+package basic
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	geo "github.com/flywave/go-geo"
+	geom "github.com/flywave/go-geom"
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-vector-tiler/maths/webmercator"
+	"github.com/flywave/go-vector-tiler/util"
+)
+
+var (
+	// projDefinitions 通过RegisterProjection注册的PROJ定义
+	projDefinitions sync.Map // uint64 -> string
+	// projCache 按SRID缓存的投影，创建投影的开销较大
+	projCache sync.Map // uint64 -> geo.Proj
+)
+
+// RegisterProjection 为SRID注册PROJ定义(如"+proj=utm +zone=50 +datum=WGS84")
+// 未注册的SRID按EPSG代码处理。需要在使用该SRID之前注册，已缓存的投影会被替换
+func RegisterProjection(srid uint64, definition string) {
+	projDefinitions.Store(srid, definition)
+	projCache.Delete(srid)
+}
+
+// projection 返回SRID对应的投影
+func projection(srid uint64) (p geo.Proj, err error) {
+	if p, ok := projCache.Load(srid); ok {
+		return p.(geo.Proj), nil
+	}
+
+	code := fmt.Sprintf("EPSG:%d", srid)
+	if def, ok := projDefinitions.Load(srid); ok {
+		code = def.(string)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			p, err = nil, fmt.Errorf("unknown projection %v: %v", code, r)
+		}
+	}()
+	if p = geo.NewProj(code); p == nil {
+		return nil, fmt.Errorf("unknown projection %v", code)
+	}
+	actual, _ := projCache.LoadOrStore(srid, p)
+	return actual.(geo.Proj), nil
+}
+
+// CheckSRID 检查SRID是否可以与Web墨卡托互相转换
+func CheckSRID(srid uint64) error {
+	if srid == util.WebMercator || srid == util.WGS84 {
+		return nil
+	}
+	_, err := projection(srid)
+	return err
+}
+
+// transformer 返回from坐标系到to坐标系的坐标转换函数
+func transformer(from, to uint64) (func(coords ...float64) ([]float64, error), error) {
+	switch {
+	case from == to:
+		return func(coords ...float64) ([]float64, error) { return coords, nil }, nil
+	case from == util.WGS84 && to == util.WebMercator:
+		return webmercator.PToXY, nil
+	case from == util.WebMercator && to == util.WGS84:
+		return webmercator.PToLonLat, nil
+	}
+
+	src, err := projection(from)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := projection(to)
+	if err != nil {
+		return nil, err
+	}
+	return func(coords ...float64) ([]float64, error) {
+		if len(coords) < 2 {
+			return nil, fmt.Errorf("expected at least 2 coordinates, got %v", len(coords))
+		}
+		pts := src.TransformTo(dst, []vec2d.T{{coords[0], coords[1]}})
+		if len(pts) != 1 || math.IsInf(pts[0][0], 0) || math.IsNaN(pts[0][0]) ||
+			math.IsInf(pts[0][1], 0) || math.IsNaN(pts[0][1]) {
+			return nil, fmt.Errorf("failed to transform (%v, %v) from %v to %v", coords[0], coords[1], from, to)
+		}
+		return []float64{pts[0][0], pts[0][1]}, nil
+	}, nil
+}
+
+// Reproject 将几何从from坐标系转换到to坐标系
+func Reproject(from, to uint64, geometry geom.Geometry) (geom.Geometry, error) {
+	if from == to {
+		return CloneGeometry(geometry)
+	}
+	f, err := transformer(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("don't know how to convert from %v to %v: %w", from, to, err)
+	}
+	return ApplyToPoints(geometry, f)
+}
+
+// TransformPoint 将点从from坐标系转换到to坐标系
+func TransformPoint(from, to uint64, pt [2]float64) ([2]float64, error) {
+	f, err := transformer(from, to)
+	if err != nil {
+		return pt, err
+	}
+	c, err := f(pt[0], pt[1])
+	if err != nil {
+		return pt, err
+	}
+	return [2]float64{c[0], c[1]}, nil
+}
+
+// PointToWebMercator 将SRID坐标系下的点转换为Web墨卡托坐标
+func PointToWebMercator(srid uint64, pt [2]float64) ([2]float64, error) {
+	return TransformPoint(srid, util.WebMercator, pt)
+}
+
+// PointFromWebMercator 将Web墨卡托坐标转换为SRID坐标系下的点
+func PointFromWebMercator(srid uint64, pt [2]float64) ([2]float64, error) {
+	return TransformPoint(util.WebMercator, srid, pt)
+}
@@ -0,0 +1,96 @@
+package basic
+
+import (
+	"math"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+
+	"github.com/flywave/go-vector-tiler/maths/webmercator"
+)
+
+func TestPointToWebMercator(t *testing.T) {
+	pt := [2]float64{116.4, 39.9}
+	got, err := PointToWebMercator(4326, pt)
+	if err != nil {
+		t.Fatalf("PointToWebMercator() error = %v", err)
+	}
+	if want := [2]float64{webmercator.LonToX(pt[0]), webmercator.LatToY(pt[1])}; math.Abs(got[0]-want[0]) > 1e-6 || math.Abs(got[1]-want[1]) > 1e-6 {
+		t.Errorf("PointToWebMercator() = %v, want %v", got, want)
+	}
+	back, err := PointFromWebMercator(4326, got)
+	if err != nil || math.Abs(back[0]-pt[0]) > 1e-9 || math.Abs(back[1]-pt[1]) > 1e-9 {
+		t.Errorf("PointFromWebMercator() = %v, %v, want %v", back, err, pt)
+	}
+}
+
+func TestProjectionCache(t *testing.T) {
+	p1, err := projection(32650)
+	if err != nil {
+		t.Fatalf("projection() error = %v", err)
+	}
+	if p2, _ := projection(32650); p2 != p1 {
+		t.Error("projection() should return the cached projection")
+	}
+
+	RegisterProjection(32650, "+proj=utm +zone=50 +datum=WGS84 +units=m +no_defs")
+	defer RegisterProjection(32650, "EPSG:32650")
+	if p3, _ := projection(32650); p3 == p1 {
+		t.Error("RegisterProjection() should replace the cached projection")
+	}
+
+	if err := CheckSRID(9999); err == nil {
+		t.Error("CheckSRID(9999) should fail for an unknown EPSG code")
+	}
+}
+
+// TestTransformPoint_Projections checks known coordinates of non-WGS84 source SRIDs.
+// Expected values are computed from the projection formulas on the GRS80/WGS84 ellipsoids.
+func TestTransformPoint_Projections(t *testing.T) {
+	tests := []struct {
+		name      string
+		srid      uint64
+		lonLat    [2]float64
+		projected [2]float64
+		// tolerance for coordinates in srid
+		tolerance float64
+	}{
+		{"Lambert-93 origin", 2154, [2]float64{3, 46.5}, [2]float64{700000, 6600000}, 1e-2},
+		{"Lambert-93 Paris", 2154, [2]float64{2.3522219, 48.856614}, [2]float64{652470.6423, 6862036.8029}, 1e-2},
+		{"CGCS2000 Beijing", 4490, [2]float64{116.4, 39.9}, [2]float64{116.4, 39.9}, 1e-7},
+		{"UTM zone 50N Beijing", 32650, [2]float64{116.4, 39.9}, [2]float64{448709.3802, 4416830.5622}, 1e-2},
+	}
+
+	near := func(got, want [2]float64, tolerance float64) bool {
+		return math.Abs(got[0]-want[0]) <= tolerance && math.Abs(got[1]-want[1]) <= tolerance
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := TransformPoint(4326, tc.srid, tc.lonLat)
+			if err != nil || !near(got, tc.projected, tc.tolerance) {
+				t.Errorf("TransformPoint(4326, %v) = %v, %v, want %v", tc.srid, got, err, tc.projected)
+			}
+			back, err := TransformPoint(tc.srid, 4326, tc.projected)
+			if err != nil || !near(back, tc.lonLat, 1e-7) {
+				t.Errorf("TransformPoint(%v, 4326) = %v, %v, want %v", tc.srid, back, err, tc.lonLat)
+			}
+
+			mercator := [2]float64{webmercator.LonToX(tc.lonLat[0]), webmercator.LatToY(tc.lonLat[1])}
+			g, err := Reproject(tc.srid, 3857, Point(tc.projected))
+			if err != nil {
+				t.Fatalf("Reproject(%v, 3857) error = %v", tc.srid, err)
+			}
+			pt, ok := g.(geom.Point)
+			if !ok || !near([2]float64{pt.X(), pt.Y()}, mercator, 1e-2) {
+				t.Fatalf("Reproject(%v, 3857) = %v, want %v", tc.srid, g, mercator)
+			}
+			g, err = Reproject(3857, tc.srid, pt)
+			if err != nil {
+				t.Fatalf("Reproject(3857, %v) error = %v", tc.srid, err)
+			}
+			if pt, ok := g.(geom.Point); !ok || !near([2]float64{pt.X(), pt.Y()}, tc.projected, tc.tolerance) {
+				t.Errorf("Reproject(3857, %v) = %v, want %v", tc.srid, g, tc.projected)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package tile
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/maths/simplify"
+)
+
+// DropStrategy 瓦片超出预算时丢弃要素的顺序
+type DropStrategy int
+
+const (
+	// DropSmallestFirst 先丢弃尺寸最小的要素(面按面积，线按长度，点的尺寸为0)
+	DropSmallestFirst DropStrategy = iota
+	// DropDensestFirst 先丢弃顶点数最多的要素
+	DropDensestFirst
+	// DropByPriority 按Config.PriorityProperty属性值丢弃，值小的先丢弃，没有该属性的要素最先丢弃
+	DropByPriority
+)
+
+// budgetSimplifySteps 丢弃要素前尝试加大简化容差的次数，容差每次加倍
+const budgetSimplifySteps = 4
+
+// budgetTolerance 首次额外简化的容差，单位为4096范围下的像素
+const budgetTolerance = 1.0
+
+// mvtEncoder 可按MVT编码瓦片的导出器，用于计算瓦片大小
+type mvtEncoder interface {
+	GenerateMVT(layers []*Layer, tile *Tile) ([]byte, error)
+}
+
+// budgetResult 预算处理的结果
+type budgetResult struct {
+	// tolerance 额外简化使用的容差，0表示没有简化
+	tolerance float64
+	// dropped 丢弃的要素数
+	dropped int
+}
+
+// applied 是否对瓦片做了调整
+func (r budgetResult) applied() bool {
+	return r.tolerance > 0 || r.dropped > 0
+}
+
+// budgetEnabled 是否设置了瓦片预算
+func (m *Tiler) budgetEnabled() bool {
+	return m.config.MaxTileBytes > 0 || m.config.MaxFeaturesPerTile > 0
+}
+
+// budgetEncoder 返回计算瓦片大小的编码器，导出器不支持MVT编码时使用默认的MVT编码
+func (m *Tiler) budgetEncoder(t *Tile) mvtEncoder {
+	exporter, _ := m.tilePath(t)
+	if enc, ok := exporter.(mvtEncoder); ok {
+		return enc
+	}
+	return NewMVTExporter()
+}
+
+// fitBudget 调整瓦片内容使其满足MaxFeaturesPerTile和MaxTileBytes
+// 先按丢弃顺序截断到最大要素数，超出字节数时逐步加大简化容差，仍然超出时继续丢弃要素
+func (m *Tiler) fitBudget(t *Tile, layers []*Layer) ([]*Layer, budgetResult, error) {
+	var result budgetResult
+
+	ranked := m.rankFeatures(layers)
+	keep := len(ranked)
+	if max := m.config.MaxFeaturesPerTile; max > 0 && keep > max {
+		keep = max
+	}
+
+	if m.config.MaxTileBytes <= 0 {
+		result.dropped = len(ranked) - keep
+		return keepFeatures(layers, ranked, keep), result, nil
+	}
+
+	encoder := m.budgetEncoder(t)
+	fits := func(ls []*Layer) (bool, error) {
+		data, err := encoder.GenerateMVT(ls, t)
+		if err != nil {
+			return false, err
+		}
+		return len(data) <= m.config.MaxTileBytes, nil
+	}
+
+	current := keepFeatures(layers, ranked, keep)
+	ok, err := fits(current)
+	if err != nil {
+		return nil, result, err
+	}
+
+	// 加大简化容差
+	source := layers
+	for step := 0; !ok && step < budgetSimplifySteps; step++ {
+		result.tolerance = budgetTolerance * math.Pow(2, float64(step))
+		source = simplifyLayers(layers, result.tolerance)
+		current = keepFeatures(source, ranked, keep)
+		if ok, err = fits(current); err != nil {
+			return nil, result, err
+		}
+	}
+
+	// 二分查找满足预算的最大保留要素数
+	if !ok {
+		lo, hi := 0, keep-1
+		for lo < hi {
+			mid := (lo + hi + 1) / 2
+			fit, err := fits(keepFeatures(source, ranked, mid))
+			if err != nil {
+				return nil, result, err
+			}
+			if fit {
+				lo = mid
+			} else {
+				hi = mid - 1
+			}
+		}
+		keep = lo
+		current = keepFeatures(source, ranked, keep)
+	}
+
+	result.dropped = len(ranked) - keep
+	return current, result, nil
+}
+
+// featureRef 要素在图层列表中的位置
+type featureRef struct {
+	layer   int
+	feature int
+}
+
+// rankFeatures 按丢弃顺序排列所有要素，越靠前越先丢弃
+func (m *Tiler) rankFeatures(layers []*Layer) []featureRef {
+	var refs []featureRef
+	var weights []float64
+	for li, layer := range layers {
+		for fi, f := range layer.Features {
+			refs = append(refs, featureRef{layer: li, feature: fi})
+			weights = append(weights, m.keepWeight(f))
+		}
+	}
+
+	idx := make([]int, len(refs))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return weights[idx[i]] < weights[idx[j]]
+	})
+
+	ranked := make([]featureRef, len(refs))
+	for i, k := range idx {
+		ranked[i] = refs[k]
+	}
+	return ranked
+}
+
+// keepWeight 返回要素的保留权重，权重小的先丢弃
+func (m *Tiler) keepWeight(f *geom.Feature) float64 {
+	switch m.config.DropStrategy {
+	case DropDensestFirst:
+		return -float64(countVertices(f.Geometry))
+	case DropByPriority:
+		if v, ok := propertyAsFloat(f.Properties[m.config.PriorityProperty]); ok {
+			return v
+		}
+		return math.Inf(-1)
+	default:
+		return geometrySize(f.Geometry)
+	}
+}
+
+// keepFeatures 保留ranked中最后keep个要素，保持原有顺序并去掉空图层
+func keepFeatures(layers []*Layer, ranked []featureRef, keep int) []*Layer {
+	if keep >= len(ranked) {
+		return layers
+	}
+
+	dropped := make(map[featureRef]struct{}, len(ranked)-keep)
+	for _, ref := range ranked[:len(ranked)-keep] {
+		dropped[ref] = struct{}{}
+	}
+
+	var result []*Layer
+	for li, layer := range layers {
+		nl := &Layer{Name: layer.Name, Extent: layer.Extent}
+		for fi, f := range layer.Features {
+			if _, ok := dropped[featureRef{layer: li, feature: fi}]; !ok {
+				nl.Features = append(nl.Features, f)
+			}
+		}
+		if len(nl.Features) > 0 {
+			result = append(result, nl)
+		}
+	}
+	return result
+}
+
+// simplifyLayers 按容差简化所有要素，容差按图层范围缩放
+// 返回的图层与要素与原图层一一对应，不修改原图层
+func simplifyLayers(layers []*Layer, tolerance float64) []*Layer {
+	result := make([]*Layer, len(layers))
+	for li, layer := range layers {
+		tol := tolerance
+		if layer.Extent > 0 {
+			tol = tolerance * float64(layer.Extent) / 4096
+		}
+
+		nl := &Layer{Name: layer.Name, Extent: layer.Extent, Features: make([]*geom.Feature, len(layer.Features))}
+		for fi, f := range layer.Features {
+			nf := *f
+			if nf.Geometry != nil {
+				nf.Geometry = simplify.SimplifyGeometry(nf.Geometry, tol)
+			}
+			nl.Features[fi] = &nf
+		}
+		result[li] = nl
+	}
+	return result
+}
+
+// geometrySize 返回几何对象的尺寸，面为面积，线为长度，点为0
+func geometrySize(g geom.Geometry) float64 {
+	switch gg := g.(type) {
+	case geom.LineString:
+		return lineLength(gg.Data())
+	case geom.MultiLine:
+		var n float64
+		for _, l := range gg.Data() {
+			n += lineLength(l)
+		}
+		return n
+	case geom.Polygon:
+		return polygonArea(gg.Data())
+	case geom.MultiPolygon:
+		var n float64
+		for _, p := range gg.Data() {
+			n += polygonArea(p)
+		}
+		return n
+	case geom.Collection:
+		var n float64
+		for _, sub := range gg.Geometries() {
+			n += geometrySize(sub)
+		}
+		return n
+	}
+	return 0
+}
+
+// lineLength 计算线的长度
+func lineLength(pts [][]float64) float64 {
+	var n float64
+	for i := 1; i < len(pts); i++ {
+		n += math.Hypot(pts[i][0]-pts[i-1][0], pts[i][1]-pts[i-1][1])
+	}
+	return n
+}
+
+// polygonArea 计算多边形面积，外环面积减去内环面积
+func polygonArea(rings [][][]float64) float64 {
+	var n float64
+	for i, ring := range rings {
+		a := ringArea(ring)
+		if i == 0 {
+			n += a
+		} else {
+			n -= a
+		}
+	}
+	return math.Max(n, 0)
+}
+
+// ringArea 计算环的面积(绝对值)
+func ringArea(ring [][]float64) float64 {
+	return math.Abs(ringSignedArea(ring))
+}
+
+// budgetMessage 描述预算处理的结果
+func budgetMessage(task *tileTask, r budgetResult) string {
+	return fmt.Sprintf("瓦片 z=%d x=%d y=%d 超出预算: 简化容差=%g, 丢弃要素 %d 个",
+		task.z, task.x, task.y, r.tolerance, r.dropped)
+}
@@ -0,0 +1,195 @@
+package tile
+
+import (
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// sizingExporter 按顶点数计算编码大小的导出器，每个顶点10字节
+type sizingExporter struct {
+	MockExporter
+}
+
+func (e *sizingExporter) GenerateMVT(layers []*Layer, tile *Tile) ([]byte, error) {
+	n := 0
+	for _, l := range layers {
+		for _, f := range l.Features {
+			n += countVertices(f.Geometry)
+		}
+	}
+	return make([]byte, n*10), nil
+}
+
+// square 返回以(x, y)为左下角、边长为size的正方形
+func square(x, y, size float64) basic.Polygon {
+	return basic.Polygon{{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}}}
+}
+
+// featureNames 返回图层中所有要素的name属性
+func featureNames(layers []*Layer) []string {
+	var names []string
+	for _, l := range layers {
+		for _, f := range l.Features {
+			names = append(names, f.Properties["name"].(string))
+		}
+	}
+	return names
+}
+
+// TestTiler_fitBudget 测试瓦片预算的简化和丢弃
+func TestTiler_fitBudget(t *testing.T) {
+	named := func(name string, g geom.Geometry, props map[string]interface{}) *geom.Feature {
+		if props == nil {
+			props = map[string]interface{}{}
+		}
+		props["name"] = name
+		return &geom.Feature{Geometry: g, Properties: props}
+	}
+
+	// 近似直线的折线，简化后只剩两个端点
+	var zigzag basic.Line
+	for i := 0; i < 100; i++ {
+		zigzag = append(zigzag, basic.Point{float64(i * 10), float64(i%2) * 0.1})
+	}
+
+	testCases := []struct {
+		name      string
+		config    Config
+		layers    []*Layer
+		expected  []string
+		tolerance float64
+		dropped   int
+	}{
+		{
+			name:   "未超出预算",
+			config: Config{MaxTileBytes: 1000, MaxFeaturesPerTile: 10},
+			layers: []*Layer{{Name: "a", Extent: 4096, Features: []*geom.Feature{
+				named("p1", basic.Point{1, 1}, nil),
+				named("p2", basic.Point{2, 2}, nil),
+			}}},
+			expected: []string{"p1", "p2"},
+		},
+		{
+			name:   "按优先级截断要素数",
+			config: Config{MaxFeaturesPerTile: 2, DropStrategy: DropByPriority, PriorityProperty: "rank"},
+			layers: []*Layer{
+				{Name: "a", Extent: 4096, Features: []*geom.Feature{
+					named("low", basic.Point{1, 1}, map[string]interface{}{"rank": 1}),
+					named("none", basic.Point{2, 2}, nil),
+				}},
+				{Name: "b", Extent: 4096, Features: []*geom.Feature{
+					named("high", basic.Point{3, 3}, map[string]interface{}{"rank": 3}),
+				}},
+			},
+			expected: []string{"low", "high"},
+			dropped:  1,
+		},
+		{
+			name:   "简化后满足字节预算",
+			config: Config{MaxTileBytes: 100},
+			layers: []*Layer{{Name: "a", Extent: 4096, Features: []*geom.Feature{
+				named("line", zigzag, nil),
+			}}},
+			expected:  []string{"line"},
+			tolerance: 1,
+		},
+		{
+			name:   "简化后仍超出预算时丢弃最小的要素",
+			config: Config{MaxTileBytes: 50},
+			layers: []*Layer{{Name: "a", Extent: 4096, Features: []*geom.Feature{
+				named("small", square(0, 0, 10), nil),
+				named("large", square(100, 100, 500), nil),
+				named("medium", square(1000, 1000, 50), nil),
+			}}},
+			expected:  []string{"large"},
+			tolerance: 8,
+			dropped:   2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := tc.config
+			config.Exporter = &sizingExporter{}
+			tiler := NewTiler(&config)
+			defer tiler.Stop()
+
+			layers, result, err := tiler.fitBudget(NewTile(5, 0, 0), tc.layers)
+			if err != nil {
+				t.Fatalf("fitBudget() 错误 = %v", err)
+			}
+			names := featureNames(layers)
+			if len(names) != len(tc.expected) {
+				t.Fatalf("保留要素 = %v, want %v", names, tc.expected)
+			}
+			for i := range names {
+				if names[i] != tc.expected[i] {
+					t.Fatalf("保留要素 = %v, want %v", names, tc.expected)
+				}
+			}
+			if result.tolerance != tc.tolerance || result.dropped != tc.dropped {
+				t.Errorf("fitBudget() 结果 = %+v, want tolerance=%v dropped=%v",
+					result, tc.tolerance, tc.dropped)
+			}
+		})
+	}
+}
+
+// TestTiler_rankFeaturesDensest 测试按顶点数排列丢弃顺序
+func TestTiler_rankFeaturesDensest(t *testing.T) {
+	tiler := NewTiler(&Config{DropStrategy: DropDensestFirst})
+	defer tiler.Stop()
+
+	layers := []*Layer{{Name: "a", Features: []*geom.Feature{
+		{Geometry: basic.Point{0, 0}},
+		{Geometry: basic.Line{{0, 0}, {1, 1}, {2, 0}, {3, 1}}},
+		{Geometry: basic.Line{{0, 0}, {1, 1}}},
+	}}}
+
+	ranked := tiler.rankFeatures(layers)
+	expected := []int{1, 2, 0}
+	for i, ref := range ranked {
+		if ref.feature != expected[i] {
+			t.Fatalf("rankFeatures() = %v, want 要素顺序 %v", ranked, expected)
+		}
+	}
+}
+
+// TestTiler_processTileBudget 测试瓦片预算结果写入统计报告
+func TestTiler_processTileBudget(t *testing.T) {
+	var features []*geom.Feature
+	for i := 0; i < 5; i++ {
+		features = append(features, &geom.Feature{
+			Geometry:   basic.Point{0, 0},
+			Properties: map[string]interface{}{"rank": i},
+		})
+	}
+
+	exporter := &MockExporter{}
+	tiler := NewTiler(&Config{
+		Provider:           &MockProvider{layers: []*Layer{{Name: "points", Features: features}}, srid: 4326},
+		Exporter:           exporter,
+		MaxFeaturesPerTile: 3,
+		DropStrategy:       DropByPriority,
+		PriorityProperty:   "rank",
+	})
+	defer tiler.Stop()
+
+	tiler.processTile(&tileTask{z: 3, x: 0, y: 0})
+
+	saved := exporter.GetSavedTiles()
+	if len(saved) != 1 || len(saved[0].Layers) != 1 {
+		t.Fatalf("导出瓦片 = %+v, want 1个瓦片1个图层", saved)
+	}
+	kept := saved[0].Layers[0].Features
+	if len(kept) != 3 || kept[0].Properties["rank"] != 2 {
+		t.Errorf("保留要素数 = %v, 首个要素rank = %v, want 3个, rank 2", len(kept), kept[0].Properties["rank"])
+	}
+
+	s := tiler.Report().Zooms[3]
+	if s == nil || s.DroppedTiles != 1 || s.DroppedFeatures != 2 || s.SimplifiedTiles != 0 {
+		t.Errorf("z=3 统计 = %+v, want 1个丢弃瓦片, 2个丢弃要素", s)
+	}
+}
@@ -0,0 +1,202 @@
+package tile
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CheckpointFileName 断点文件名，位于OutputDir下
+const CheckpointFileName = ".tiler-checkpoint"
+
+// checkpointHeader 断点文件首行前缀，后接配置指纹
+const checkpointHeader = "# go-vector-tiler checkpoint "
+
+// checkpoint 记录已完成的瓦片任务，用于中断后继续生成
+// 文件首行为配置指纹，其后每行一个 "z/x/y"
+type checkpoint struct {
+	path string
+	file *os.File
+	done map[tileTask]struct{}
+	mu   sync.Mutex
+}
+
+// openCheckpoint 打开断点文件并加载已完成的任务
+// 文件不存在或指纹与当前配置不一致时重新创建
+func openCheckpoint(path string, fingerprint string) (*checkpoint, bool, error) {
+	done, err := loadCheckpoint(path, fingerprint)
+	if err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, errCheckpointMismatch) {
+		return nil, false, err
+	}
+	resumed := err == nil
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, false, fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	var file *os.File
+	if resumed {
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	} else {
+		done = make(map[tileTask]struct{})
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%s%s\n", checkpointHeader, fingerprint)
+		}
+	}
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return nil, false, fmt.Errorf("打开断点文件失败: %w", err)
+	}
+
+	return &checkpoint{path: path, file: file, done: done}, resumed, nil
+}
+
+// errCheckpointMismatch 断点文件与当前配置不匹配
+var errCheckpointMismatch = errors.New("断点文件与当前配置不匹配")
+
+// loadCheckpoint 读取断点文件中已完成的任务
+func loadCheckpoint(path string, fingerprint string) (map[tileTask]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() || scanner.Text() != checkpointHeader+fingerprint {
+		return nil, errCheckpointMismatch
+	}
+
+	done := make(map[tileTask]struct{})
+	for scanner.Scan() {
+		var task tileTask
+		// 进程中断时最后一行可能不完整，忽略无法解析的行
+		if _, err := fmt.Sscanf(scanner.Text(), "%d/%d/%d", &task.z, &task.x, &task.y); err != nil {
+			continue
+		}
+		done[task] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取断点文件失败: %w", err)
+	}
+	return done, nil
+}
+
+// Done 判断任务是否已经完成
+func (c *checkpoint) Done(task *tileTask) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.done[*task]
+	return ok
+}
+
+// Len 返回已完成的任务数
+func (c *checkpoint) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.done)
+}
+
+// Mark 记录任务完成
+func (c *checkpoint) Mark(task *tileTask) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.done[*task]; ok {
+		return nil
+	}
+	if _, err := fmt.Fprintf(c.file, "%d/%d/%d\n", task.z, task.x, task.y); err != nil {
+		return fmt.Errorf("写入断点文件失败: %w", err)
+	}
+	c.done[*task] = struct{}{}
+	return nil
+}
+
+// Close 关闭断点文件，保留记录以便下次继续
+func (c *checkpoint) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.Close()
+}
+
+// Remove 关闭并删除断点文件，在全部任务完成后调用
+func (c *checkpoint) Remove() error {
+	if err := c.Close(); err != nil {
+		return err
+	}
+	if c == nil {
+		return nil
+	}
+	return os.Remove(c.path)
+}
+
+// checkpointFingerprint 计算影响瓦片结果的配置指纹
+func (m *Tiler) checkpointFingerprint(zooms []int) string {
+	c := m.config
+	ext := ""
+	if c.Exporter != nil {
+		ext = c.Exporter.Extension()
+	}
+	parts := []string{
+		fmt.Sprintf("extent=%d", c.TileExtent),
+		fmt.Sprintf("buffer=%d", c.TileBuffer),
+		fmt.Sprintf("simplify=%t/%d", c.SimplifyGeometries, c.SimplificationMaxZoom),
+		fmt.Sprintf("zooms=%v", zooms),
+		fmt.Sprintf("bound=%v", *c.Bound),
+		fmt.Sprintf("srs=%s", c.SRS),
+		fmt.Sprintf("exporter=%s", ext),
+		fmt.Sprintf("overzoom=%d", c.OverzoomMaxZoom),
+		fmt.Sprintf("budget=%d/%d/%d/%s", c.MaxTileBytes, c.MaxFeaturesPerTile, c.DropStrategy, c.PriorityProperty),
+	}
+	if c.TileMatrixSet != nil {
+		parts = append(parts, fmt.Sprintf("grid=%s/%d/%d", c.TileMatrixSet.ID, c.TileMatrixSet.SRID, len(c.TileMatrixSet.Matrices)))
+	}
+
+	// 图层选项按名称排序，保证指纹稳定
+	names := make([]string, 0, len(c.LayerOptions))
+	for name := range c.LayerOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o := c.LayerOptions[name]
+		if o == nil {
+			continue
+		}
+		simplify := "inherit"
+		if o.Simplify != nil {
+			simplify = fmt.Sprint(*o.Simplify)
+		}
+		maxZoom := "none"
+		if o.MaxZoom != nil {
+			maxZoom = fmt.Sprint(*o.MaxZoom)
+		}
+		// 筛选函数无法比较，配置文件的筛选条件按内容计入
+		parts = append(parts, fmt.Sprintf("layer=%s:%d-%s/%d/%g/%s/%d/%t/%t/%#v",
+			name, o.MinZoom, maxZoom, o.Buffer, o.Tolerance, simplify, o.Extent, o.FeatureZoom != nil, o.Filter != nil, o.Filters))
+	}
+
+	sum := sha1.Sum([]byte(strings.Join(parts, ";")))
+	return hex.EncodeToString(sum[:])
+}
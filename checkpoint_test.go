@@ -0,0 +1,114 @@
+package tile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+func newCheckpointTestTiler(dir string, exporter *MockExporter, maxZoom int) *Tiler {
+	layer := &Layer{
+		Name:     "test_layer",
+		Features: []*geom.Feature{{Geometry: basic.Point{0, 0}}},
+	}
+	return NewTiler(&Config{
+		Provider:    &MockProvider{layers: []*Layer{layer}, srid: 4326},
+		Exporter:    exporter,
+		OutputDir:   dir,
+		MinZoom:     0,
+		MaxZoom:     maxZoom,
+		Bound:       &[4]float64{-1, -1, 1, 1},
+		Concurrency: 2,
+		Resume:      true,
+	})
+}
+
+// TestCheckpoint_Resume 测试从断点继续时跳过已完成的瓦片
+func TestCheckpoint_Resume(t *testing.T) {
+	dir := t.TempDir()
+	exporter := &MockExporter{}
+	tiler := newCheckpointTestTiler(dir, exporter, 1)
+
+	zooms := tiler.getZoomLevels()
+	path := filepath.Join(dir, CheckpointFileName)
+	content := fmt.Sprintf("%s%s\n0/0/0\n1/0/", checkpointHeader, tiler.checkpointFingerprint(zooms))
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	saved := exporter.GetSavedTiles()
+	for _, s := range saved {
+		if s.Tile.Z == 0 {
+			t.Errorf("已完成的瓦片 0/0/0 不应再次导出")
+		}
+	}
+	if want := int(tiler.count(zooms)) - 1; len(saved) != want {
+		t.Errorf("导出瓦片数量 = %v, want %v", len(saved), want)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("全部完成后断点文件应被删除, err = %v", err)
+	}
+}
+
+// TestCheckpoint_Mismatch 测试配置变化后断点失效
+func TestCheckpoint_Mismatch(t *testing.T) {
+	dir := t.TempDir()
+	exporter := &MockExporter{}
+	tiler := newCheckpointTestTiler(dir, exporter, 1)
+
+	path := filepath.Join(dir, CheckpointFileName)
+	content := checkpointHeader + "other\n0/0/0\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	if got, want := len(exporter.GetSavedTiles()), int(tiler.count(tiler.getZoomLevels())); got != want {
+		t.Errorf("导出瓦片数量 = %v, want %v", got, want)
+	}
+}
+
+// TestCheckpoint_FilterFingerprint 测试配置文件的筛选条件变化后断点指纹不同
+func TestCheckpoint_FilterFingerprint(t *testing.T) {
+	fingerprint := func(value interface{}) string {
+		tiler := newCheckpointTestTiler(t.TempDir(), &MockExporter{}, 1)
+		layer := &LayerConfig{Filter: []FeatureFilter{{Property: "kind", Value: value}}}
+		tiler.config.LayerOptions = map[string]*LayerOptions{"test_layer": layer.options()}
+		return tiler.checkpointFingerprint(tiler.getZoomLevels())
+	}
+	if fingerprint("road") != fingerprint("road") {
+		t.Error("相同筛选条件的指纹应相同")
+	}
+	if fingerprint("road") == fingerprint("rail") {
+		t.Error("筛选条件变化后指纹应不同")
+	}
+}
+
+// TestCheckpoint_Stopped 测试中途停止时保留断点文件
+func TestCheckpoint_Stopped(t *testing.T) {
+	dir := t.TempDir()
+	tiler := newCheckpointTestTiler(dir, &MockExporter{}, 10)
+	tiler.Stop()
+
+	if err := tiler.Tiler(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Tiler() 错误 = %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := loadCheckpoint(filepath.Join(dir, CheckpointFileName), tiler.checkpointFingerprint(tiler.getZoomLevels())); err != nil {
+		t.Errorf("停止后断点文件应保留, err = %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	tile "github.com/flywave/go-vector-tiler"
+)
+
+// runCount 统计范围内各级别的瓦片数
+func runCount(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("vtiler count", "vtiler count [选项]", stderr)
+	var r rangeFlags
+	r.register(fs)
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	config, err := r.config()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	tiler := tile.NewTiler(config)
+	defer tiler.Stop()
+
+	total := 0
+	for z := config.MinZoom; z <= config.MaxZoom; z++ {
+		n := tiler.Count([]uint32{uint32(z)})
+		total += n
+		fmt.Fprintf(stdout, "z%-2d %d\n", z, n)
+	}
+	fmt.Fprintf(stdout, "合计 %d\n", total)
+	return 0
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	tile "github.com/flywave/go-vector-tiler"
+)
+
+// runInspect 输出MVT瓦片中的图层、要素数和属性
+func runInspect(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("vtiler inspect", "vtiler inspect [选项] 瓦片文件", stderr)
+	features := fs.Bool("features", false, "输出每个要素的属性")
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	path := fs.Arg(0)
+	data, err := readTileFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	layers, err := tile.DecodeMVT(data, nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", path, err)
+		return 1
+	}
+
+	fields := make(map[string]map[string]string)
+	for _, v := range tile.VectorLayers(0, layers) {
+		fields[v.ID] = v.Fields
+	}
+
+	fmt.Fprintf(stdout, "%s: %d 字节, %d 个图层\n", path, len(data), len(layers))
+	for _, l := range layers {
+		fmt.Fprintf(stdout, "\n图层 %s: %d 个要素, 范围 %d\n", l.Name, len(l.Features), l.Extent)
+		if types := geometryTypes(l); types != "" {
+			fmt.Fprintf(stdout, "  几何: %s\n", types)
+		}
+		if f := fields[l.Name]; len(f) > 0 {
+			fmt.Fprintln(stdout, "  属性:")
+			for _, k := range sortedKeys(f) {
+				fmt.Fprintf(stdout, "    %s: %s\n", k, f[k])
+			}
+		}
+		if !*features {
+			continue
+		}
+		for i, f := range l.Features {
+			props := make([]string, 0, len(f.Properties))
+			for _, k := range sortedKeys(f.Properties) {
+				props = append(props, fmt.Sprintf("%s=%v", k, f.Properties[k]))
+			}
+			if f.ID != nil {
+				props = append([]string{fmt.Sprintf("id=%v", f.ID)}, props...)
+			}
+			fmt.Fprintf(stdout, "  [%d] %s\n", i, strings.Join(props, " "))
+		}
+	}
+	return 0
+}
+
+// readTileFile 读取瓦片文件，gzip压缩的瓦片自动解压
+func readTileFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		return data, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// geometryTypes 返回图层中各几何类型的要素数
+func geometryTypes(l *tile.Layer) string {
+	counts := make(map[string]int)
+	for _, f := range l.Features {
+		if f.Geometry != nil {
+			counts[f.Geometry.GetType()]++
+		}
+	}
+	res := make([]string, 0, len(counts))
+	for _, typ := range sortedKeys(counts) {
+		res = append(res, fmt.Sprintf("%s %d", typ, counts[typ]))
+	}
+	return strings.Join(res, ", ")
+}
+
+// sortedKeys 返回按字母排序的键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,257 @@
+// vtiler 矢量瓦片生成命令行工具
+//
+// 用法:
+//
+//	vtiler [选项] 输入文件或目录...   由GeoJSON/GeoJSONSeq数据生成瓦片
+//	vtiler -config 配置文件           按YAML/JSON配置文件生成瓦片
+//	vtiler inspect [选项] 瓦片文件    查看MVT瓦片中的图层、要素数和属性
+//	vtiler count [选项]               统计范围内各级别的瓦片数
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	tile "github.com/flywave/go-vector-tiler"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 执行命令并返回退出码，参数错误返回2，执行失败返回1
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "inspect":
+			return runInspect(args[1:], stdout, stderr)
+		case "count":
+			return runCount(args[1:], stdout, stderr)
+		case "tile":
+			args = args[1:]
+		}
+	}
+	return runTile(args, stdout, stderr)
+}
+
+// rangeFlags 生成和统计共用的级别和范围参数
+type rangeFlags struct {
+	minZoom int
+	maxZoom int
+	bbox    string
+}
+
+// register 注册级别和范围参数
+func (r *rangeFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&r.minZoom, "minzoom", tile.DefaultConfig.MinZoom, "最小级别")
+	fs.IntVar(&r.maxZoom, "maxzoom", tile.DefaultConfig.MaxZoom, "最大级别")
+	b := tile.DefaultConfig.Bound
+	fs.StringVar(&r.bbox, "bbox", fmt.Sprintf("%g,%g,%g,%g", b[0], b[1], b[2], b[3]), "范围(WGS84): minx,miny,maxx,maxy")
+}
+
+// config 返回只包含级别和范围的配置
+func (r *rangeFlags) config() (*tile.Config, error) {
+	if r.minZoom < 0 || r.maxZoom < r.minZoom {
+		return nil, fmt.Errorf("无效的级别范围: %d-%d", r.minZoom, r.maxZoom)
+	}
+	bound, err := parseBBox(r.bbox)
+	if err != nil {
+		return nil, err
+	}
+	return &tile.Config{MinZoom: r.minZoom, MaxZoom: r.maxZoom, Bound: bound}, nil
+}
+
+// parseBBox 解析minx,miny,maxx,maxy形式的范围
+func parseBBox(s string) (*[4]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("无效的范围 %q: 需要minx,miny,maxx,maxy", s)
+	}
+	var bound [4]float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的范围 %q: %v", s, err)
+		}
+		bound[i] = v
+	}
+	if bound[0] >= bound[2] || bound[1] >= bound[3] {
+		return nil, fmt.Errorf("无效的范围 %q: 最小值需小于最大值", s)
+	}
+	return &bound, nil
+}
+
+// newExporter 返回输出格式对应的导出器
+func newExporter(format string) (tile.Exporter, error) {
+	switch strings.ToLower(format) {
+	case "mvt", "pbf":
+		return tile.NewMVTExporter(), nil
+	case "geojson", "json":
+		return tile.NewGeoJSONExporter(), nil
+	case "svg":
+		return tile.NewSVGExporter(), nil
+	}
+	return nil, fmt.Errorf("不支持的输出格式: %s", format)
+}
+
+// newFlagSet 创建子命令的参数集，usage为参数之前的用法说明
+func newFlagSet(name, usage string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "用法: %s\n\n选项:\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags 解析参数，返回非负退出码时命令应直接退出
+func parseFlags(fs *flag.FlagSet, args []string) int {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	return -1
+}
+
+// runTile 由GeoJSON数据生成瓦片
+func runTile(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("vtiler", "vtiler [选项] 输入文件或目录...", stderr)
+	var r rangeFlags
+	r.register(fs)
+	output := fs.String("o", tile.DefaultConfig.OutputDir, "输出目录")
+	format := fs.String("format", "mvt", "输出格式: mvt、geojson或svg")
+	extent := fs.Uint64("extent", tile.DefaultConfig.TileExtent, "瓦片范围")
+	buffer := fs.Uint64("buffer", tile.DefaultConfig.TileBuffer, "瓦片缓冲区")
+	simplify := fs.Bool("simplify", tile.DefaultConfig.SimplifyGeometries, "简化几何")
+	simplifyMaxZoom := fs.Uint("simplify-maxzoom", tile.DefaultConfig.SimplificationMaxZoom, "小于该级别时简化几何")
+	concurrency := fs.Int("concurrency", tile.DefaultConfig.Concurrency, "并发数")
+	layerProperty := fs.String("layer-property", "", "以要素的该属性值作为图层名，为空时使用文件名")
+	srid := fs.Uint64("srid", tile.DefaultGeoJSONProviderOptions.SRID, "输入坐标的空间参考: 4326或3857")
+	resume := fs.Bool("resume", false, "断点续传")
+	tileJSON := fs.Bool("tilejson", false, "在输出目录写入tiles.json")
+	report := fs.String("report", "", "生成完成后将统计报告写入该JSON文件")
+	configFile := fs.String("config", "", "YAML或JSON配置文件，设置后忽略输入文件和其他生成选项")
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	if *configFile != "" {
+		return runConfig(*configFile, *report, stdout, stderr)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	config, err := r.config()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	exporter, err := newExporter(*format)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	provider, err := tile.NewGeoJSONProviderWithOptions(tile.GeoJSONProviderOptions{
+		LayerProperty: *layerProperty,
+		SRID:          *srid,
+	}, fs.Args()...)
+	if err != nil {
+		fmt.Fprintf(stderr, "读取输入失败: %v\n", err)
+		return 1
+	}
+
+	config.Provider = provider
+	config.Progress = tile.NewDefaultProgress()
+	config.Exporter = exporter
+	config.OutputDir = *output
+	config.TileExtent = *extent
+	config.TileBuffer = *buffer
+	config.SimplifyGeometries = *simplify
+	config.SimplificationMaxZoom = *simplifyMaxZoom
+	config.Concurrency = *concurrency
+	config.Resume = *resume
+	if *tileJSON {
+		config.TileJSON = &tile.TileJSONOptions{}
+	}
+	return generate(config, *report, stdout, stderr)
+}
+
+// runConfig 按配置文件生成瓦片
+func runConfig(path, report string, stdout, stderr io.Writer) int {
+	cf, err := tile.LoadConfigFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	config, err := cf.Config()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if closer, ok := config.Provider.(io.Closer); ok {
+		defer closer.Close()
+	}
+	config.Progress = tile.NewDefaultProgress()
+	return generate(config, report, stdout, stderr)
+}
+
+// generate 生成瓦片，report不为空时写入统计报告
+func generate(config *tile.Config, report string, stdout, stderr io.Writer) int {
+	tiler := tile.NewTiler(config)
+
+	// 中断时停止生成，启用断点续传时可继续
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(signals)
+		close(signals)
+	}()
+	go func() {
+		if _, ok := <-signals; ok {
+			tiler.Stop()
+		}
+	}()
+
+	err := tiler.Tiler()
+	stats := tiler.Report()
+	if report != "" {
+		if werr := writeReport(report, stats); werr != nil {
+			fmt.Fprintf(stderr, "写入统计报告失败: %v\n", werr)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	var tiles, empty int64
+	for _, z := range stats.Zooms {
+		tiles += z.Tiles
+		empty += z.EmptyTiles
+	}
+	fmt.Fprintf(stdout, "已导出 %d 个瓦片，跳过 %d 个空瓦片，输出目录: %s\n", tiles, empty, config.OutputDir)
+	return 0
+}
+
+// writeReport 将统计报告写入JSON文件
+func writeReport(path string, report *tile.TilingReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
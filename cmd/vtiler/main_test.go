@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runArgs 执行命令并返回退出码和输出
+func runArgs(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// TestParseBBox 测试范围参数解析
+func TestParseBBox(t *testing.T) {
+	b, err := parseBBox("116, 39.5,117,40.5")
+	if err != nil || *b != [4]float64{116, 39.5, 117, 40.5} {
+		t.Errorf("parseBBox() = %v, %v", b, err)
+	}
+	for _, s := range []string{"", "1,2,3", "a,2,3,4", "117,39,116,40"} {
+		if _, err := parseBBox(s); err == nil {
+			t.Errorf("parseBBox(%q) 应返回错误", s)
+		}
+	}
+}
+
+// TestCount 测试统计瓦片数
+func TestCount(t *testing.T) {
+	code, out, errOut := runArgs("count", "-minzoom", "0", "-maxzoom", "2")
+	if code != 0 {
+		t.Fatalf("退出码 = %d, 错误输出: %s", code, errOut)
+	}
+	for _, want := range []string{"z0  1\n", "z1  4\n", "z2  16\n", "合计 21\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("输出缺少 %q:\n%s", want, out)
+		}
+	}
+
+	if code, _, _ := runArgs("count", "-minzoom", "3", "-maxzoom", "1"); code != 2 {
+		t.Errorf("无效级别范围退出码 = %d, want 2", code)
+	}
+}
+
+// TestTileAndInspect 测试由GeoJSON生成瓦片并查看瓦片
+func TestTileAndInspect(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "roads.geojson")
+	data := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{"name":"长安街","lanes":8},"geometry":{"type":"LineString","coordinates":[[116.30,39.90],[116.50,39.91]]}}
+	]}`
+	if err := os.WriteFile(input, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	code, out, errOut := runArgs("-o", dir, "-minzoom", "1", "-maxzoom", "3", "-bbox", "116,39,117,40",
+		"-extent", "4096", "-concurrency", "2", input)
+	if code != 0 {
+		t.Fatalf("退出码 = %d, 错误输出: %s", code, errOut)
+	}
+	if !strings.Contains(out, "已导出") {
+		t.Errorf("输出缺少统计:\n%s", out)
+	}
+
+	tiles, err := filepath.Glob(filepath.Join(dir, "3", "*", "*.mvt"))
+	if err != nil || len(tiles) == 0 {
+		t.Fatalf("级别3没有瓦片: %v", err)
+	}
+	code, out, errOut = runArgs("inspect", "-features", tiles[0])
+	if code != 0 {
+		t.Fatalf("inspect退出码 = %d, 错误输出: %s", code, errOut)
+	}
+	if !strings.HasPrefix(out, tiles[0]+": ") || !strings.Contains(out, "个图层") {
+		t.Errorf("inspect输出:\n%s", out)
+	}
+
+	if code, _, _ := runArgs("inspect", filepath.Join(dir, "missing.mvt")); code != 1 {
+		t.Errorf("瓦片不存在退出码 = %d, want 1", code)
+	}
+}
+
+// TestTileConfig 测试按配置文件生成瓦片
+func TestTileConfig(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{"name":"长安街"},"geometry":{"type":"LineString","coordinates":[[116.30,39.90],[116.50,39.91]]}}
+	]}`
+	if err := os.WriteFile(filepath.Join(dir, "roads.geojson"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := `providers:
+  - type: geojson
+    paths: [roads.geojson]
+grid:
+  bounds: [116, 39, 117, 40]
+minzoom: 1
+maxzoom: 3
+output:
+  format: geojson
+  path: tiles
+`
+	path := filepath.Join(dir, "tiles.yaml")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, errOut := runArgs("-config", path)
+	if code != 0 {
+		t.Fatalf("退出码 = %d, 错误输出: %s", code, errOut)
+	}
+	if !strings.Contains(out, filepath.Join(dir, "tiles")) {
+		t.Errorf("输出缺少输出目录:\n%s", out)
+	}
+	if tiles, _ := filepath.Glob(filepath.Join(dir, "tiles", "3", "*", "*.geojson")); len(tiles) == 0 {
+		t.Error("级别3没有瓦片")
+	}
+
+	if err := os.WriteFile(path, []byte("providers: []\nmaxzoom: high\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, _, errOut = runArgs("-config", path)
+	if code != 2 || !strings.Contains(errOut, path+":1:") || !strings.Contains(errOut, path+":2:") {
+		t.Errorf("无效配置退出码 = %d, 错误输出: %s", code, errOut)
+	}
+}
+
+// TestTile_InvalidArgs 测试无效参数
+func TestTile_InvalidArgs(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"-format", "png", "input.geojson"},
+		{"-bbox", "1,2,3", "input.geojson"},
+		{"-unknown"},
+	}
+	for _, args := range tests {
+		if code, _, _ := runArgs(args...); code != 2 {
+			t.Errorf("run(%q) 退出码 = %d, want 2", args, code)
+		}
+	}
+	if code, _, _ := runArgs("-h"); code != 0 {
+		t.Errorf("-h 退出码 = %d, want 0", code)
+	}
+	if code, _, _ := runArgs(filepath.Join(t.TempDir(), "missing.geojson")); code != 1 {
+		t.Errorf("输入不存在退出码 = %d, want 1", code)
+	}
+}
@@ -16,8 +16,53 @@ type Config struct {
 	SRS                   string
 	Exporter              Exporter
 	OutputDir             string
+	// StreamingProvider 只实现StreamingProvider的数据源，设置时优先于Provider使用
+	StreamingProvider StreamingProvider
+	// Resume 启用断点续传，已完成的瓦片记录在OutputDir下的断点文件中，
+	// 使用相同配置再次运行时跳过这些瓦片
+	Resume bool
+	// ErrorPolicy 错误处理策略(默认ErrorPolicyFailFast)
+	ErrorPolicy ErrorPolicy
+	// LayerOptions 按图层名设置的处理选项
+	LayerOptions map[string]*LayerOptions
+	// MaxTileBytes 瓦片MVT编码后的最大字节数，超出时先加大简化容差再丢弃要素，0表示不限制
+	MaxTileBytes int
+	// MaxFeaturesPerTile 单个瓦片的最大要素数，0表示不限制
+	MaxFeaturesPerTile int
+	// DropStrategy 超出预算时丢弃要素的顺序(默认DropSmallestFirst)
+	DropStrategy DropStrategy
+	// PriorityProperty DropByPriority策略使用的属性名
+	PriorityProperty string
+	// OverzoomMaxZoom 大于最大级别时，继续生成到该级别。这些瓦片由最大级别瓦片处理后的要素
+	// 裁剪缩放得到，不再查询Provider；0表示不生成
+	OverzoomMaxZoom int
+	// BottomUp 自底向上生成，只在最大级别查询Provider，
+	// 更低级别的瓦片由四个子瓦片处理后的要素合并生成，不支持Resume。
+	// 为使合并生成的瓦片仍有完整的缓冲区，最大级别按2^(MaxZoom-MinZoom)倍的缓冲区查询和处理，
+	// 级别跨度大时读取的数据量随之增加。
+	// 最大级别瓦片出错被放弃时，包含它的低级别瓦片也不导出
+	BottomUp bool
+	// TileMatrixSet 瓦片网格，为nil时使用Web墨卡托网格(WebMercatorQuad)。
+	// 使用其他网格时要素转换到网格坐标系后生成瓦片，SRS需为WGS84_PROJ4、GMERC_PROJ4或"EPSG:代码"，
+	// BottomUp和超级别生成要求网格为四叉树
+	TileMatrixSet *TileMatrixSet
+	// TileJSON 不为nil时，生成完成后在OutputDir下写入TileJSON文件(tiles.json)；
+	// 启用Resume时未完成也会写入，以便继续生成时合并已导出瓦片中的图层
+	TileJSON *TileJSONOptions
 }
 
+// ErrorPolicy 瓦片处理出错时的策略
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyFailFast 遇到第一个错误即停止生成
+	ErrorPolicyFailFast ErrorPolicy = iota
+	// ErrorPolicySkipFeature 跳过出错的要素，继续处理其他要素和瓦片
+	ErrorPolicySkipFeature
+	// ErrorPolicySkipTile 跳过出错的瓦片，继续处理其他瓦片
+	ErrorPolicySkipTile
+)
+
 // DefaultConfig 默认配置
 var DefaultConfig = Config{
 	TileExtent:            32768,
@@ -0,0 +1,480 @@
+package tile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	geom "github.com/flywave/go-geom"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile 声明式瓦片生成配置，由YAML或JSON文件读取
+// 字段名与YAML键一致，未设置的字段使用DefaultConfig中的默认值。
+// 文件中的相对路径相对于配置文件所在目录
+type ConfigFile struct {
+	// Providers 数据源，多个数据源按图层合并
+	Providers []ProviderConfig `yaml:"providers"`
+	// Layers 按图层名设置的级别范围、筛选条件和处理参数
+	Layers map[string]*LayerConfig `yaml:"layers"`
+	// Grid 瓦片网格和生成范围
+	Grid GridConfig `yaml:"grid"`
+	// MinZoom和MaxZoom 生成的级别范围
+	MinZoom int `yaml:"minzoom"`
+	MaxZoom int `yaml:"maxzoom"`
+	// Extent 瓦片范围
+	Extent uint64 `yaml:"extent"`
+	// Buffer 瓦片缓冲区
+	Buffer uint64 `yaml:"buffer"`
+	// Simplify 几何简化
+	Simplify SimplifyConfig `yaml:"simplify"`
+	// Concurrency 并发数
+	Concurrency int `yaml:"concurrency"`
+	// Output 输出格式和位置
+	Output OutputConfig `yaml:"output"`
+
+	// file 配置文件路径，用于错误信息
+	file string
+	// dir 相对路径的基准目录
+	dir string
+	// lines 字段路径到行列号的映射
+	lines map[string][2]int
+}
+
+// ProviderConfig 数据源配置
+type ProviderConfig struct {
+	// Type 数据源类型: geojson、shapefile、flatgeobuf或tileset
+	Type string `yaml:"type"`
+	// Paths 数据文件或目录
+	Paths []string `yaml:"paths"`
+	// SRID 坐标的空间参考(4326或3857)，为0时使用数据源的默认值，tileset不支持
+	SRID uint64 `yaml:"srid"`
+	// LayerProperty geojson: 以要素的该属性值作为图层名
+	LayerProperty string `yaml:"layer_property"`
+	// LayerName shapefile和flatgeobuf: 图层名
+	LayerName string `yaml:"layer_name"`
+	// Encoding shapefile: DBF属性的字符编码
+	Encoding string `yaml:"encoding"`
+	// Layers tileset: 只读取这些图层
+	Layers []string `yaml:"layers"`
+	// Extension tileset: 瓦片文件扩展名
+	Extension string `yaml:"extension"`
+}
+
+// LayerConfig 图层配置，对应LayerOptions
+type LayerConfig struct {
+	MinZoom   int     `yaml:"minzoom"`
+	MaxZoom   *int    `yaml:"maxzoom"`
+	Buffer    uint64  `yaml:"buffer"`
+	Extent    uint64  `yaml:"extent"`
+	Tolerance float64 `yaml:"tolerance"`
+	Simplify  *bool   `yaml:"simplify"`
+	// Filter 要素须满足的全部条件
+	Filter []FeatureFilter `yaml:"filter"`
+}
+
+// GridConfig 瓦片网格配置
+type GridConfig struct {
+	// TileMatrixSet WebMercatorQuad(默认)、WorldCRS84Quad或OGC TileMatrixSet JSON文件路径
+	TileMatrixSet string `yaml:"tile_matrix_set"`
+	// Bounds 生成范围[minx, miny, maxx, maxy]，坐标系由SRS确定
+	Bounds *[4]float64 `yaml:"bounds"`
+	// SRS Bounds的坐标系，为空时为WGS84
+	SRS string `yaml:"srs"`
+}
+
+// SimplifyConfig 几何简化配置
+type SimplifyConfig struct {
+	// Enabled 是否简化几何(默认true)
+	Enabled bool `yaml:"enabled"`
+	// MaxZoom 小于该级别时简化几何
+	MaxZoom uint `yaml:"maxzoom"`
+}
+
+// OutputConfig 输出配置
+type OutputConfig struct {
+	// Format 输出格式: mvt、geojson、svg、mbtiles或pmtiles(默认mvt)
+	Format string `yaml:"format"`
+	// Path 输出目录，mbtiles和pmtiles为输出文件
+	Path string `yaml:"path"`
+	// Gzip mbtiles和pmtiles: 是否gzip压缩瓦片，为nil时使用导出器的默认值
+	Gzip *bool `yaml:"gzip"`
+	// Resume 断点续传
+	Resume bool `yaml:"resume"`
+	// TileJSON 不为nil时写入TileJSON，其中的名称、描述和版权信息同时用于mbtiles和pmtiles的元数据
+	TileJSON *TileJSONOptions `yaml:"tilejson"`
+}
+
+// FeatureFilter 按属性筛选要素的条件
+type FeatureFilter struct {
+	// Property 属性名
+	Property string `yaml:"property"`
+	// Op 比较运算(默认==)，见filterOps
+	Op string `yaml:"op"`
+	// Value 比较值，in和not_in为数组，has和not_has不使用
+	Value interface{} `yaml:"value"`
+}
+
+// filterOps 筛选条件支持的比较运算
+var filterOps = []string{"==", "!=", "<", "<=", ">", ">=", "in", "not_in", "has", "not_has"}
+
+// outputFormats 输出格式对应的导出器，path为输出位置
+var outputFormats = map[string]func(path string, c *OutputConfig) (Exporter, error){
+	"mvt":     func(string, *OutputConfig) (Exporter, error) { return NewMVTExporter(), nil },
+	"geojson": func(string, *OutputConfig) (Exporter, error) { return NewGeoJSONExporter(), nil },
+	"svg":     func(string, *OutputConfig) (Exporter, error) { return NewSVGExporter(), nil },
+	"mbtiles": func(path string, c *OutputConfig) (Exporter, error) {
+		options := DefaultMBTilesOptions
+		if c.Gzip != nil {
+			options.Gzip = *c.Gzip
+		}
+		if t := c.TileJSON; t != nil {
+			options.Name, options.Description, options.Attribution = t.Name, t.Description, t.Attribution
+		}
+		return NewMBTilesExporterWithOptions(path, options)
+	},
+	"pmtiles": func(path string, c *OutputConfig) (Exporter, error) {
+		options := DefaultPMTilesOptions
+		if c.Gzip != nil {
+			options.Gzip = *c.Gzip
+		}
+		if t := c.TileJSON; t != nil {
+			options.Name, options.Description, options.Attribution = t.Name, t.Description, t.Attribution
+		}
+		return NewPMTilesExporterWithOptions(path, options)
+	},
+}
+
+// archiveFormats 输出到单个文件的格式
+var archiveFormats = map[string]bool{"mbtiles": true, "pmtiles": true}
+
+// LoadConfigFile 读取并检查YAML或JSON配置文件
+// 不符合约束时返回ConfigErrors，其中每个错误带有行列号
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	return parseConfigFile(data, path, filepath.Dir(path))
+}
+
+// ParseConfigFile 检查并解析YAML或JSON配置数据，相对路径相对于当前目录
+func ParseConfigFile(data []byte) (*ConfigFile, error) {
+	return parseConfigFile(data, "", "")
+}
+
+// yamlErrorLine 匹配YAML语法错误中的行号
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// parseConfigFile 解析配置，按configFileSchema检查后解码
+func parseConfigFile(data []byte, file, dir string) (*ConfigFile, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		e := &ConfigError{File: file, Msg: err.Error()}
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			e.Line, _ = strconv.Atoi(m[1])
+			e.Msg = m[2]
+		}
+		return nil, ConfigErrors{e}
+	}
+	if len(doc.Content) == 0 {
+		return nil, ConfigErrors{{File: file, Msg: "配置为空"}}
+	}
+
+	root := doc.Content[0]
+	v := &schemaValidator{file: file, lines: make(map[string][2]int)}
+	v.validate(root, configFileSchema, "")
+	if len(v.errs) > 0 {
+		return nil, v.errs
+	}
+
+	c := &ConfigFile{
+		MaxZoom:  DefaultConfig.MaxZoom,
+		Simplify: SimplifyConfig{Enabled: DefaultConfig.SimplifyGeometries},
+		Output:   OutputConfig{Format: "mvt", Path: DefaultConfig.OutputDir},
+		file:     file,
+		dir:      dir,
+		lines:    v.lines,
+	}
+	if err := root.Decode(c); err != nil {
+		return nil, ConfigErrors{{File: file, Msg: "解码配置失败", Err: err}}
+	}
+	return c, nil
+}
+
+// errorAt 返回字段处的错误
+func (c *ConfigFile) errorAt(field string, err error) *ConfigError {
+	pos := c.lines[field]
+	return &ConfigError{File: c.file, Line: pos[0], Column: pos[1], Field: field, Err: err}
+}
+
+// path 返回相对于配置文件目录的路径
+func (c *ConfigFile) path(p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(c.dir, p)
+}
+
+// Config 创建配置文件对应的Config，打开数据源并创建导出器
+// 数据源实现io.Closer时由调用者在生成结束后关闭
+func (c *ConfigFile) Config() (*Config, error) {
+	matrixSet, err := c.tileMatrixSet()
+	if err != nil {
+		return nil, err
+	}
+	provider, err := c.provider()
+	if err != nil {
+		return nil, err
+	}
+	exporter, outputDir, err := c.exporter()
+	if err != nil {
+		if closer, ok := provider.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, err
+	}
+
+	config := &Config{
+		Provider:              provider,
+		TileExtent:            c.Extent,
+		TileBuffer:            c.Buffer,
+		SimplifyGeometries:    c.Simplify.Enabled,
+		SimplificationMaxZoom: c.Simplify.MaxZoom,
+		Concurrency:           c.Concurrency,
+		MinZoom:               c.MinZoom,
+		MaxZoom:               c.MaxZoom,
+		Bound:                 c.Grid.Bounds,
+		SRS:                   c.Grid.SRS,
+		Exporter:              exporter,
+		OutputDir:             outputDir,
+		Resume:                c.Output.Resume,
+		TileMatrixSet:         matrixSet,
+		TileJSON:              c.Output.TileJSON,
+	}
+	if len(c.Layers) > 0 {
+		config.LayerOptions = make(map[string]*LayerOptions, len(c.Layers))
+		for name, l := range c.Layers {
+			config.LayerOptions[name] = l.options()
+		}
+	}
+	return config, nil
+}
+
+// NewTiler 创建配置文件对应的Tiler
+func (c *ConfigFile) NewTiler() (*Tiler, error) {
+	config, err := c.Config()
+	if err != nil {
+		return nil, err
+	}
+	return NewTiler(config), nil
+}
+
+// tileMatrixSet 返回配置的瓦片网格，Web墨卡托网格返回nil
+func (c *ConfigFile) tileMatrixSet() (*TileMatrixSet, error) {
+	switch name := c.Grid.TileMatrixSet; name {
+	case "", WebMercatorQuad.ID:
+		return nil, nil
+	case WorldCRS84Quad.ID:
+		return WorldCRS84Quad, nil
+	default:
+		set, err := LoadTileMatrixSet(c.path(name))
+		if err != nil {
+			return nil, c.errorAt("grid.tile_matrix_set", err)
+		}
+		return set, nil
+	}
+}
+
+// provider 打开全部数据源，多个数据源时按图层合并
+func (c *ConfigFile) provider() (Provider, error) {
+	var providers []Provider
+	closeAll := func() {
+		for _, p := range providers {
+			if closer, ok := p.(io.Closer); ok {
+				closer.Close()
+			}
+		}
+	}
+	for i, pc := range c.Providers {
+		paths := make([]string, len(pc.Paths))
+		for j, p := range pc.Paths {
+			paths[j] = c.path(p)
+		}
+		opened, err := pc.open(paths)
+		if err != nil {
+			closeAll()
+			return nil, c.errorAt(fmt.Sprintf("providers[%d]", i), err)
+		}
+		providers = append(providers, opened...)
+	}
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return NewMultiProvider(providers...)
+}
+
+// open 打开数据源，flatgeobuf每个文件为一个Provider
+func (pc *ProviderConfig) open(paths []string) ([]Provider, error) {
+	switch pc.Type {
+	case "geojson":
+		p, err := NewGeoJSONProviderWithOptions(GeoJSONProviderOptions{LayerProperty: pc.LayerProperty, SRID: pc.SRID}, paths...)
+		if err != nil {
+			return nil, err
+		}
+		return []Provider{p}, nil
+	case "shapefile":
+		p, err := NewShapefileProviderWithOptions(ShapefileOptions{LayerName: pc.LayerName, Encoding: pc.Encoding, SRID: pc.SRID}, paths...)
+		if err != nil {
+			return nil, err
+		}
+		return []Provider{p}, nil
+	case "flatgeobuf":
+		var providers []Provider
+		for _, path := range paths {
+			p, err := NewFlatGeobufProviderWithOptions(FlatGeobufOptions{LayerName: pc.LayerName, SRID: pc.SRID}, path)
+			if err != nil {
+				for _, opened := range providers {
+					opened.(io.Closer).Close()
+				}
+				return nil, err
+			}
+			providers = append(providers, p)
+		}
+		return providers, nil
+	case "tileset":
+		p, err := NewTilesetProviderWithOptions(TilesetOptions{Extension: pc.Extension, Layers: pc.Layers}, paths...)
+		if err != nil {
+			return nil, err
+		}
+		return []Provider{p}, nil
+	}
+	return nil, fmt.Errorf("%w: 未知的数据源类型 %s", ErrInvalidConfig, pc.Type)
+}
+
+// exporter 创建导出器并返回输出目录，mbtiles和pmtiles的输出目录为文件所在目录
+func (c *ConfigFile) exporter() (Exporter, string, error) {
+	path := c.path(c.Output.Path)
+	newExporter, ok := outputFormats[c.Output.Format]
+	if !ok {
+		return nil, "", c.errorAt("output.format", fmt.Errorf("%w: 未知的输出格式 %s", ErrInvalidConfig, c.Output.Format))
+	}
+	exporter, err := newExporter(path, &c.Output)
+	if err != nil {
+		return nil, "", c.errorAt("output.path", err)
+	}
+	if archiveFormats[c.Output.Format] {
+		return exporter, filepath.Dir(path), nil
+	}
+	return exporter, path, nil
+}
+
+// options 返回图层配置对应的LayerOptions
+func (l *LayerConfig) options() *LayerOptions {
+	o := &LayerOptions{
+		MinZoom:   l.MinZoom,
+		MaxZoom:   l.MaxZoom,
+		Buffer:    l.Buffer,
+		Extent:    l.Extent,
+		Tolerance: l.Tolerance,
+		Simplify:  l.Simplify,
+	}
+	if len(l.Filter) > 0 {
+		filters := l.Filter
+		o.Filters = filters
+		o.Filter = func(f *geom.Feature) bool {
+			for _, filter := range filters {
+				if !filter.Match(f) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return o
+}
+
+// Match 判断要素是否满足条件
+// 比较值为数值时按数值比较，属性为数字字符串时转换为数值；否则按字符串比较。
+// 属性不存在时只有!=、not_in和not_has成立
+func (c FeatureFilter) Match(f *geom.Feature) bool {
+	var v interface{}
+	if f != nil {
+		v = f.Properties[c.Property]
+	}
+	switch c.Op {
+	case "has":
+		return v != nil
+	case "not_has":
+		return v == nil
+	case "!=":
+		return !filterEqual(v, c.Value)
+	case "in", "not_in":
+		values, _ := c.Value.([]interface{})
+		found := false
+		for _, value := range values {
+			found = found || filterEqual(v, value)
+		}
+		return found == (c.Op == "in")
+	case "<", "<=", ">", ">=":
+		cmp, ok := filterCompare(v, c.Value)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		default:
+			return cmp >= 0
+		}
+	default:
+		return filterEqual(v, c.Value)
+	}
+}
+
+// filterEqual 判断属性值是否等于比较值
+func filterEqual(v, value interface{}) bool {
+	if v == nil || value == nil {
+		return v == value
+	}
+	if b, ok := value.(bool); ok {
+		pb, isBool := v.(bool)
+		return isBool && pb == b
+	}
+	cmp, ok := filterCompare(v, value)
+	return ok && cmp == 0
+}
+
+// filterCompare 比较属性值和比较值，无法比较时返回false
+func filterCompare(v, value interface{}) (int, bool) {
+	if v == nil || value == nil {
+		return 0, false
+	}
+	if _, isBool := v.(bool); isBool {
+		return 0, false
+	}
+	if n, ok := propertyAsFloat(value); ok {
+		if _, isString := value.(string); !isString {
+			pn, ok := propertyAsFloat(v)
+			if !ok {
+				return 0, false
+			}
+			switch {
+			case pn < n:
+				return -1, true
+			case pn > n:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return strings.Compare(fmt.Sprint(v), fmt.Sprint(value)), true
+}
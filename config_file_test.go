@@ -0,0 +1,316 @@
+package tile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+)
+
+// testConfigYAML 测试用的YAML配置，数据和输出使用相对路径
+const testConfigYAML = `# 北京道路和地名
+providers:
+  - type: geojson
+    paths: [data/places.geojson]
+    layer_property: kind
+  - type: geojson
+    paths: [data/roads.geojson]
+layers:
+  roads:
+    minzoom: 4
+    buffer: 128
+    simplify: false
+    filter:
+      - property: highway
+        op: in
+        value: [primary, secondary]
+  places:
+    maxzoom: 10
+    filter:
+      - {property: pop, op: ">=", value: 1000}
+grid:
+  bounds: [110, 30, 120, 40]
+minzoom: 2
+maxzoom: 5
+extent: 4096
+simplify:
+  maxzoom: 8
+concurrency: 2
+output:
+  format: geojson
+  path: out
+  tilejson:
+    name: beijing
+`
+
+// writeConfigData 在dir下写入测试配置引用的GeoJSON数据
+func writeConfigData(t *testing.T, dir string) {
+	t.Helper()
+	files := map[string]string{
+		"places.geojson": `{"type":"FeatureCollection","features":[
+			{"type":"Feature","properties":{"kind":"cities","name":"北京","pop":2189},"geometry":{"type":"Point","coordinates":[116.4,39.9]}},
+			{"type":"Feature","properties":{"kind":"cities","name":"通州","pop":184},"geometry":{"type":"Point","coordinates":[116.6,39.9]}}
+		]}`,
+		"roads.geojson": `{"type":"FeatureCollection","features":[
+			{"type":"Feature","properties":{"highway":"primary"},"geometry":{"type":"LineString","coordinates":[[116.3,39.9],[116.5,39.9]]}}
+		]}`,
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, "data", name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestLoadConfigFile 测试读取配置文件并生成瓦片
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigData(t, dir)
+	path := filepath.Join(dir, "tiles.yaml")
+	if err := os.WriteFile(path, []byte(testConfigYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cf, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() 错误 = %v", err)
+	}
+	if len(cf.Providers) != 2 || cf.Providers[0].LayerProperty != "kind" || cf.MinZoom != 2 || cf.MaxZoom != 5 {
+		t.Errorf("ConfigFile = %+v", cf)
+	}
+	if !cf.Simplify.Enabled || cf.Simplify.MaxZoom != 8 || cf.Grid.Bounds == nil || *cf.Grid.Bounds != [4]float64{110, 30, 120, 40} {
+		t.Errorf("Simplify = %+v, Bounds = %v", cf.Simplify, cf.Grid.Bounds)
+	}
+
+	config, err := cf.Config()
+	if err != nil {
+		t.Fatalf("Config() 错误 = %v", err)
+	}
+	if _, ok := config.Provider.(*MultiProvider); !ok {
+		t.Errorf("Provider = %T, want *MultiProvider", config.Provider)
+	}
+	if _, ok := config.Exporter.(*GeoJSONExporter); !ok {
+		t.Errorf("Exporter = %T, want *GeoJSONExporter", config.Exporter)
+	}
+	if config.OutputDir != filepath.Join(dir, "out") || config.TileExtent != 4096 || config.Concurrency != 2 {
+		t.Errorf("OutputDir = %s, TileExtent = %d, Concurrency = %d", config.OutputDir, config.TileExtent, config.Concurrency)
+	}
+	roads := config.LayerOptions["roads"]
+	if roads == nil || roads.MinZoom != 4 || roads.Buffer != 128 || roads.Simplify == nil || *roads.Simplify || roads.Filter == nil {
+		t.Fatalf("roads = %+v", roads)
+	}
+	if places := config.LayerOptions["places"]; places == nil || places.MaxZoom == nil || *places.MaxZoom != 10 || roads.MaxZoom != nil {
+		t.Errorf("places = %+v, roads.MaxZoom = %v", places, roads.MaxZoom)
+	}
+	if !roads.Filter(&geom.Feature{Properties: map[string]interface{}{"highway": "secondary"}}) ||
+		roads.Filter(&geom.Feature{Properties: map[string]interface{}{"highway": "footway"}}) {
+		t.Error("roads筛选结果错误")
+	}
+
+	tiler := NewTiler(config)
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+	tj, err := ReadTileJSON(filepath.Join(dir, "out", TileJSONFileName))
+	if err != nil {
+		t.Fatalf("ReadTileJSON() 错误 = %v", err)
+	}
+	if tj.Name != "beijing" || tj.MinZoom != 2 || tj.MaxZoom != 5 {
+		t.Errorf("TileJSON = %+v", tj)
+	}
+}
+
+// TestParseConfigFile_JSON 测试JSON格式的配置和默认值
+func TestParseConfigFile_JSON(t *testing.T) {
+	cf, err := ParseConfigFile([]byte(`{
+		"providers": [{"type": "tileset", "paths": ["./missing-tileset"], "layers": ["roads"]}],
+		"grid": {"tile_matrix_set": "WorldCRS84Quad", "srs": "EPSG:4326"},
+		"output": {"format": "pmtiles", "path": "out.pmtiles", "gzip": false}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseConfigFile() 错误 = %v", err)
+	}
+	if cf.MaxZoom != DefaultConfig.MaxZoom || !cf.Simplify.Enabled || cf.Grid.Bounds != nil {
+		t.Errorf("默认值 MaxZoom = %d, Simplify = %+v, Bounds = %v", cf.MaxZoom, cf.Simplify, cf.Grid.Bounds)
+	}
+	if p := cf.Providers[0]; p.Type != "tileset" || len(p.Layers) != 1 || p.Layers[0] != "roads" {
+		t.Errorf("Providers[0] = %+v", p)
+	}
+	if cf.Output.Gzip == nil || *cf.Output.Gzip || cf.Output.Format != "pmtiles" {
+		t.Errorf("Output = %+v", cf.Output)
+	}
+	if set, err := cf.tileMatrixSet(); err != nil || set != WorldCRS84Quad {
+		t.Errorf("tileMatrixSet() = %v, %v", set, err)
+	}
+
+	// 数据源不存在时错误带有数据源的位置
+	_, err = cf.Config()
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Field != "providers[0]" || ce.Line != 2 {
+		t.Errorf("Config() 错误 = %v", err)
+	}
+}
+
+// TestParseConfigFile_Errors 测试不符合约束的配置报告行号
+func TestParseConfigFile_Errors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+		// errors 期望的错误，格式为"行号 字段: 描述片段"
+		errors []string
+	}{
+		{"缺少数据源", "minzoom: 1\n", []string{"1 : 缺少必填字段providers"}},
+		{"空数据源", "providers: []\n", []string{"1 providers: 至少需要1个元素"}},
+		{"未知字段和类型", `providers:
+  - type: geojson
+    paths: data.geojson
+    layer_name: x
+maxzoom: high
+`, []string{
+			"3 providers[0].paths: 需要数组",
+			"4 providers[0].layer_name: 未知字段",
+			"5 maxzoom: 需要整数",
+		}},
+		{"未知类型", `providers:
+  - type: postgis
+    paths: [a]
+`, []string{"2 providers[0].type: 须为flatgeobuf、geojson、shapefile、tileset之一"}},
+		{"范围和枚举", `providers: [{type: geojson, paths: [a], srid: 2000}]
+minzoom: 30
+extent: 0
+output:
+  format: png
+  resume: yes please
+`, []string{
+			"1 providers[0].srid: 须为4326、3857之一",
+			"2 minzoom: 不能大于22",
+			"3 extent: 不能小于1",
+			"5 output.format: 须为",
+			"6 output.resume: 需要布尔值",
+		}},
+		{"pmtiles续传", `providers: [{type: geojson, paths: [a]}]
+output:
+  format: pmtiles
+  path: out.pmtiles
+  resume: true
+`, []string{"5 output.resume: 不能与pmtiles格式一起使用"}},
+		{"级别范围", `providers: [{type: geojson, paths: [a]}]
+maxzoom: 6
+layers:
+  roads:
+    minzoom: 5
+    maxzoom: 3
+  water:
+    minzoom: 1
+    maxzoom: 0
+`, []string{
+			"6 layers.roads.maxzoom: 不能小于minzoom(5)",
+			"9 layers.water.maxzoom: 不能小于minzoom(1)",
+		}},
+		{"默认最大级别", `providers: [{type: geojson, paths: [a]}]
+minzoom: 15
+`, []string{"2 minzoom: 不能大于maxzoom(14)"}},
+		{"网格范围", `providers: [{type: geojson, paths: [a]}]
+grid:
+  bounds: [120, 30, 110, 40]
+`, []string{"3 grid.bounds: 最小值须小于最大值"}},
+		{"网格坐标系", `providers: [{type: geojson, paths: [a]}]
+grid: {bounds: [1, 2, 3], srs: "EPSG:abc"}
+`, []string{"2 grid.bounds: 需要4个元素"}},
+		{"坐标系", `providers: [{type: geojson, paths: [a]}]
+grid: {srs: "EPSG:abc"}
+`, []string{"2 grid.srs: 须为"}},
+		{"筛选条件", `providers: [{type: geojson, paths: [a]}]
+layers:
+  roads:
+    filter:
+      - property: highway
+        op: in
+        value: primary
+      - {op: has}
+      - {property: lanes, op: "~"}
+`, []string{
+			"5 layers.roads.filter[0]: in需要数组value",
+			"8 layers.roads.filter[1]: 缺少必填字段property",
+			"9 layers.roads.filter[2].op: 须为",
+		}},
+		{"语法错误", "providers:\n  - type: geojson\n    paths: [a]\nminzoom: 1\n\tmaxzoom: 2\n", []string{"4 : found a tab character"}},
+		{"空配置", "", []string{"0 : 配置为空"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseConfigFile([]byte(tc.config))
+			var errs ConfigErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("错误 = %v, want ConfigErrors", err)
+			}
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("errors.Is(%v, ErrInvalidConfig) = false", err)
+			}
+			if len(errs) != len(tc.errors) {
+				t.Fatalf("错误 = \n%v\nwant %q", err, tc.errors)
+			}
+			for i, e := range errs {
+				got := strconv.Itoa(e.Line) + " " + e.Field + ": " + e.Msg
+				if !strings.HasPrefix(got, tc.errors[i]) {
+					t.Errorf("错误[%d] = %q, want 前缀 %q", i, got, tc.errors[i])
+				}
+			}
+		})
+	}
+}
+
+// TestConfigError 测试配置错误的描述
+func TestConfigError(t *testing.T) {
+	err := &ConfigError{File: "tiles.yaml", Line: 3, Column: 5, Field: "output.format", Msg: "须为mvt之一"}
+	if got, want := err.Error(), "tiles.yaml:3:5: output.format: 须为mvt之一"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	cause := errors.New("no such file")
+	err = &ConfigError{Field: "providers[0]", Err: cause}
+	if got, want := err.Error(), "providers[0]: no such file"; got != want || !errors.Is(err, cause) || errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+// TestFeatureFilter 测试按属性筛选要素
+func TestFeatureFilter(t *testing.T) {
+	props := map[string]interface{}{"name": "长安街", "lanes": 8, "width": "30.5", "toll": false}
+	testCases := []struct {
+		filter FeatureFilter
+		want   bool
+	}{
+		{FeatureFilter{Property: "name", Value: "长安街"}, true},
+		{FeatureFilter{Property: "lanes", Op: "==", Value: 8.0}, true},
+		{FeatureFilter{Property: "lanes", Op: "!=", Value: 8}, false},
+		{FeatureFilter{Property: "missing", Op: "!=", Value: 8}, true},
+		{FeatureFilter{Property: "lanes", Op: ">", Value: 4}, true},
+		{FeatureFilter{Property: "lanes", Op: "<=", Value: 4}, false},
+		{FeatureFilter{Property: "width", Op: ">=", Value: 30}, true},
+		{FeatureFilter{Property: "name", Op: "<", Value: 5}, false},
+		{FeatureFilter{Property: "name", Op: ">", Value: "b"}, true},
+		{FeatureFilter{Property: "toll", Value: false}, true},
+		{FeatureFilter{Property: "toll", Value: "false"}, false},
+		{FeatureFilter{Property: "lanes", Op: "in", Value: []interface{}{4, 6, 8}}, true},
+		{FeatureFilter{Property: "name", Op: "not_in", Value: []interface{}{"长安街"}}, false},
+		{FeatureFilter{Property: "missing", Op: "not_in", Value: []interface{}{"a"}}, true},
+		{FeatureFilter{Property: "name", Op: "has"}, true},
+		{FeatureFilter{Property: "missing", Op: "has"}, false},
+		{FeatureFilter{Property: "missing", Op: "not_has"}, true},
+		{FeatureFilter{Property: "missing", Op: ">", Value: 1}, false},
+	}
+	f := &geom.Feature{Properties: props}
+	for _, tc := range testCases {
+		if got := tc.filter.Match(f); got != tc.want {
+			t.Errorf("%+v.Match() = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package tile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaKind 配置值的类型
+type schemaKind int
+
+const (
+	schemaString schemaKind = iota
+	schemaInt
+	schemaNumber
+	schemaBool
+	// schemaValue 标量或标量数组，用于筛选条件的比较值
+	schemaValue
+	schemaObject
+	schemaArray
+	// schemaMap 键为任意字符串的映射
+	schemaMap
+)
+
+// schemaKindNames 类型不符时的描述
+var schemaKindNames = map[schemaKind]string{
+	schemaString: "字符串",
+	schemaInt:    "整数",
+	schemaNumber: "数值",
+	schemaBool:   "布尔值",
+	schemaValue:  "标量或标量数组",
+	schemaObject: "对象",
+	schemaArray:  "数组",
+	schemaMap:    "对象",
+}
+
+// configSchema 配置文件中一个值的约束
+type configSchema struct {
+	kind schemaKind
+	// fields 对象的字段
+	fields map[string]*configSchema
+	// required 对象的必填字段
+	required []string
+	// variants 按type字段的值区分的对象字段，与fields合并后检查
+	variants map[string]map[string]*configSchema
+	// items 数组元素或映射值的约束
+	items *configSchema
+	// minItems 数组的最少元素数
+	minItems int
+	// length 不为0时数组须为该长度
+	length int
+	// enum 标量的可选值
+	enum []string
+	// min和max 数值的范围
+	min, max *float64
+	// check 对象的附加检查，在对象本身没有错误时调用，返回出错的节点和描述
+	check func(n *yaml.Node) (*yaml.Node, string)
+}
+
+// schemaValidator 按configSchema检查YAML节点，记录错误和各字段的位置
+type schemaValidator struct {
+	file  string
+	errs  ConfigErrors
+	lines map[string][2]int
+}
+
+// fail 记录节点n处的错误
+func (v *schemaValidator) fail(n *yaml.Node, path, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ConfigError{
+		File:   v.file,
+		Line:   n.Line,
+		Column: n.Column,
+		Field:  path,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
+
+// validate 检查节点n是否满足约束s，path为字段路径
+func (v *schemaValidator) validate(n *yaml.Node, s *configSchema, path string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	v.lines[path] = [2]int{n.Line, n.Column}
+
+	switch s.kind {
+	case schemaObject:
+		v.validateObject(n, s, path)
+	case schemaMap:
+		if n.Kind != yaml.MappingNode {
+			v.fail(n, path, "需要%s", schemaKindNames[s.kind])
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v.validate(n.Content[i+1], s.items, joinField(path, n.Content[i].Value))
+		}
+	case schemaArray:
+		if n.Kind != yaml.SequenceNode {
+			v.fail(n, path, "需要%s", schemaKindNames[s.kind])
+			return
+		}
+		if s.length > 0 && len(n.Content) != s.length {
+			v.fail(n, path, "需要%d个元素", s.length)
+		} else if len(n.Content) < s.minItems {
+			v.fail(n, path, "至少需要%d个元素", s.minItems)
+		}
+		for i, item := range n.Content {
+			v.validate(item, s.items, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case schemaValue:
+		if n.Kind == yaml.SequenceNode {
+			for i, item := range n.Content {
+				v.validateScalar(item, &configSchema{kind: schemaString}, fmt.Sprintf("%s[%d]", path, i))
+			}
+			return
+		}
+		v.validateScalar(n, &configSchema{kind: schemaString}, path)
+	default:
+		v.validateScalar(n, s, path)
+	}
+}
+
+// validateObject 检查对象的字段，不允许未知字段和重复字段
+func (v *schemaValidator) validateObject(n *yaml.Node, s *configSchema, path string) {
+	if n.Kind != yaml.MappingNode {
+		v.fail(n, path, "需要%s", schemaKindNames[s.kind])
+		return
+	}
+	errs := len(v.errs)
+
+	fields := s.fields
+	if s.variants != nil {
+		typ := mappingValue(n, "type")
+		if typ == nil {
+			v.fail(n, path, "缺少必填字段type")
+			return
+		}
+		variant, ok := s.variants[typ.Value]
+		if !ok {
+			v.fail(typ, joinField(path, "type"), "须为%s之一", strings.Join(sortedNames(s.variants), "、"))
+			return
+		}
+		fields = make(map[string]*configSchema, len(s.fields)+len(variant))
+		for k, f := range s.fields {
+			fields[k] = f
+		}
+		for k, f := range variant {
+			fields[k] = f
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		field := joinField(path, key.Value)
+		f, ok := fields[key.Value]
+		switch {
+		case !ok:
+			v.fail(key, field, "未知字段")
+		case seen[key.Value]:
+			v.fail(key, field, "重复字段")
+		default:
+			v.validate(value, f, field)
+		}
+		seen[key.Value] = true
+	}
+	for _, name := range s.required {
+		if !seen[name] {
+			v.fail(n, path, "缺少必填字段%s", name)
+		}
+	}
+
+	if s.check != nil && len(v.errs) == errs {
+		if node, msg := s.check(n); msg != "" {
+			field := path
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i+1] == node {
+					field = joinField(path, n.Content[i].Value)
+				}
+			}
+			v.fail(node, field, "%s", msg)
+		}
+	}
+}
+
+// validateScalar 检查标量的类型、可选值和范围
+func (v *schemaValidator) validateScalar(n *yaml.Node, s *configSchema, path string) {
+	name := schemaKindNames[s.kind]
+	if n.Kind != yaml.ScalarNode || n.Tag == "!!null" {
+		v.fail(n, path, "需要%s", name)
+		return
+	}
+	switch s.kind {
+	case schemaInt:
+		if n.Tag != "!!int" {
+			v.fail(n, path, "需要%s", name)
+			return
+		}
+	case schemaNumber:
+		if n.Tag != "!!int" && n.Tag != "!!float" {
+			v.fail(n, path, "需要%s", name)
+			return
+		}
+	case schemaBool:
+		if n.Tag != "!!bool" {
+			v.fail(n, path, "需要%s", name)
+			return
+		}
+	}
+
+	if len(s.enum) > 0 {
+		found := false
+		for _, e := range s.enum {
+			found = found || n.Value == e
+		}
+		if !found {
+			v.fail(n, path, "须为%s之一", strings.Join(s.enum, "、"))
+			return
+		}
+	}
+	if s.min != nil || s.max != nil {
+		f, _ := nodeFloat(n)
+		if s.min != nil && f < *s.min {
+			v.fail(n, path, "不能小于%g", *s.min)
+		} else if s.max != nil && f > *s.max {
+			v.fail(n, path, "不能大于%g", *s.max)
+		}
+	}
+}
+
+// joinField 连接字段路径
+func joinField(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// mappingValue 返回对象中键为key的值节点，不存在时返回nil
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// nodeFloat 将数值标量节点转换为浮点数
+func nodeFloat(n *yaml.Node) (float64, bool) {
+	if n == nil {
+		return 0, false
+	}
+	var f float64
+	if err := n.Decode(&f); err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// sortedNames 返回排序后的键
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaBound 返回数值范围约束
+func schemaBound(v float64) *float64 {
+	return &v
+}
+
+// zoomRangeCheck 检查对象的minzoom不大于maxzoom
+// defaultMax为nil时maxzoom不存在表示不限制，否则maxzoom不存在时与defaultMax比较
+func zoomRangeCheck(defaultMax *float64) func(n *yaml.Node) (*yaml.Node, string) {
+	return func(n *yaml.Node) (*yaml.Node, string) {
+		minNode, maxNode := mappingValue(n, "minzoom"), mappingValue(n, "maxzoom")
+		minZoom, _ := nodeFloat(minNode)
+		maxZoom, ok := nodeFloat(maxNode)
+		switch {
+		case !ok && defaultMax == nil:
+			return nil, ""
+		case !ok:
+			maxZoom = *defaultMax
+		}
+		if minZoom <= maxZoom {
+			return nil, ""
+		}
+		if maxNode != nil {
+			return maxNode, fmt.Sprintf("不能小于minzoom(%g)", minZoom)
+		}
+		return minNode, fmt.Sprintf("不能大于maxzoom(%g)", maxZoom)
+	}
+}
+
+var (
+	zoomSchema    = &configSchema{kind: schemaInt, min: schemaBound(0), max: schemaBound(float64(MaxZ))}
+	uintSchema    = &configSchema{kind: schemaInt, min: schemaBound(0)}
+	stringSchema  = &configSchema{kind: schemaString}
+	boolSchema    = &configSchema{kind: schemaBool}
+	stringsSchema = &configSchema{kind: schemaArray, items: stringSchema}
+	sridSchema    = &configSchema{kind: schemaInt, enum: []string{"4326", "3857"}}
+)
+
+// filterSchema 要素筛选条件的约束
+var filterSchema = &configSchema{
+	kind: schemaObject,
+	fields: map[string]*configSchema{
+		"property": stringSchema,
+		"op":       {kind: schemaString, enum: filterOps},
+		"value":    {kind: schemaValue},
+	},
+	required: []string{"property"},
+	check: func(n *yaml.Node) (*yaml.Node, string) {
+		op, value := "==", mappingValue(n, "value")
+		if node := mappingValue(n, "op"); node != nil {
+			op = node.Value
+		}
+		switch op {
+		case "has", "not_has":
+			return nil, ""
+		case "in", "not_in":
+			if value == nil || value.Kind != yaml.SequenceNode {
+				return n, fmt.Sprintf("%s需要数组value", op)
+			}
+		default:
+			if value == nil || value.Kind != yaml.ScalarNode {
+				return n, fmt.Sprintf("%s需要标量value", op)
+			}
+		}
+		return nil, ""
+	},
+}
+
+// configFileSchema 配置文件的约束
+var configFileSchema = &configSchema{
+	kind: schemaObject,
+	fields: map[string]*configSchema{
+		"providers": {
+			kind:     schemaArray,
+			minItems: 1,
+			items: &configSchema{
+				kind: schemaObject,
+				fields: map[string]*configSchema{
+					"type":  stringSchema,
+					"paths": {kind: schemaArray, minItems: 1, items: stringSchema},
+				},
+				required: []string{"paths"},
+				variants: map[string]map[string]*configSchema{
+					"geojson":    {"srid": sridSchema, "layer_property": stringSchema},
+					"shapefile":  {"srid": sridSchema, "layer_name": stringSchema, "encoding": stringSchema},
+					"flatgeobuf": {"srid": sridSchema, "layer_name": stringSchema},
+					"tileset":    {"layers": stringsSchema, "extension": stringSchema},
+				},
+			},
+		},
+		"layers": {
+			kind: schemaMap,
+			items: &configSchema{
+				kind: schemaObject,
+				fields: map[string]*configSchema{
+					"minzoom":   zoomSchema,
+					"maxzoom":   zoomSchema,
+					"buffer":    uintSchema,
+					"extent":    {kind: schemaInt, min: schemaBound(1)},
+					"tolerance": {kind: schemaNumber, min: schemaBound(0)},
+					"simplify":  boolSchema,
+					"filter":    {kind: schemaArray, items: filterSchema},
+				},
+				check: zoomRangeCheck(nil),
+			},
+		},
+		"grid": {
+			kind: schemaObject,
+			fields: map[string]*configSchema{
+				"tile_matrix_set": stringSchema,
+				"bounds":          {kind: schemaArray, length: 4, items: &configSchema{kind: schemaNumber}},
+				"srs":             stringSchema,
+			},
+			check: func(n *yaml.Node) (*yaml.Node, string) {
+				if srs := mappingValue(n, "srs"); srs != nil {
+					if _, ok := srsSRID(srs.Value); !ok {
+						return srs, "须为\"EPSG:代码\"、WGS84_PROJ4或GMERC_PROJ4"
+					}
+				}
+				if bounds := mappingValue(n, "bounds"); bounds != nil {
+					var b [4]float64
+					for i, item := range bounds.Content {
+						b[i], _ = nodeFloat(item)
+					}
+					if b[0] >= b[2] || b[1] >= b[3] {
+						return bounds, "最小值须小于最大值"
+					}
+				}
+				return nil, ""
+			},
+		},
+		"minzoom": zoomSchema,
+		"maxzoom": zoomSchema,
+		"extent":  {kind: schemaInt, min: schemaBound(1)},
+		"buffer":  uintSchema,
+		"simplify": {
+			kind: schemaObject,
+			fields: map[string]*configSchema{
+				"enabled": boolSchema,
+				"maxzoom": zoomSchema,
+			},
+		},
+		"concurrency": {kind: schemaInt, min: schemaBound(1)},
+		"output": {
+			kind: schemaObject,
+			fields: map[string]*configSchema{
+				"format": {kind: schemaString, enum: sortedNames(outputFormats)},
+				"path":   stringSchema,
+				"gzip":   boolSchema,
+				"resume": boolSchema,
+				"tilejson": {
+					kind: schemaObject,
+					fields: map[string]*configSchema{
+						"name":        stringSchema,
+						"description": stringSchema,
+						"attribution": stringSchema,
+						"version":     stringSchema,
+						"tiles":       stringsSchema,
+					},
+				},
+			},
+			check: func(n *yaml.Node) (*yaml.Node, string) {
+				// pmtiles归档在结束时整体重写，只包含本次运行的瓦片
+				format, resume := mappingValue(n, "format"), mappingValue(n, "resume")
+				if format == nil || format.Value != "pmtiles" || resume == nil || resume.Tag != "!!bool" {
+					return nil, ""
+				}
+				var enabled bool
+				if err := resume.Decode(&enabled); err == nil && enabled {
+					return resume, "不能与pmtiles格式一起使用"
+				}
+				return nil, ""
+			},
+		},
+	},
+	required: []string{"providers"},
+	check:    zoomRangeCheck(schemaBound(float64(DefaultConfig.MaxZoom))),
+}
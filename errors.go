@@ -1,6 +1,12 @@
 package tile
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
 
 // 集中定义所有错误变量
 var (
@@ -10,4 +16,227 @@ var (
 	ErrInvalidPath = errors.New("invalid path")
 	// ErrEmptyLayers 表示空图层
 	ErrEmptyLayers = errors.New("empty layers")
+	// ErrNotDescendant 表示瓦片不是祖先瓦片的子孙瓦片
+	ErrNotDescendant = errors.New("tile is not a descendant of ancestor")
+	// ErrBottomUpResume 表示自底向上生成不支持断点续传
+	ErrBottomUpResume = errors.New("bottom-up tiling does not support resume")
+	// ErrInvalidShapefile 表示无效的Shapefile文件
+	ErrInvalidShapefile = errors.New("invalid shapefile")
+	// ErrInvalidFlatGeobuf 表示无效的FlatGeobuf文件
+	ErrInvalidFlatGeobuf = errors.New("invalid flatgeobuf")
+	// ErrUnsupportedProjection 表示不支持的坐标系
+	ErrUnsupportedProjection = errors.New("unsupported projection")
+	// ErrInvalidTileMatrixSet 表示无效的瓦片矩阵集
+	ErrInvalidTileMatrixSet = errors.New("invalid tile matrix set")
+	// ErrNotWebMercator 表示瓦片不在Web墨卡托网格中
+	ErrNotWebMercator = errors.New("tile is not in the web mercator grid")
+	// ErrExporterClosed 表示导出器已关闭
+	ErrExporterClosed = errors.New("exporter is closed")
+	// ErrIncrementalArchive 表示单文件归档不支持只重新生成部分瓦片
+	ErrIncrementalArchive = errors.New("archive exporter does not support resume or retile")
+	// ErrSinkNotOpen 表示瓦片存储未打开
+	ErrSinkNotOpen = errors.New("tile sink is not open")
+	// ErrNoProvider 表示未设置数据源
+	ErrNoProvider = errors.New("provider is not set")
+	// ErrTileAborted 表示瓦片处理被放弃
+	ErrTileAborted = errors.New("tile aborted")
+	// ErrInvalidMVT 表示无效的MVT数据
+	ErrInvalidMVT = errors.New("invalid mvt")
+	// ErrEmptyTileset 表示瓦片目录中没有级别子目录
+	ErrEmptyTileset = errors.New("tileset has no zoom levels")
+	// ErrInvalidConfig 表示配置文件不符合约束
+	ErrInvalidConfig = errors.New("invalid config")
+)
+
+// Stage 瓦片处理流水线的阶段
+type Stage string
+
+const (
+	// StageProvider 从Provider获取数据
+	StageProvider Stage = "provider"
+	// StageReproject 坐标转换
+	StageReproject Stage = "reproject"
+	// StageSimplify 几何简化
+	StageSimplify Stage = "simplify"
+	// StagePrepare 几何预处理(PrepareGeo)
+	StagePrepare Stage = "prepare"
+	// StageClean 几何裁剪和修复(CleanGeometry)
+	StageClean Stage = "clean"
+	// StageMerge 合并子瓦片(自底向上生成)
+	StageMerge Stage = "merge"
+	// StageOverzoom 由最大级别瓦片生成超级别瓦片
+	StageOverzoom Stage = "overzoom"
+	// StageBudget 按瓦片预算简化和丢弃要素
+	StageBudget Stage = "budget"
+	// StageExport 导出瓦片
+	StageExport Stage = "export"
+	// StageRemove 删除旧瓦片
+	StageRemove Stage = "remove"
 )
+
+// stageMessages 各阶段错误的描述
+var stageMessages = map[Stage]string{
+	StageProvider:  "获取数据失败",
+	StageReproject: "坐标转换失败",
+	StageSimplify:  "几何简化失败",
+	StagePrepare:   "几何预处理失败",
+	StageClean:     "几何裁剪失败",
+	StageMerge:     "合并子瓦片失败",
+	StageOverzoom:  "生成超级别瓦片失败",
+	StageBudget:    "瓦片预算处理失败",
+	StageExport:    "导出瓦片失败",
+	StageRemove:    "删除瓦片失败",
+}
+
+// TileError 表示单个瓦片处理中的错误
+type TileError struct {
+	Z uint32
+	X uint32
+	Y uint32
+	// Layer 出错的图层名，与图层无关时为空
+	Layer string
+	// Feature 出错要素在图层中的序号，与要素无关时为-1
+	Feature int
+	// Stage 出错的处理阶段
+	Stage Stage
+	// Err 原始错误
+	Err error
+}
+
+// newTileError 创建与图层和要素无关的瓦片错误
+func newTileError(task *tileTask, stage Stage, err error) *TileError {
+	return &TileError{Z: task.z, X: task.x, Y: task.y, Feature: -1, Stage: stage, Err: err}
+}
+
+// newFeatureError 创建要素级别的瓦片错误
+func newFeatureError(task *tileTask, layer string, feature int, stage Stage, err error) *TileError {
+	return &TileError{Z: task.z, X: task.x, Y: task.y, Layer: layer, Feature: feature, Stage: stage, Err: err}
+}
+
+func (e *TileError) Error() string {
+	msg, ok := stageMessages[e.Stage]
+	if !ok {
+		msg = string(e.Stage) + "失败"
+	}
+	if e.Layer != "" && e.Feature >= 0 {
+		return fmt.Sprintf("%s (z=%d, x=%d, y=%d, layer=%s, feature=%d): %v",
+			msg, e.Z, e.X, e.Y, e.Layer, e.Feature, e.Err)
+	}
+	return fmt.Sprintf("%s (z=%d, x=%d, y=%d): %v", msg, e.Z, e.X, e.Y, e.Err)
+}
+
+func (e *TileError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON 将错误序列化为JSON，原始错误输出为字符串
+func (e *TileError) MarshalJSON() ([]byte, error) {
+	msg := ""
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	return json.Marshal(struct {
+		Z       uint32 `json:"z"`
+		X       uint32 `json:"x"`
+		Y       uint32 `json:"y"`
+		Layer   string `json:"layer,omitempty"`
+		Feature int    `json:"feature"`
+		Stage   Stage  `json:"stage"`
+		Error   string `json:"error"`
+	}{e.Z, e.X, e.Y, e.Layer, e.Feature, e.Stage, msg})
+}
+
+// ErrorReport 汇总运行中跳过的瓦片错误
+// 在ErrorPolicySkipFeature和ErrorPolicySkipTile策略下由Tiler返回，
+// 可通过errors.As取得其中的*TileError
+type ErrorReport struct {
+	Errors []*TileError `json:"errors"`
+}
+
+func (r *ErrorReport) Error() string {
+	if len(r.Errors) == 0 {
+		return "没有瓦片错误"
+	}
+	return fmt.Sprintf("%d 个瓦片错误, 第一个错误: %v", len(r.Errors), r.Errors[0])
+}
+
+func (r *ErrorReport) Unwrap() []error {
+	errs := make([]error, len(r.Errors))
+	for i, err := range r.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// WriteJSON 将错误报告以JSON格式写入w
+func (r *ErrorReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// ConfigError 表示配置文件中的一个错误
+type ConfigError struct {
+	// File 配置文件路径，由数据解析时为空
+	File string
+	// Line和Column 出错位置，从1开始，未知时为0
+	Line   int
+	Column int
+	// Field 出错字段的路径，如providers[0].paths
+	Field string
+	// Msg 错误描述
+	Msg string
+	// Err 原始错误，为nil时表示配置不符合约束
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File + ":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d:", e.Line, e.Column)
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	if e.Field != "" {
+		b.WriteString(e.Field + ": ")
+	}
+	b.WriteString(e.Msg)
+	if e.Err != nil {
+		if e.Msg != "" {
+			b.WriteString(": ")
+		}
+		b.WriteString(e.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *ConfigError) Unwrap() error {
+	if e.Err != nil {
+		return e.Err
+	}
+	return ErrInvalidConfig
+}
+
+// ConfigErrors 配置文件中的全部错误，按出现的位置排列
+// 可通过errors.As取得其中的*ConfigError
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e ConfigErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
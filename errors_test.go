@@ -0,0 +1,173 @@
+package tile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// failingExporter 总是返回错误的模拟导出器
+type failingExporter struct {
+	MockExporter
+}
+
+func (e *failingExporter) SaveTile(layers []*Layer, tile *Tile, path string) error {
+	return errors.New("disk full")
+}
+
+// newErrorPolicyTiler 创建第一个要素无法转换坐标的Tiler
+func newErrorPolicyTiler(policy ErrorPolicy, exporter Exporter) *Tiler {
+	layer := &Layer{
+		Name: "roads",
+		Features: []*geom.Feature{
+			{Geometry: nil},
+			{Geometry: basic.Point{0, 0}},
+		},
+	}
+	return NewTiler(&Config{
+		Provider:    &MockProvider{layers: []*Layer{layer}, srid: 4326},
+		Exporter:    exporter,
+		MinZoom:     0,
+		MaxZoom:     1,
+		Bound:       &[4]float64{-1, -1, 1, 1},
+		ErrorPolicy: policy,
+	})
+}
+
+// TestErrorPolicy_FailFast 测试遇到错误立即停止
+func TestErrorPolicy_FailFast(t *testing.T) {
+	tiler := newErrorPolicyTiler(ErrorPolicyFailFast, &MockExporter{})
+
+	err := tiler.Tiler()
+	if err == nil {
+		t.Fatal("Tiler() 应该返回错误")
+	}
+
+	var tileErr *TileError
+	if !errors.As(err, &tileErr) {
+		t.Fatalf("errors.As(*TileError) 失败: %v", err)
+	}
+	if tileErr.Stage != StageReproject || tileErr.Layer != "roads" || tileErr.Feature != 0 {
+		t.Errorf("TileError = %+v", tileErr)
+	}
+
+	var report *ErrorReport
+	if errors.As(err, &report) {
+		t.Error("FailFast策略不应返回ErrorReport")
+	}
+}
+
+// TestErrorPolicy_SkipFeature 测试跳过出错的要素
+func TestErrorPolicy_SkipFeature(t *testing.T) {
+	exporter := &MockExporter{}
+	tiler := newErrorPolicyTiler(ErrorPolicySkipFeature, exporter)
+	total := int(tiler.count(tiler.getZoomLevels()))
+
+	err := tiler.Tiler()
+
+	var report *ErrorReport
+	if !errors.As(err, &report) {
+		t.Fatalf("应返回ErrorReport, 得到 %v", err)
+	}
+	if len(report.Errors) != total {
+		t.Errorf("错误数量 = %v, want %v", len(report.Errors), total)
+	}
+
+	saved := exporter.GetSavedTiles()
+	if len(saved) != total {
+		t.Fatalf("导出瓦片数量 = %v, want %v", len(saved), total)
+	}
+	for _, s := range saved {
+		if n := len(s.Layers[0].Features); n != 1 {
+			t.Errorf("瓦片 %d/%d/%d 要素数量 = %v, want 1", s.Tile.Z, s.Tile.X, s.Tile.Y, n)
+		}
+	}
+
+	var tileErr *TileError
+	if !errors.As(err, &tileErr) || tileErr.Stage != StageReproject {
+		t.Errorf("errors.As(*TileError) = %+v", tileErr)
+	}
+}
+
+// TestErrorPolicy_SkipTile 测试跳过出错的瓦片
+func TestErrorPolicy_SkipTile(t *testing.T) {
+	exporter := &MockExporter{}
+	tiler := newErrorPolicyTiler(ErrorPolicySkipTile, exporter)
+	total := int(tiler.count(tiler.getZoomLevels()))
+
+	err := tiler.Tiler()
+
+	var report *ErrorReport
+	if !errors.As(err, &report) {
+		t.Fatalf("应返回ErrorReport, 得到 %v", err)
+	}
+	if len(report.Errors) != total {
+		t.Errorf("错误数量 = %v, want %v", len(report.Errors), total)
+	}
+	if n := len(exporter.GetSavedTiles()); n != 0 {
+		t.Errorf("导出瓦片数量 = %v, want 0", n)
+	}
+}
+
+// TestErrorPolicy_ExportError 测试导出错误的收集
+func TestErrorPolicy_ExportError(t *testing.T) {
+	tiler := newErrorPolicyTiler(ErrorPolicySkipFeature, &failingExporter{})
+
+	var report *ErrorReport
+	if err := tiler.Tiler(); !errors.As(err, &report) {
+		t.Fatalf("应返回ErrorReport, 得到 %v", err)
+	}
+
+	exports := 0
+	for _, e := range report.Errors {
+		if e.Stage == StageExport {
+			exports++
+			if e.Feature != -1 || e.Layer != "" {
+				t.Errorf("导出错误不应包含要素信息: %+v", e)
+			}
+		}
+	}
+	if exports == 0 {
+		t.Error("应包含导出错误")
+	}
+}
+
+// TestErrorReport_WriteJSON 测试错误报告的JSON输出
+func TestErrorReport_WriteJSON(t *testing.T) {
+	report := &ErrorReport{Errors: []*TileError{
+		{Z: 3, X: 1, Y: 2, Layer: "roads", Feature: 7, Stage: StageReproject, Err: errors.New("bad srid")},
+		{Z: 3, X: 1, Y: 3, Feature: -1, Stage: StageExport, Err: errors.New("disk full")},
+	}}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() 错误 = %v", err)
+	}
+
+	var decoded struct {
+		Errors []struct {
+			Z       uint32 `json:"z"`
+			Layer   string `json:"layer"`
+			Feature int    `json:"feature"`
+			Stage   string `json:"stage"`
+			Error   string `json:"error"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("解析JSON失败: %v", err)
+	}
+	if len(decoded.Errors) != 2 {
+		t.Fatalf("错误数量 = %v, want 2", len(decoded.Errors))
+	}
+	first := decoded.Errors[0]
+	if first.Z != 3 || first.Layer != "roads" || first.Feature != 7 || first.Stage != "reproject" || first.Error != "bad srid" {
+		t.Errorf("第一个错误 = %+v", first)
+	}
+	if decoded.Errors[1].Stage != "export" {
+		t.Errorf("第二个错误阶段 = %v, want export", decoded.Errors[1].Stage)
+	}
+}
@@ -1,8 +1,94 @@
 package tile
 
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
 // Exporter 定义瓦片导出接口
 type Exporter interface {
 	SaveTile(res []*Layer, tile *Tile, path string) error
 	Extension() string
 	RelativeTilePath(zoom, x, y int) string
 }
+
+// TileRemover 可选接口，支持删除已导出的瓦片
+// 增量重新生成时用于清理不再有数据的瓦片
+type TileRemover interface {
+	RemoveTile(tile *Tile, path string) error
+}
+
+// SizedExporter 可选接口，保存瓦片并返回编码后的字节数(存储压缩前)
+// Tiler用于统计报告中的瓦片大小，未实现时大小记为0
+type SizedExporter interface {
+	SaveTileSize(res []*Layer, tile *Tile, path string) (int64, error)
+}
+
+// Finalizer 可选接口，Tiler全部瓦片导出后调用Finalize
+// 用于需要在生成结束时统一写出的导出器，如单文件归档。生成出错停止或被取消时不调用
+type Finalizer interface {
+	Finalize() error
+}
+
+// TilesetMetadata 瓦片集元数据，生成开始时传给TileSink.Open
+type TilesetMetadata struct {
+	// Name 瓦片集名称
+	Name string
+	// Format 瓦片格式，即编码器的扩展名(mvt、geojson、svg)
+	Format string
+	// MinZoom 最小级别
+	MinZoom uint32
+	// MaxZoom 最大级别，包括超级别生成的级别
+	MaxZoom uint32
+	// Bounds 经纬度范围[minLon, minLat, maxLon, maxLat]
+	Bounds [4]float64
+	// TileMatrixSet 瓦片网格，nil为Web墨卡托网格
+	TileMatrixSet *TileMatrixSet
+	// VectorLayers 各图层的属性字段和级别范围，只在MetadataUpdater.UpdateMetadata中给出
+	VectorLayers []VectorLayer
+	// Incremental 为true时本次运行只生成部分瓦片(Resume或Retile)，存储中已有的其余瓦片需要保留
+	Incremental bool
+}
+
+// Encoder 将瓦片的图层编码为字节数据
+type Encoder interface {
+	Encode(layers []*Layer, tile *Tile) ([]byte, error)
+	Extension() string
+}
+
+// TileSink 编码后瓦片的存储，如目录、归档或内存
+// Open在写入任何瓦片之前调用，WriteTile可被多个协程并发调用，Close释放资源并提交已写入的瓦片
+type TileSink interface {
+	Open(ctx context.Context, meta TilesetMetadata) error
+	WriteTile(ctx context.Context, z, x, y uint32, data []byte) error
+	Close() error
+}
+
+// TileDeleter 可选接口，TileSink支持删除已写入的瓦片
+type TileDeleter interface {
+	DeleteTile(ctx context.Context, z, x, y uint32) error
+}
+
+// MetadataUpdater 可选接口，全部瓦片写入后接收包含vector_layers的完整元数据
+type MetadataUpdater interface {
+	UpdateMetadata(meta TilesetMetadata) error
+}
+
+// ExporterLifecycle 可选接口，Tiler在生成开始时调用Open，结束时(包括出错和停止)调用Close
+// 同时实现Finalizer时，Finalize在Close之前调用
+type ExporterLifecycle interface {
+	Open(ctx context.Context, meta TilesetMetadata) error
+	Close() error
+}
+
+// removeTileFile 删除瓦片文件，文件不存在时不报错
+func removeTileFile(path string) error {
+	if path == "" {
+		return ErrInvalidPath
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除文件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package tile
+
+import (
+	"encoding/json"
+	"strconv"
+
+	geom "github.com/flywave/go-geom"
+)
+
+const (
+	// MinZoomProperty 要素最小可见级别的属性名
+	MinZoomProperty = "minzoom"
+	// MaxZoomProperty 要素最大可见级别的属性名
+	MaxZoomProperty = "maxzoom"
+	// TippecanoeProperty tippecanoe风格的嵌套属性名，值为包含minzoom/maxzoom的对象
+	TippecanoeProperty = "tippecanoe"
+)
+
+// FeatureZoomFunc 返回要素可见的级别范围
+// 没有限制的一端返回-1；ok为false表示要素在所有级别可见
+type FeatureZoomFunc func(f *geom.Feature) (minZoom, maxZoom int, ok bool)
+
+// DefaultFeatureZoom 从要素属性读取可见级别范围
+// 优先读取 "tippecanoe": {"minzoom": n, "maxzoom": n}，其次读取顶层的minzoom/maxzoom属性
+func DefaultFeatureZoom(f *geom.Feature) (minZoom, maxZoom int, ok bool) {
+	if f == nil || f.Properties == nil {
+		return -1, -1, false
+	}
+
+	props := f.Properties
+	if nested, isMap := props[TippecanoeProperty].(map[string]interface{}); isMap {
+		props = nested
+	}
+
+	minZoom, minOK := propertyAsInt(props[MinZoomProperty])
+	maxZoom, maxOK := propertyAsInt(props[MaxZoomProperty])
+	if !minOK {
+		minZoom = -1
+	}
+	if !maxOK {
+		maxZoom = -1
+	}
+	return minZoom, maxZoom, minOK || maxOK
+}
+
+// featureVisible 判断要素在给定级别是否输出
+func featureVisible(fn FeatureZoomFunc, f *geom.Feature, z uint32) bool {
+	minZoom, maxZoom, ok := fn(f)
+	if !ok {
+		return true
+	}
+	if minZoom >= 0 && int(z) < minZoom {
+		return false
+	}
+	return maxZoom < 0 || int(z) <= maxZoom
+}
+
+// featureVisibleRange 判断要素在[top, bottom]范围内的某个级别是否输出
+func featureVisibleRange(fn FeatureZoomFunc, f *geom.Feature, top, bottom uint32) bool {
+	minZoom, maxZoom, ok := fn(f)
+	if !ok {
+		return true
+	}
+	return minZoom <= int(bottom) && (maxZoom < 0 || maxZoom >= int(top))
+}
+
+// featureZoomFunc 返回图层使用的要素级别函数
+func (m *Tiler) featureZoomFunc(name string) FeatureZoomFunc {
+	if opts := m.config.LayerOptions[name]; opts != nil && opts.FeatureZoom != nil {
+		return opts.FeatureZoom
+	}
+	return DefaultFeatureZoom
+}
+
+// featureFilter 返回图层使用的要素筛选函数，没有设置时保留全部要素
+func (m *Tiler) featureFilter(name string) func(*geom.Feature) bool {
+	if opts := m.config.LayerOptions[name]; opts != nil && opts.Filter != nil {
+		return opts.Filter
+	}
+	return func(*geom.Feature) bool { return true }
+}
+
+// propertyAsInt 将属性值转换为整数
+func propertyAsInt(v interface{}) (int, bool) {
+	f, ok := propertyAsFloat(v)
+	return int(f), ok
+}
+
+// propertyAsFloat 将数值或数字字符串属性转换为浮点数
+func propertyAsFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,103 @@
+package tile
+
+import (
+	"encoding/json"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// TestDefaultFeatureZoom 测试从要素属性读取可见级别
+func TestDefaultFeatureZoom(t *testing.T) {
+	testCases := []struct {
+		name       string
+		properties map[string]interface{}
+		min, max   int
+		ok         bool
+	}{
+		{"无属性", nil, -1, -1, false},
+		{"无级别属性", map[string]interface{}{"name": "a"}, -1, -1, false},
+		{"顶层属性", map[string]interface{}{"minzoom": float64(3), "maxzoom": 7}, 3, 7, true},
+		{"仅最小级别", map[string]interface{}{"minzoom": "5"}, 5, -1, true},
+		{"tippecanoe嵌套", map[string]interface{}{
+			"minzoom":    1,
+			"tippecanoe": map[string]interface{}{"maxzoom": json.Number("9")},
+		}, -1, 9, true},
+		{"无效值", map[string]interface{}{"minzoom": "high"}, -1, -1, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			min, max, ok := DefaultFeatureZoom(&geom.Feature{Properties: tc.properties})
+			if min != tc.min || max != tc.max || ok != tc.ok {
+				t.Errorf("DefaultFeatureZoom() = (%v, %v, %v), want (%v, %v, %v)",
+					min, max, ok, tc.min, tc.max, tc.ok)
+			}
+		})
+	}
+}
+
+// TestTiler_processTileFeatureZoom 测试按要素级别范围过滤要素
+func TestTiler_processTileFeatureZoom(t *testing.T) {
+	newFeature := func(name string, props map[string]interface{}) *geom.Feature {
+		props["name"] = name
+		return &geom.Feature{Geometry: basic.Point{0, 0}, Properties: props}
+	}
+	layers := []*Layer{
+		{
+			Name: "places",
+			Features: []*geom.Feature{
+				newFeature("city", map[string]interface{}{"maxzoom": 4}),
+				newFeature("village", map[string]interface{}{"tippecanoe": map[string]interface{}{"minzoom": 5}}),
+			},
+		},
+		{
+			Name:     "roads",
+			Features: []*geom.Feature{newFeature("highway", map[string]interface{}{"minzoom": 10})},
+		},
+	}
+
+	exporter := &MockExporter{}
+	tiler := NewTiler(&Config{
+		Provider:   &MockProvider{layers: layers, srid: 4326},
+		Exporter:   exporter,
+		TileExtent: 4096,
+		LayerOptions: map[string]*LayerOptions{
+			// 自定义回调忽略属性，道路在所有级别可见
+			"roads": {FeatureZoom: func(*geom.Feature) (int, int, bool) { return -1, -1, false }},
+		},
+	})
+	defer tiler.Stop()
+
+	tiler.processTile(&tileTask{z: 2, x: 0, y: 0})
+	tiler.processTile(&tileTask{z: 6, x: 0, y: 0})
+
+	saved := exporter.GetSavedTiles()
+	if len(saved) != 2 {
+		t.Fatalf("导出瓦片数量 = %v, want 2", len(saved))
+	}
+	expected := map[uint32][]string{
+		2: {"city", "highway"},
+		6: {"village", "highway"},
+	}
+	for _, s := range saved {
+		var names []string
+		for _, l := range s.Layers {
+			for _, f := range l.Features {
+				names = append(names, f.Properties["name"].(string))
+			}
+		}
+		want := expected[s.Tile.Z]
+		if len(names) != len(want) {
+			t.Errorf("z=%d 要素 = %v, want %v", s.Tile.Z, names, want)
+			continue
+		}
+		for i := range want {
+			if names[i] != want[i] {
+				t.Errorf("z=%d 要素 = %v, want %v", s.Tile.Z, names, want)
+				break
+			}
+		}
+	}
+}
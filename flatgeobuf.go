@@ -0,0 +1,788 @@
+package tile
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	geom "github.com/flywave/go-geom"
+	gen "github.com/flywave/go-geom/general"
+	"github.com/flywave/go-vector-tiler/basic"
+	"github.com/flywave/go-vector-tiler/util"
+)
+
+// fgbMagic FlatGeobuf文件标识，第4个字节为主版本号
+var fgbMagic = []byte{'f', 'g', 'b', 3, 'f', 'g', 'b'}
+
+const (
+	// fgbNodeSize 打包Hilbert R树节点的字节数：外包框4个double加偏移量
+	fgbNodeSize = 40
+	// fgbDefaultIndexNodeSize 文件头未指定时的索引节点大小
+	fgbDefaultIndexNodeSize = 16
+	// fgbMaxHeaderSize 文件头的最大字节数
+	fgbMaxHeaderSize = 10 * 1024 * 1024
+	// fgbMaxFeatureSize 单个要素的最大字节数，无法确定文件长度时用于限制要素长度
+	fgbMaxFeatureSize = 256 * 1024 * 1024
+)
+
+// FlatGeobuf几何类型
+const (
+	fgbUnknown            = 0
+	fgbPoint              = 1
+	fgbLineString         = 2
+	fgbPolygon            = 3
+	fgbMultiPoint         = 4
+	fgbMultiLineString    = 5
+	fgbMultiPolygon       = 6
+	fgbGeometryCollection = 7
+)
+
+// FlatGeobuf属性列类型
+const (
+	fgbByte = iota
+	fgbUByte
+	fgbBool
+	fgbShort
+	fgbUShort
+	fgbInt
+	fgbUInt
+	fgbLong
+	fgbULong
+	fgbFloat
+	fgbDouble
+	fgbString
+	fgbJSON
+	fgbDateTime
+	fgbBinary
+)
+
+// FlatGeobufOptions FlatGeobuf数据源选项
+type FlatGeobufOptions struct {
+	// LayerName 图层名，为空时依次取文件头中的名称和文件名(不含扩展名)
+	LayerName string
+	// SRID 坐标的空间参考，为0时由文件头的坐标系确定，文件头没有坐标系时为WGS84
+	SRID uint64
+}
+
+// FlatGeobufProvider 读取FlatGeobuf文件的Provider
+// 有空间索引时按瓦片范围查询打包Hilbert R树，只读取相交的要素；没有索引时顺序扫描。
+// 数据不会整体读入内存，可以用于很大的文件。Z、M等维度被忽略，要素ID为要素在文件中的序号
+type FlatGeobufProvider struct {
+	r      io.ReaderAt
+	closer io.Closer
+	// size 文件长度，无法确定时为-1
+	size int64
+
+	name         string
+	srid         uint64
+	geometryType uint8
+	columns      []fgbColumn
+	count        uint64
+	nodeSize     uint64
+	// levels 索引各层节点的序号范围，第0层为叶子节点
+	levels         [][2]uint64
+	indexOffset    int64
+	featuresOffset int64
+}
+
+// fgbColumn 属性列
+type fgbColumn struct {
+	name string
+	kind uint8
+}
+
+// NewFlatGeobufProvider 由FlatGeobuf文件创建Provider，使用完后应调用Close
+func NewFlatGeobufProvider(path string) (*FlatGeobufProvider, error) {
+	return NewFlatGeobufProviderWithOptions(FlatGeobufOptions{}, path)
+}
+
+// NewFlatGeobufProviderWithOptions 使用自定义选项由FlatGeobuf文件创建Provider
+func NewFlatGeobufProviderWithOptions(options FlatGeobufOptions, path string) (*FlatGeobufProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取FlatGeobuf文件失败: %w", err)
+	}
+	if options.LayerName == "" {
+		base := filepath.Base(path)
+		options.LayerName = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	p, err := NewFlatGeobufReader(f, options)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("FlatGeobuf文件 %s: %w", path, err)
+	}
+	p.closer = f
+	return p, nil
+}
+
+// NewFlatGeobufReader 由io.ReaderAt创建Provider，只读取文件头
+// options.LayerName为空时使用文件头中的名称
+func NewFlatGeobufReader(r io.ReaderAt, options FlatGeobufOptions) (*FlatGeobufProvider, error) {
+	head := make([]byte, len(fgbMagic)+5)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFlatGeobuf, err)
+	}
+	if string(head[:3]) != string(fgbMagic[:3]) || head[3] != fgbMagic[3] || string(head[4:7]) != string(fgbMagic[4:]) {
+		return nil, fmt.Errorf("%w: 文件标识无效", ErrInvalidFlatGeobuf)
+	}
+	headerSize := binary.LittleEndian.Uint32(head[8:])
+	if headerSize < 8 || headerSize > fgbMaxHeaderSize {
+		return nil, fmt.Errorf("%w: 文件头长度无效", ErrInvalidFlatGeobuf)
+	}
+	buf := make([]byte, headerSize)
+	if _, err := r.ReadAt(buf, 12); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFlatGeobuf, err)
+	}
+
+	p := &FlatGeobufProvider{r: r, size: readerSize(r), indexOffset: 12 + int64(headerSize)}
+	var crsOrg string
+	var crsCode int32
+	h := fgbRoot(buf)
+	p.name = h.string(0)
+	p.geometryType = h.uint8(2, fgbUnknown)
+	p.columns = fgbColumns(h, 7)
+	p.count = h.uint64(8, 0)
+	p.nodeSize = uint64(h.uint16(9, fgbDefaultIndexNodeSize))
+	if crs, ok := h.table(10); ok {
+		crsOrg = crs.string(0)
+		crsCode = crs.int32(1, 0)
+	}
+	if err := h.err(); err != nil {
+		return nil, fmt.Errorf("文件头: %w", err)
+	}
+
+	if options.LayerName != "" {
+		p.name = options.LayerName
+	}
+	if p.srid = options.SRID; p.srid == 0 {
+		var err error
+		if p.srid, err = fgbSRID(crsOrg, crsCode); err != nil {
+			return nil, err
+		}
+	}
+
+	p.featuresOffset = p.indexOffset
+	if p.nodeSize > 0 && p.count > 0 {
+		if p.nodeSize < 2 {
+			return nil, fmt.Errorf("%w: 索引节点大小无效", ErrInvalidFlatGeobuf)
+		}
+		p.levels = fgbLevelBounds(p.count, p.nodeSize)
+		p.featuresOffset += int64(p.levels[0][1]) * fgbNodeSize
+	}
+	return p, nil
+}
+
+// readerSize 返回数据的长度，无法确定时返回-1
+func readerSize(r io.ReaderAt) int64 {
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return r.Size()
+	case interface{ Stat() (os.FileInfo, error) }:
+		if info, err := r.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	}
+	return -1
+}
+
+// fgbSRID 由文件头的坐标系确定SRID，支持EPSG代码表示的坐标系
+func fgbSRID(org string, code int32) (uint64, error) {
+	if code == 0 {
+		return util.WGS84, nil
+	}
+	if (org != "" && !strings.EqualFold(org, "EPSG")) || code < 0 {
+		return 0, fmt.Errorf("%w: %s:%d", ErrUnsupportedProjection, org, code)
+	}
+	if code == 900913 {
+		return util.WebMercator, nil
+	}
+	if err := basic.CheckSRID(uint64(code)); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUnsupportedProjection, err)
+	}
+	return uint64(code), nil
+}
+
+// fgbLevelBounds 计算打包R树各层节点的序号范围
+// 节点按从根到叶子的顺序存放，返回的第0层为叶子节点，最后一层为根节点
+func fgbLevelBounds(count, nodeSize uint64) [][2]uint64 {
+	n := count
+	levelNodes := []uint64{n}
+	total := n
+	for {
+		n = (n + nodeSize - 1) / nodeSize
+		levelNodes = append(levelNodes, n)
+		total += n
+		if n == 1 {
+			break
+		}
+	}
+
+	levels := make([][2]uint64, len(levelNodes))
+	end := total
+	for i, size := range levelNodes {
+		levels[i] = [2]uint64{end - size, end}
+		end -= size
+	}
+	return levels
+}
+
+// Close 关闭文件
+func (p *FlatGeobufProvider) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+	return p.closer.Close()
+}
+
+// GetSrid 返回坐标的空间参考
+func (p *FlatGeobufProvider) GetSrid() uint64 {
+	return p.srid
+}
+
+// GetDataByTile 返回与带缓冲区瓦片范围相交的要素，读取出错时返回nil
+func (p *FlatGeobufProvider) GetDataByTile(t *Tile) []*Layer {
+	layers, _ := CollectLayers(context.Background(), p, t)
+	return layers
+}
+
+// Features 依次回调与带缓冲区瓦片范围相交的要素
+func (p *FlatGeobufProvider) Features(ctx context.Context, t *Tile, fn func(layer string, f *geom.Feature) error) error {
+	box, err := tileBoundsInSRID(t, p.srid)
+	if err != nil {
+		return err
+	}
+	if p.levels == nil {
+		return p.scan(ctx, box, fn)
+	}
+
+	hits, err := p.search(ctx, box)
+	if err != nil {
+		return err
+	}
+	for _, hit := range hits {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f, _, err := p.readFeature(p.featuresOffset+int64(hit.offset), hit.index)
+		if err != nil {
+			return err
+		}
+		if err := fn(p.name, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fgbHit 索引查询命中的要素
+type fgbHit struct {
+	// offset 要素相对要素区起点的偏移量
+	offset uint64
+	index  uint64
+}
+
+// search 查询与box相交的要素，按要素在文件中的顺序返回
+func (p *FlatGeobufProvider) search(ctx context.Context, box [4]float64) ([]fgbHit, error) {
+	type pending struct {
+		node  uint64
+		level int
+	}
+	queue := []pending{{node: 0, level: len(p.levels) - 1}}
+	leaves := p.levels[0]
+
+	var hits []fgbHit
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		next := queue[0]
+		queue = queue[1:]
+
+		end := min(next.node+p.nodeSize, p.levels[next.level][1])
+		if next.node >= end {
+			return nil, fmt.Errorf("%w: 索引节点无效", ErrInvalidFlatGeobuf)
+		}
+		buf := make([]byte, (end-next.node)*fgbNodeSize)
+		if _, err := p.r.ReadAt(buf, p.indexOffset+int64(next.node)*fgbNodeSize); err != nil {
+			return nil, fmt.Errorf("读取FlatGeobuf索引失败: %w", err)
+		}
+
+		for i := uint64(0); i < end-next.node; i++ {
+			b := buf[i*fgbNodeSize:]
+			node := [4]float64{
+				math.Float64frombits(binary.LittleEndian.Uint64(b[0:])),
+				math.Float64frombits(binary.LittleEndian.Uint64(b[8:])),
+				math.Float64frombits(binary.LittleEndian.Uint64(b[16:])),
+				math.Float64frombits(binary.LittleEndian.Uint64(b[24:])),
+			}
+			if !boxesIntersect(node, box) {
+				continue
+			}
+			offset := binary.LittleEndian.Uint64(b[32:])
+			if next.level == 0 {
+				hits = append(hits, fgbHit{offset: offset, index: next.node + i - leaves[0]})
+			} else {
+				queue = append(queue, pending{node: offset, level: next.level - 1})
+			}
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool { return hits[i].offset < hits[j].offset })
+	return hits, nil
+}
+
+// scan 没有索引时顺序读取全部要素，回调与box相交的要素
+func (p *FlatGeobufProvider) scan(ctx context.Context, box [4]float64, fn func(layer string, f *geom.Feature) error) error {
+	offset := p.featuresOffset
+	for index := uint64(0); ; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f, next, err := p.readFeature(offset, index)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		offset = next
+
+		if f.Geometry == nil {
+			continue
+		}
+		bounds, ok, err := geometryBounds(f.Geometry)
+		if err != nil {
+			return err
+		}
+		if ok && boxesIntersect(bounds, box) {
+			if err := fn(p.name, f); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readFeature 读取offset处的要素，返回要素和下一个要素的位置
+// offset位于文件末尾时返回io.EOF
+func (p *FlatGeobufProvider) readFeature(offset int64, index uint64) (*geom.Feature, int64, error) {
+	var size [4]byte
+	if n, err := p.r.ReadAt(size[:], offset); err != nil {
+		if n == 0 && errors.Is(err, io.EOF) {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, fmt.Errorf("读取FlatGeobuf要素 %d 失败: %w", index, unexpectedEOF(err))
+	}
+	n := int64(binary.LittleEndian.Uint32(size[:]))
+	if n > fgbMaxFeatureSize || (p.size >= 0 && n > p.size-offset-4) {
+		return nil, 0, fmt.Errorf("%w: 要素 %d 的长度%d超出文件范围", ErrInvalidFlatGeobuf, index, n)
+	}
+	buf := make([]byte, n)
+	if _, err := p.r.ReadAt(buf, offset+4); err != nil {
+		return nil, 0, fmt.Errorf("读取FlatGeobuf要素 %d 失败: %w", index, unexpectedEOF(err))
+	}
+
+	f, err := p.decodeFeature(buf, index)
+	if err != nil {
+		return nil, 0, fmt.Errorf("FlatGeobuf要素 %d: %w", index, err)
+	}
+	return f, offset + 4 + int64(len(buf)), nil
+}
+
+// decodeFeature 解析要素缓冲区
+func (p *FlatGeobufProvider) decodeFeature(buf []byte, index uint64) (*geom.Feature, error) {
+	f := &geom.Feature{ID: index, Type: "Feature"}
+	t := fgbRoot(buf)
+	if g, ok := t.table(0); ok {
+		var err error
+		if f.Geometry, err = fgbGeometry(g, p.geometryType); err != nil {
+			return nil, err
+		}
+	}
+	columns := p.columns
+	if c := fgbColumns(t, 2); len(c) > 0 {
+		columns = c
+	}
+	properties := t.bytes(1)
+	if err := t.err(); err != nil {
+		return nil, err
+	}
+	var err error
+	if f.Properties, err = fgbProperties(properties, columns); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// unexpectedEOF 将要素中途遇到的文件结尾转换为io.ErrUnexpectedEOF，与正常读完所有要素区分
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// fgbBuffer FlatBuffers缓冲区，读取前检查偏移量，越界时记录第一个错误并返回零值
+type fgbBuffer struct {
+	b   []byte
+	err error
+}
+
+// slice 返回off处n个字节，越界时返回nil
+func (b *fgbBuffer) slice(off, n uint64) []byte {
+	if b.err != nil {
+		return nil
+	}
+	if off > uint64(len(b.b)) || n > uint64(len(b.b))-off {
+		b.err = fmt.Errorf("%w: 偏移量 %d 长度 %d 超出缓冲区", ErrInvalidFlatGeobuf, off, n)
+		return nil
+	}
+	return b.b[off : off+n]
+}
+
+func (b *fgbBuffer) uint16(off uint64) uint16 {
+	if v := b.slice(off, 2); v != nil {
+		return binary.LittleEndian.Uint16(v)
+	}
+	return 0
+}
+
+func (b *fgbBuffer) uint32(off uint64) uint32 {
+	if v := b.slice(off, 4); v != nil {
+		return binary.LittleEndian.Uint32(v)
+	}
+	return 0
+}
+
+// indirect 返回off处相对偏移量指向的位置
+func (b *fgbBuffer) indirect(off uint64) uint64 {
+	return off + uint64(b.uint32(off))
+}
+
+// fgbTable FlatBuffers表，字段按声明顺序编号
+type fgbTable struct {
+	buf *fgbBuffer
+	pos uint64
+}
+
+// fgbRoot 返回缓冲区的根表
+func fgbRoot(buf []byte) fgbTable {
+	b := &fgbBuffer{b: buf}
+	return fgbTable{buf: b, pos: b.indirect(0)}
+}
+
+// err 返回读取表及其子表时遇到的第一个错误
+func (t fgbTable) err() error {
+	return t.buf.err
+}
+
+// field 返回字段的绝对位置，字段不存在或表无效时返回0
+func (t fgbTable) field(i int) uint64 {
+	vtable := int64(t.pos) - int64(int32(t.buf.uint32(t.pos)))
+	if t.buf.err != nil {
+		return 0
+	}
+	if vtable < 0 {
+		t.buf.err = fmt.Errorf("%w: 虚表偏移量 %d 无效", ErrInvalidFlatGeobuf, vtable)
+		return 0
+	}
+	entry := uint64(4 + 2*i)
+	if entry+2 > uint64(t.buf.uint16(uint64(vtable))) {
+		return 0
+	}
+	o := t.buf.uint16(uint64(vtable) + entry)
+	if o == 0 {
+		return 0
+	}
+	return t.pos + uint64(o)
+}
+
+func (t fgbTable) uint8(i int, def uint8) uint8 {
+	if o := t.field(i); o != 0 {
+		if v := t.buf.slice(o, 1); v != nil {
+			return v[0]
+		}
+	}
+	return def
+}
+
+func (t fgbTable) uint16(i int, def uint16) uint16 {
+	if o := t.field(i); o != 0 {
+		return t.buf.uint16(o)
+	}
+	return def
+}
+
+func (t fgbTable) int32(i int, def int32) int32 {
+	if o := t.field(i); o != 0 {
+		return int32(t.buf.uint32(o))
+	}
+	return def
+}
+
+func (t fgbTable) uint64(i int, def uint64) uint64 {
+	if o := t.field(i); o != 0 {
+		if v := t.buf.slice(o, 8); v != nil {
+			return binary.LittleEndian.Uint64(v)
+		}
+	}
+	return def
+}
+
+func (t fgbTable) bytes(i int) []byte {
+	return t.vector(i, 1)
+}
+
+func (t fgbTable) string(i int) string {
+	return string(t.bytes(i))
+}
+
+// vector 返回每个元素size字节的向量数据，字段不存在或越界时返回nil
+func (t fgbTable) vector(i int, size uint64) []byte {
+	o := t.field(i)
+	if o == 0 {
+		return nil
+	}
+	o = t.buf.indirect(o)
+	n := uint64(t.buf.uint32(o))
+	return t.buf.slice(o+4, n*size)
+}
+
+func (t fgbTable) table(i int) (fgbTable, bool) {
+	o := t.field(i)
+	if o == 0 {
+		return fgbTable{}, false
+	}
+	pos := t.buf.indirect(o)
+	return fgbTable{buf: t.buf, pos: pos}, t.buf.err == nil
+}
+
+func (t fgbTable) tables(i int) []fgbTable {
+	o := t.field(i)
+	if o == 0 {
+		return nil
+	}
+	start := t.buf.indirect(o) + 4
+	offsets := t.vector(i, 4)
+	tables := make([]fgbTable, len(offsets)/4)
+	for k := range tables {
+		tables[k] = fgbTable{buf: t.buf, pos: t.buf.indirect(start + uint64(4*k))}
+	}
+	return tables
+}
+
+func (t fgbTable) float64s(i int) []float64 {
+	b := t.vector(i, 8)
+	values := make([]float64, len(b)/8)
+	for k := range values {
+		values[k] = math.Float64frombits(binary.LittleEndian.Uint64(b[8*k:]))
+	}
+	return values
+}
+
+func (t fgbTable) uint32s(i int) []uint32 {
+	b := t.vector(i, 4)
+	values := make([]uint32, len(b)/4)
+	for k := range values {
+		values[k] = binary.LittleEndian.Uint32(b[4*k:])
+	}
+	return values
+}
+
+// fgbColumns 读取属性列定义
+func fgbColumns(t fgbTable, i int) []fgbColumn {
+	var columns []fgbColumn
+	for _, c := range t.tables(i) {
+		columns = append(columns, fgbColumn{name: c.string(0), kind: c.uint8(1, fgbByte)})
+	}
+	return columns
+}
+
+// fgbGeometry 解析几何表，geometryType为文件头中的几何类型，未知时使用几何表中的类型
+func fgbGeometry(g fgbTable, geometryType uint8) (geom.Geometry, error) {
+	if geometryType == fgbUnknown {
+		geometryType = g.uint8(6, fgbUnknown)
+	}
+
+	switch geometryType {
+	case fgbMultiPolygon:
+		var polygons [][][][]float64
+		for _, part := range g.tables(7) {
+			rings, err := fgbRings(part)
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, rings)
+		}
+		if err := g.err(); err != nil {
+			return nil, err
+		}
+		return gen.NewMultiPolygon(polygons), nil
+	case fgbGeometryCollection:
+		var geoms []geom.Geometry
+		for _, part := range g.tables(7) {
+			pg, err := fgbGeometry(part, fgbUnknown)
+			if err != nil {
+				return nil, err
+			}
+			if pg != nil {
+				geoms = append(geoms, pg)
+			}
+		}
+		if err := g.err(); err != nil {
+			return nil, err
+		}
+		return gen.NewGeometryCollection(geoms...), nil
+	}
+
+	xy := g.float64s(1)
+	if err := g.err(); err != nil {
+		return nil, err
+	}
+	if len(xy) < 2 {
+		return nil, nil
+	}
+	switch geometryType {
+	case fgbPoint:
+		return gen.NewPoint([]float64{xy[0], xy[1]}), nil
+	case fgbMultiPoint:
+		return gen.NewMultiPoint(fgbPoints(xy)), nil
+	case fgbLineString:
+		return gen.NewLineString(fgbPoints(xy)), nil
+	case fgbMultiLineString:
+		lines, err := fgbRings(g)
+		if err != nil {
+			return nil, err
+		}
+		return gen.NewMultiLineString(lines), nil
+	case fgbPolygon:
+		rings, err := fgbRings(g)
+		if err != nil {
+			return nil, err
+		}
+		return gen.NewPolygon(rings), nil
+	}
+	return nil, fmt.Errorf("%w: 不支持的几何类型 %d", ErrInvalidFlatGeobuf, geometryType)
+}
+
+// fgbPoints 将坐标数组转换为点列表
+func fgbPoints(xy []float64) [][]float64 {
+	pts := make([][]float64, len(xy)/2)
+	for i := range pts {
+		pts[i] = []float64{xy[2*i], xy[2*i+1]}
+	}
+	return pts
+}
+
+// fgbRings 按ends将坐标拆分为多个环或线，没有ends时为一个
+func fgbRings(g fgbTable) ([][][]float64, error) {
+	pts := fgbPoints(g.float64s(1))
+	ends := g.uint32s(0)
+	if err := g.err(); err != nil {
+		return nil, err
+	}
+	if len(ends) == 0 {
+		return [][][]float64{pts}, nil
+	}
+	rings := make([][][]float64, 0, len(ends))
+	start := 0
+	for _, end := range ends {
+		if int(end) < start || int(end) > len(pts) {
+			return nil, fmt.Errorf("%w: 环结束位置 %d 超出坐标范围", ErrInvalidFlatGeobuf, end)
+		}
+		rings = append(rings, pts[start:end])
+		start = int(end)
+	}
+	return rings, nil
+}
+
+// fgbPropertySizes 定长属性类型的字节数
+var fgbPropertySizes = map[uint8]int{
+	fgbByte:   1,
+	fgbUByte:  1,
+	fgbBool:   1,
+	fgbShort:  2,
+	fgbUShort: 2,
+	fgbInt:    4,
+	fgbUInt:   4,
+	fgbLong:   8,
+	fgbULong:  8,
+	fgbFloat:  4,
+	fgbDouble: 8,
+}
+
+// fgbProperties 按属性列定义解析属性
+// 属性依次为uint16列序号和值，字符串、JSON、时间和二进制值前有uint32长度
+func fgbProperties(b []byte, columns []fgbColumn) (map[string]interface{}, error) {
+	properties := make(map[string]interface{})
+	for pos := 0; pos < len(b); {
+		if len(b)-pos < 2 {
+			return nil, fmt.Errorf("%w: 属性在位置 %d 被截断", ErrInvalidFlatGeobuf, pos)
+		}
+		index := int(binary.LittleEndian.Uint16(b[pos:]))
+		pos += 2
+		if index >= len(columns) {
+			return nil, fmt.Errorf("%w: 属性列序号 %d 超出范围", ErrInvalidFlatGeobuf, index)
+		}
+		c := columns[index]
+
+		size, fixed := fgbPropertySizes[c.kind]
+		switch {
+		case fixed:
+		case c.kind == fgbString || c.kind == fgbJSON || c.kind == fgbDateTime || c.kind == fgbBinary:
+			if len(b)-pos < 4 {
+				return nil, fmt.Errorf("%w: 属性%s在位置 %d 被截断", ErrInvalidFlatGeobuf, c.name, pos)
+			}
+			n := uint64(binary.LittleEndian.Uint32(b[pos:]))
+			if n > uint64(len(b)-pos-4) {
+				return nil, fmt.Errorf("%w: 属性%s的长度%d超出范围", ErrInvalidFlatGeobuf, c.name, n)
+			}
+			pos += 4
+			size = int(n)
+		default:
+			return nil, fmt.Errorf("%w: 不支持的属性类型 %d", ErrInvalidFlatGeobuf, c.kind)
+		}
+		if len(b)-pos < size {
+			return nil, fmt.Errorf("%w: 属性%s在位置 %d 被截断", ErrInvalidFlatGeobuf, c.name, pos)
+		}
+		v := b[pos : pos+size]
+		pos += size
+
+		switch c.kind {
+		case fgbByte:
+			properties[c.name] = int64(int8(v[0]))
+		case fgbUByte:
+			properties[c.name] = int64(v[0])
+		case fgbBool:
+			properties[c.name] = v[0] != 0
+		case fgbShort:
+			properties[c.name] = int64(int16(binary.LittleEndian.Uint16(v)))
+		case fgbUShort:
+			properties[c.name] = int64(binary.LittleEndian.Uint16(v))
+		case fgbInt:
+			properties[c.name] = int64(int32(binary.LittleEndian.Uint32(v)))
+		case fgbUInt:
+			properties[c.name] = int64(binary.LittleEndian.Uint32(v))
+		case fgbLong:
+			properties[c.name] = int64(binary.LittleEndian.Uint64(v))
+		case fgbULong:
+			properties[c.name] = binary.LittleEndian.Uint64(v)
+		case fgbFloat:
+			properties[c.name] = float64(math.Float32frombits(binary.LittleEndian.Uint32(v)))
+		case fgbDouble:
+			properties[c.name] = math.Float64frombits(binary.LittleEndian.Uint64(v))
+		case fgbString, fgbJSON, fgbDateTime:
+			properties[c.name] = string(v)
+		case fgbBinary:
+			properties[c.name] = append([]byte(nil), v...)
+		}
+	}
+	return properties, nil
+}
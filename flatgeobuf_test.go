@@ -0,0 +1,451 @@
+package tile
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// fgbTestGeometry 测试用几何
+type fgbTestGeometry struct {
+	kind  uint8
+	xy    []float64
+	ends  []uint32
+	parts []fgbTestGeometry
+}
+
+// fgbTestFeature 测试用要素
+type fgbTestFeature struct {
+	geometry   fgbTestGeometry
+	properties []byte
+}
+
+// fgbTestFile 测试用FlatGeobuf文件
+type fgbTestFile struct {
+	name         string
+	geometryType uint8
+	columns      []fgbColumn
+	crsOrg       string
+	crsCode      int32
+	nodeSize     uint16
+	features     []fgbTestFeature
+}
+
+// buildGeometry 写入几何表
+func (g fgbTestGeometry) build(b *flatbuffers.Builder) flatbuffers.UOffsetT {
+	var parts []flatbuffers.UOffsetT
+	for _, p := range g.parts {
+		parts = append(parts, p.build(b))
+	}
+	var partsOff, endsOff, xyOff flatbuffers.UOffsetT
+	if len(parts) > 0 {
+		b.StartVector(4, len(parts), 4)
+		for i := len(parts) - 1; i >= 0; i-- {
+			b.PrependUOffsetT(parts[i])
+		}
+		partsOff = b.EndVector(len(parts))
+	}
+	if len(g.ends) > 0 {
+		b.StartVector(4, len(g.ends), 4)
+		for i := len(g.ends) - 1; i >= 0; i-- {
+			b.PrependUint32(g.ends[i])
+		}
+		endsOff = b.EndVector(len(g.ends))
+	}
+	if len(g.xy) > 0 {
+		b.StartVector(8, len(g.xy), 8)
+		for i := len(g.xy) - 1; i >= 0; i-- {
+			b.PrependFloat64(g.xy[i])
+		}
+		xyOff = b.EndVector(len(g.xy))
+	}
+
+	b.StartObject(8)
+	if endsOff != 0 {
+		b.PrependUOffsetTSlot(0, endsOff, 0)
+	}
+	if xyOff != 0 {
+		b.PrependUOffsetTSlot(1, xyOff, 0)
+	}
+	b.PrependByteSlot(6, g.kind, 0)
+	if partsOff != 0 {
+		b.PrependUOffsetTSlot(7, partsOff, 0)
+	}
+	return b.EndObject()
+}
+
+// bounds 返回几何的外包框
+func (g fgbTestGeometry) bounds() [4]float64 {
+	box := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for i := 0; i+1 < len(g.xy); i += 2 {
+		box = [4]float64{min(box[0], g.xy[i]), min(box[1], g.xy[i+1]), max(box[2], g.xy[i]), max(box[3], g.xy[i+1])}
+	}
+	for _, p := range g.parts {
+		pb := p.bounds()
+		box = [4]float64{min(box[0], pb[0]), min(box[1], pb[1]), max(box[2], pb[2]), max(box[3], pb[3])}
+	}
+	return box
+}
+
+// header 生成文件头
+func (f fgbTestFile) header() []byte {
+	b := flatbuffers.NewBuilder(0)
+	name := b.CreateString(f.name)
+	var columns []flatbuffers.UOffsetT
+	for _, c := range f.columns {
+		n := b.CreateString(c.name)
+		b.StartObject(11)
+		b.PrependUOffsetTSlot(0, n, 0)
+		b.PrependByteSlot(1, c.kind, 0)
+		columns = append(columns, b.EndObject())
+	}
+	b.StartVector(4, len(columns), 4)
+	for i := len(columns) - 1; i >= 0; i-- {
+		b.PrependUOffsetT(columns[i])
+	}
+	columnsOff := b.EndVector(len(columns))
+	var crs flatbuffers.UOffsetT
+	if f.crsCode != 0 {
+		org := b.CreateString(f.crsOrg)
+		b.StartObject(6)
+		b.PrependUOffsetTSlot(0, org, 0)
+		b.PrependInt32Slot(1, f.crsCode, 0)
+		crs = b.EndObject()
+	}
+
+	b.StartObject(14)
+	b.PrependUOffsetTSlot(0, name, 0)
+	b.PrependByteSlot(2, f.geometryType, 0)
+	b.PrependUOffsetTSlot(7, columnsOff, 0)
+	b.PrependUint64Slot(8, uint64(len(f.features)), 0)
+	b.PrependUint16Slot(9, f.nodeSize, fgbDefaultIndexNodeSize)
+	if crs != 0 {
+		b.PrependUOffsetTSlot(10, crs, 0)
+	}
+	b.Finish(b.EndObject())
+	return b.FinishedBytes()
+}
+
+// bytes 生成完整的文件内容，nodeSize大于0时写入打包R树索引
+func (f fgbTestFile) bytes() []byte {
+	var features bytes.Buffer
+	var offsets []uint64
+	for _, feature := range f.features {
+		b := flatbuffers.NewBuilder(0)
+		g := feature.geometry.build(b)
+		var props flatbuffers.UOffsetT
+		if len(feature.properties) > 0 {
+			props = b.CreateByteVector(feature.properties)
+		}
+		b.StartObject(3)
+		b.PrependUOffsetTSlot(0, g, 0)
+		if props != 0 {
+			b.PrependUOffsetTSlot(1, props, 0)
+		}
+		b.Finish(b.EndObject())
+
+		offsets = append(offsets, uint64(features.Len()))
+		binary.Write(&features, binary.LittleEndian, uint32(len(b.FinishedBytes())))
+		features.Write(b.FinishedBytes())
+	}
+
+	var out bytes.Buffer
+	out.Write(fgbMagic)
+	out.WriteByte(0)
+	header := f.header()
+	binary.Write(&out, binary.LittleEndian, uint32(len(header)))
+	out.Write(header)
+
+	if f.nodeSize > 0 && len(f.features) > 0 {
+		levels := fgbLevelBounds(uint64(len(f.features)), uint64(f.nodeSize))
+		type node struct {
+			box    [4]float64
+			offset uint64
+		}
+		nodes := make([]node, levels[0][1])
+		for i, feature := range f.features {
+			nodes[levels[0][0]+uint64(i)] = node{feature.geometry.bounds(), offsets[i]}
+		}
+		for l := 1; l < len(levels); l++ {
+			for j := levels[l][0]; j < levels[l][1]; j++ {
+				first := levels[l-1][0] + (j-levels[l][0])*uint64(f.nodeSize)
+				last := min(first+uint64(f.nodeSize), levels[l-1][1])
+				n := node{box: nodes[first].box, offset: first}
+				for _, c := range nodes[first+1 : last] {
+					n.box = [4]float64{min(n.box[0], c.box[0]), min(n.box[1], c.box[1]), max(n.box[2], c.box[2]), max(n.box[3], c.box[3])}
+				}
+				nodes[j] = n
+			}
+		}
+		for _, n := range nodes {
+			binary.Write(&out, binary.LittleEndian, n.box)
+			binary.Write(&out, binary.LittleEndian, n.offset)
+		}
+	}
+
+	out.Write(features.Bytes())
+	return out.Bytes()
+}
+
+// fgbTestProperties 编码属性
+func fgbTestProperties(values ...interface{}) []byte {
+	var b bytes.Buffer
+	for i, v := range values {
+		binary.Write(&b, binary.LittleEndian, uint16(i))
+		switch v := v.(type) {
+		case string:
+			binary.Write(&b, binary.LittleEndian, uint32(len(v)))
+			b.WriteString(v)
+		default:
+			binary.Write(&b, binary.LittleEndian, v)
+		}
+	}
+	return b.Bytes()
+}
+
+// TestFlatGeobufProvider 测试使用索引和顺序扫描查询要素
+func TestFlatGeobufProvider(t *testing.T) {
+	point := func(x, y float64) fgbTestGeometry {
+		return fgbTestGeometry{kind: fgbPoint, xy: []float64{x, y}}
+	}
+	file := fgbTestFile{
+		name:    "places",
+		crsCode: 4326,
+		columns: []fgbColumn{{"name", fgbString}, {"pop", fgbInt}, {"area", fgbDouble}, {"capital", fgbBool}},
+		features: []fgbTestFeature{
+			{point(116.4, 39.9), fgbTestProperties("beijing", int32(21000000), 16410.5, uint8(1))},
+			{point(-0.1, 51.5), fgbTestProperties("london", int32(9000000))},
+			{fgbTestGeometry{kind: fgbPolygon, ends: []uint32{5, 10},
+				xy: []float64{110, 30, 120, 30, 120, 45, 110, 45, 110, 30, 112, 32, 114, 32, 114, 34, 112, 34, 112, 32}}, fgbTestProperties("region")},
+			{fgbTestGeometry{kind: fgbMultiPolygon, parts: []fgbTestGeometry{
+				{kind: fgbPolygon, xy: []float64{0, 0, 1, 0, 1, 1, 0, 0}},
+				{kind: fgbPolygon, xy: []float64{2, 2, 3, 2, 3, 3, 2, 2}},
+			}}, nil},
+		},
+	}
+
+	for _, nodeSize := range []uint16{16, 0} {
+		file.nodeSize = nodeSize
+		path := filepath.Join(t.TempDir(), "places.fgb")
+		if err := os.WriteFile(path, file.bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		provider, err := NewFlatGeobufProvider(path)
+		if err != nil {
+			t.Fatalf("NewFlatGeobufProvider() 错误 = %v", err)
+		}
+		defer provider.Close()
+		if provider.GetSrid() != 4326 {
+			t.Errorf("GetSrid() = %v, want 4326", provider.GetSrid())
+		}
+
+		layers := provider.GetDataByTile(NewTile(0, 0, 0))
+		if len(layers) != 1 || layers[0].Name != "places" || len(layers[0].Features) != 4 {
+			t.Fatalf("nodeSize=%d GetDataByTile() = %+v, want places图层4个要素", nodeSize, layers)
+		}
+		beijing := layers[0].Features[0]
+		want := map[string]interface{}{"name": "beijing", "pop": int64(21000000), "area": 16410.5, "capital": true}
+		for k, v := range want {
+			if beijing.Properties[k] != v {
+				t.Errorf("属性 %s = %#v, want %#v", k, beijing.Properties[k], v)
+			}
+		}
+		if pt, ok := beijing.Geometry.(geom.Point); !ok || pt.X() != 116.4 || pt.Y() != 39.9 {
+			t.Errorf("点 = %#v", beijing.Geometry)
+		}
+		if poly, ok := layers[0].Features[2].Geometry.(geom.Polygon); !ok || len(poly.Data()) != 2 {
+			t.Errorf("多边形 = %#v, want 带洞的多边形", layers[0].Features[2].Geometry)
+		}
+		if mp, ok := layers[0].Features[3].Geometry.(geom.MultiPolygon); !ok || len(mp.Data()) != 2 {
+			t.Errorf("多多边形 = %#v", layers[0].Features[3].Geometry)
+		}
+
+		// 北京附近的瓦片只返回点和包含它的多边形
+		layers = provider.GetDataByTile(NewTileLatLong(8, 39.9, 116.4))
+		if len(layers) != 1 || len(layers[0].Features) != 2 ||
+			layers[0].Features[0].ID != uint64(0) || layers[0].Features[1].ID != uint64(2) {
+			t.Errorf("nodeSize=%d 北京瓦片 = %+v, want 要素0和2", nodeSize, layers)
+		}
+	}
+}
+
+// TestFlatGeobufProvider_Index 测试多层索引的查询结果与逐个比较一致
+func TestFlatGeobufProvider_Index(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	file := fgbTestFile{name: "points", geometryType: fgbPoint, crsCode: 3857, nodeSize: 4}
+	var points [][2]float64
+	for i := 0; i < 300; i++ {
+		pt := [2]float64{(r.Float64()*2 - 1) * 2e7, (r.Float64()*2 - 1) * 2e7}
+		points = append(points, pt)
+		file.features = append(file.features, fgbTestFeature{geometry: fgbTestGeometry{xy: pt[:]}})
+	}
+
+	provider, err := NewFlatGeobufReader(bytes.NewReader(file.bytes()), FlatGeobufOptions{})
+	if err != nil {
+		t.Fatalf("NewFlatGeobufReader() 错误 = %v", err)
+	}
+	if len(provider.levels) != 6 {
+		t.Fatalf("索引层数 = %v, want 6", len(provider.levels))
+	}
+
+	for _, tile := range []*Tile{NewTile(2, 1, 1), NewTile(3, 5, 2), NewTile(4, 0, 15)} {
+		var got, want []int
+		err := provider.Features(context.Background(), tile, func(layer string, f *geom.Feature) error {
+			got = append(got, int(f.ID.(uint64)))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Features() 错误 = %v", err)
+		}
+		box := tileMercatorBounds(tile)
+		for i, pt := range points {
+			if boxesIntersect([4]float64{pt[0], pt[1], pt[0], pt[1]}, box) {
+				want = append(want, i)
+			}
+		}
+		sort.Ints(got)
+		if len(got) != len(want) {
+			t.Fatalf("瓦片 %v 要素 = %v, want %v", tile.ToString(), got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("瓦片 %v 要素 = %v, want %v", tile.ToString(), got, want)
+			}
+		}
+	}
+}
+
+// TestFlatGeobufProvider_SRID 测试文件头中的EPSG坐标系
+func TestFlatGeobufProvider_SRID(t *testing.T) {
+	for _, tt := range []struct {
+		org  string
+		code int32
+		want uint64
+	}{
+		{"", 0, 4326},
+		{"EPSG", 3857, 3857},
+		{"epsg", 900913, 3857},
+		{"EPSG", 32650, 32650},
+	} {
+		file := fgbTestFile{name: "srid", crsOrg: tt.org, crsCode: tt.code}
+		provider, err := NewFlatGeobufReader(bytes.NewReader(file.bytes()), FlatGeobufOptions{})
+		if err != nil {
+			t.Fatalf("%s:%d NewFlatGeobufReader() 错误 = %v", tt.org, tt.code, err)
+		}
+		if provider.GetSrid() != tt.want {
+			t.Errorf("%s:%d GetSrid() = %v, want %v", tt.org, tt.code, provider.GetSrid(), tt.want)
+		}
+	}
+}
+
+// TestFlatGeobufProvider_Invalid 测试无效的FlatGeobuf文件
+func TestFlatGeobufProvider_Invalid(t *testing.T) {
+	if _, err := NewFlatGeobufReader(bytes.NewReader([]byte("not a flatgeobuf file")), FlatGeobufOptions{}); !errors.Is(err, ErrInvalidFlatGeobuf) {
+		t.Errorf("NewFlatGeobufReader() 错误 = %v, want %v", err, ErrInvalidFlatGeobuf)
+	}
+
+	esri := fgbTestFile{name: "esri", crsOrg: "ESRI", crsCode: 102100}
+	if _, err := NewFlatGeobufReader(bytes.NewReader(esri.bytes()), FlatGeobufOptions{}); !errors.Is(err, ErrUnsupportedProjection) {
+		t.Errorf("NewFlatGeobufReader() 错误 = %v, want %v", err, ErrUnsupportedProjection)
+	}
+
+	// 截断的要素
+	file := fgbTestFile{name: "broken", features: []fgbTestFeature{{geometry: fgbTestGeometry{kind: fgbPoint, xy: []float64{1, 1}}}}}
+	data := file.bytes()
+	provider, err := NewFlatGeobufReader(bytes.NewReader(data[:len(data)-8]), FlatGeobufOptions{})
+	if err != nil {
+		t.Fatalf("NewFlatGeobufReader() 错误 = %v", err)
+	}
+	if _, err := CollectLayers(context.Background(), provider, NewTile(0, 0, 0)); err == nil {
+		t.Error("读取截断的要素应返回错误")
+	}
+
+	// 要素长度超出文件范围时不分配缓冲区
+	offset := 12 + binary.LittleEndian.Uint32(data[8:])
+	binary.LittleEndian.PutUint32(data[offset:], 0xfffffff0)
+	for _, r := range []io.ReaderAt{bytes.NewReader(data), struct{ io.ReaderAt }{bytes.NewReader(data)}} {
+		provider, err := NewFlatGeobufReader(r, FlatGeobufOptions{})
+		if err != nil {
+			t.Fatalf("NewFlatGeobufReader() 错误 = %v", err)
+		}
+		if _, err := CollectLayers(context.Background(), provider, NewTile(0, 0, 0)); !errors.Is(err, ErrInvalidFlatGeobuf) {
+			t.Errorf("CollectLayers() 错误 = %v, want %v", err, ErrInvalidFlatGeobuf)
+		}
+	}
+}
+
+// TestFlatGeobufProvider_Corrupt 测试损坏的要素返回错误而不是越界
+func TestFlatGeobufProvider_Corrupt(t *testing.T) {
+	file := fgbTestFile{
+		name:    "corrupt",
+		columns: []fgbColumn{{"name", fgbString}, {"pop", fgbInt}},
+		features: []fgbTestFeature{
+			{fgbTestGeometry{kind: fgbGeometryCollection, parts: []fgbTestGeometry{
+				{kind: fgbPolygon, ends: []uint32{4}, xy: []float64{0, 0, 1, 0, 1, 1, 0, 0}},
+				{kind: fgbMultiPolygon, parts: []fgbTestGeometry{{kind: fgbPolygon, xy: []float64{2, 2, 3, 2, 3, 3, 2, 2}}}},
+			}}, fgbTestProperties("corrupt", int32(7))},
+		},
+	}
+	data := file.bytes()
+	provider, err := NewFlatGeobufReader(bytes.NewReader(data), FlatGeobufOptions{})
+	if err != nil {
+		t.Fatalf("NewFlatGeobufReader() 错误 = %v", err)
+	}
+	feature := data[provider.featuresOffset+4:]
+	if _, err := provider.decodeFeature(feature, 0); err != nil {
+		t.Fatalf("decodeFeature() 错误 = %v", err)
+	}
+
+	check := func(name string, buf []byte) {
+		t.Helper()
+		if _, err := provider.decodeFeature(buf, 0); err != nil && !errors.Is(err, ErrInvalidFlatGeobuf) {
+			t.Errorf("%s: decodeFeature() 错误 = %v, want %v", name, err, ErrInvalidFlatGeobuf)
+		}
+	}
+	for i := range feature {
+		check("截断", feature[:i])
+		for _, v := range []byte{0x00, 0x7f, 0xff} {
+			buf := append([]byte(nil), feature...)
+			buf[i] = v
+			check("修改", buf)
+		}
+	}
+
+	// 损坏的文件头
+	header := int(provider.indexOffset)
+	for i := 12; i < header; i++ {
+		buf := append([]byte(nil), data[:header]...)
+		buf[i] ^= 0xff
+		if _, err := NewFlatGeobufReader(bytes.NewReader(buf), FlatGeobufOptions{}); err != nil && !errors.Is(err, ErrInvalidFlatGeobuf) && !errors.Is(err, ErrUnsupportedProjection) {
+			t.Errorf("NewFlatGeobufReader() 错误 = %v, want %v", err, ErrInvalidFlatGeobuf)
+		}
+	}
+
+	// 属性长度超出范围
+	props := fgbTestProperties("corrupt")
+	binary.LittleEndian.PutUint32(props[2:], 100)
+	if _, err := fgbProperties(props, file.columns); !errors.Is(err, ErrInvalidFlatGeobuf) {
+		t.Errorf("fgbProperties() 错误 = %v, want %v", err, ErrInvalidFlatGeobuf)
+	}
+	// 环结束位置超出坐标范围
+	bad := fgbTestFile{name: "bad", features: []fgbTestFeature{{geometry: fgbTestGeometry{kind: fgbPolygon, ends: []uint32{9}, xy: []float64{0, 0, 1, 0, 1, 1, 0, 0}}}}}
+	provider, err = NewFlatGeobufReader(bytes.NewReader(bad.bytes()), FlatGeobufOptions{})
+	if err != nil {
+		t.Fatalf("NewFlatGeobufReader() 错误 = %v", err)
+	}
+	if _, err := CollectLayers(context.Background(), provider, NewTile(0, 0, 0)); !errors.Is(err, ErrInvalidFlatGeobuf) {
+		t.Errorf("CollectLayers() 错误 = %v, want %v", err, ErrInvalidFlatGeobuf)
+	}
+}
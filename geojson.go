@@ -1,6 +1,7 @@
 package tile
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -44,22 +45,56 @@ func NewGeoJSONExporterWithOptions(options GeoJSONOptions) *GeoJSONExporter {
 	}
 }
 
+// SaveTile 保存瓦片为GeoJSON格式
 func (s *GeoJSONExporter) SaveTile(res []*Layer, tile *Tile, path string) error {
+	_, err := s.SaveTileSize(res, tile, path)
+	return err
+}
+
+// SaveTileSize 保存瓦片并返回编码后的字节数
+func (s *GeoJSONExporter) SaveTileSize(res []*Layer, tile *Tile, path string) (int64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if tile == nil {
-		return ErrInvalidTile
+		return 0, ErrInvalidTile
 	}
 
 	if path == "" {
-		return ErrInvalidPath
+		return 0, ErrInvalidPath
 	}
 
 	// 确保目录存在
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, s.Options.DirMode); err != nil {
-		return fmt.Errorf("创建目录失败: %w", err)
+		return 0, fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	data, err := s.GenerateGeoJSON(res, tile)
+	if err != nil {
+		return 0, err
+	}
+
+	// 将GeoJSON数据写入文件
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("创建文件失败: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return 0, fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// GenerateGeoJSON 生成包含瓦片行列号的GeoJSON FeatureCollection
+func (s *GeoJSONExporter) GenerateGeoJSON(res []*Layer, tile *Tile) ([]byte, error) {
+	if tile == nil {
+		return nil, ErrInvalidTile
 	}
 
 	// 构建GeoJSON FeatureCollection
@@ -85,22 +120,28 @@ func (s *GeoJSONExporter) SaveTile(res []*Layer, tile *Tile, path string) error
 		}
 	}
 
-	// 将GeoJSON数据写入文件
-	file, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("创建文件失败: %w", err)
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
 	if s.Options.Indent {
 		encoder.SetIndent("", "  ")
 	}
 	if err := encoder.Encode(geoJSONData); err != nil {
-		return fmt.Errorf("编码GeoJSON失败: %w", err)
+		return nil, fmt.Errorf("编码GeoJSON失败: %w", err)
 	}
+	return buf.Bytes(), nil
+}
+
+// Encode 实现Encoder接口，与GenerateGeoJSON相同
+func (s *GeoJSONExporter) Encode(layers []*Layer, tile *Tile) ([]byte, error) {
+	return s.GenerateGeoJSON(layers, tile)
+}
 
-	return nil
+// RemoveTile 删除已导出的瓦片文件
+func (s *GeoJSONExporter) RemoveTile(tile *Tile, path string) error {
+	if tile == nil {
+		return ErrInvalidTile
+	}
+	return removeTileFile(path)
 }
 
 func (s *GeoJSONExporter) Extension() string {
@@ -0,0 +1,274 @@
+package tile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	geom "github.com/flywave/go-geom"
+	gen "github.com/flywave/go-geom/general"
+	"github.com/flywave/go-vector-tiler/basic"
+	"github.com/flywave/go-vector-tiler/util"
+)
+
+// GeoJSONExtensions 读取目录时识别的GeoJSON文件扩展名
+var GeoJSONExtensions = []string{".geojson", ".json", ".geojsonl", ".geojsons", ".ndjson"}
+
+// GeoJSONProviderOptions GeoJSON数据源选项
+type GeoJSONProviderOptions struct {
+	// LayerProperty 非空时以要素的该属性值作为图层名，属性不存在时使用文件名
+	LayerProperty string
+	// SRID 要素坐标的空间参考(默认WGS84)
+	SRID uint64
+}
+
+// DefaultGeoJSONProviderOptions 默认GeoJSON数据源选项
+var DefaultGeoJSONProviderOptions = GeoJSONProviderOptions{
+	SRID: util.WGS84,
+}
+
+// GeoJSONProvider 读取GeoJSON文件的Provider
+// 支持FeatureCollection和GeoJSONSeq(每行一个要素，可带RS分隔符)格式，
+// 图层名默认取文件名(不含扩展名)。要素读入内存并建立空间索引
+type GeoJSONProvider struct {
+	*MemoryProvider
+}
+
+// NewGeoJSONProvider 由GeoJSON文件或目录创建Provider
+func NewGeoJSONProvider(paths ...string) (*GeoJSONProvider, error) {
+	return NewGeoJSONProviderWithOptions(DefaultGeoJSONProviderOptions, paths...)
+}
+
+// NewGeoJSONProviderWithOptions 使用自定义选项由GeoJSON文件或目录创建Provider
+// 目录中扩展名属于GeoJSONExtensions的文件按文件名顺序读取，不包括子目录
+func NewGeoJSONProviderWithOptions(options GeoJSONProviderOptions, paths ...string) (*GeoJSONProvider, error) {
+	if options.SRID == 0 {
+		options.SRID = DefaultGeoJSONProviderOptions.SRID
+	}
+
+	files, err := geoJSONFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &geoJSONReader{options: options, index: make(map[string]*Layer)}
+	for _, file := range files {
+		if err := r.readFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	mp, err := NewMemoryProvider(options.SRID, r.layers)
+	if err != nil {
+		return nil, err
+	}
+	return &GeoJSONProvider{MemoryProvider: mp}, nil
+}
+
+// geoJSONFiles 展开目录，返回需要读取的文件
+func geoJSONFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取GeoJSON文件失败: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取GeoJSON目录失败: %w", err)
+		}
+		var names []string
+		for _, entry := range entries {
+			if !entry.IsDir() && isGeoJSONFile(entry.Name()) {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			files = append(files, filepath.Join(path, name))
+		}
+	}
+	return files, nil
+}
+
+// isGeoJSONFile 判断文件扩展名是否属于GeoJSONExtensions
+func isGeoJSONFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range GeoJSONExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// geoJSONObject GeoJSON的Feature或FeatureCollection对象
+type geoJSONObject struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+	Features   []geoJSONObject        `json:"features"`
+}
+
+// geoJSONReader 读取GeoJSON文件并按图层名汇总要素
+type geoJSONReader struct {
+	options GeoJSONProviderOptions
+	layers  []*Layer
+	index   map[string]*Layer
+}
+
+// readFile 读取一个GeoJSON文件
+// 文件中可以依次包含多个FeatureCollection或Feature对象，因此同时支持GeoJSONSeq格式
+func (r *geoJSONReader) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取GeoJSON文件失败: %w", err)
+	}
+	// GeoJSONSeq的记录分隔符(RS)替换为空白
+	data = bytes.ReplaceAll(data, []byte{0x1e}, []byte{' '})
+
+	base := filepath.Base(path)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// 数值解码为json.Number，保留大于2^53的整数
+	decoder.UseNumber()
+	n := 0
+	for {
+		var obj geoJSONObject
+		if err := decoder.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("解析GeoJSON文件 %s 失败: %w", path, err)
+		}
+
+		switch obj.Type {
+		case "FeatureCollection":
+			for _, f := range obj.Features {
+				if err := r.addFeature(name, f); err != nil {
+					return fmt.Errorf("解析GeoJSON文件 %s 第 %d 个要素失败: %w", path, n, err)
+				}
+				n++
+			}
+		case "Feature":
+			if err := r.addFeature(name, obj); err != nil {
+				return fmt.Errorf("解析GeoJSON文件 %s 第 %d 个要素失败: %w", path, n, err)
+			}
+			n++
+		default:
+			return fmt.Errorf("解析GeoJSON文件 %s 失败: 不支持的类型 %q", path, obj.Type)
+		}
+	}
+}
+
+// addFeature 将要素加入图层，图层名由LayerProperty属性或文件名决定
+func (r *geoJSONReader) addFeature(name string, obj geoJSONObject) error {
+	obj.ID = geoJSONValue(obj.ID)
+	for k, v := range obj.Properties {
+		obj.Properties[k] = geoJSONValue(v)
+	}
+
+	var g geom.Geometry
+	if len(obj.Geometry) > 0 && string(obj.Geometry) != "null" {
+		bg, err := basic.UnmarshalJSON(obj.Geometry)
+		if err != nil {
+			return err
+		}
+		if g, err = geoJSONGeometry(bg); err != nil {
+			return err
+		}
+	}
+
+	if r.options.LayerProperty != "" {
+		if v, ok := obj.Properties[r.options.LayerProperty]; ok && v != nil {
+			name = fmt.Sprint(v)
+		}
+	}
+
+	properties := obj.Properties
+	if properties == nil {
+		properties = make(map[string]interface{})
+	}
+
+	layer, ok := r.index[name]
+	if !ok {
+		layer = &Layer{Name: name, SRID: int(r.options.SRID)}
+		r.index[name] = layer
+		r.layers = append(r.layers, layer)
+	}
+	layer.Features = append(layer.Features, &geom.Feature{
+		ID:         obj.ID,
+		Type:       "Feature",
+		Geometry:   g,
+		Properties: properties,
+	})
+	return nil
+}
+
+// geoJSONValue 将json.Number转换为int64或float64，递归处理对象和数组
+// 超出int64的正整数转换为uint64，其他数值转换为float64，超出float64范围时保留原文字符串
+func geoJSONValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(vv.String(), 10, 64); err == nil {
+			return u
+		}
+		if f, err := vv.Float64(); err == nil {
+			return f
+		}
+		return vv.String()
+	case map[string]interface{}:
+		for k, e := range vv {
+			vv[k] = geoJSONValue(e)
+		}
+	case []interface{}:
+		for i, e := range vv {
+			vv[i] = geoJSONValue(e)
+		}
+	}
+	return v
+}
+
+// geoJSONGeometry 将basic几何转换为geom.Geometry
+// basic中MultiPoint和Collection没有实现geom.Geometry，转换为general中对应的几何
+func geoJSONGeometry(g basic.Geometry) (geom.Geometry, error) {
+	if gg, ok := g.(geom.Geometry); ok {
+		return gg, nil
+	}
+	switch gg := g.(type) {
+	case basic.MultiPoint:
+		pts := make([][]float64, len(gg))
+		for i, pt := range gg {
+			pts[i] = []float64{pt[0], pt[1]}
+		}
+		return gen.NewMultiPoint(pts), nil
+	case basic.Collection:
+		geoms := make([]geom.Geometry, 0, len(gg))
+		for _, sub := range gg {
+			ng, err := geoJSONGeometry(sub)
+			if err != nil {
+				return nil, err
+			}
+			geoms = append(geoms, ng)
+		}
+		return gen.NewGeometryCollection(geoms...), nil
+	}
+	return nil, fmt.Errorf("不支持的几何类型 %v", g)
+}
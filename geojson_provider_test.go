@@ -0,0 +1,137 @@
+package tile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// writeTestFile 在dir下写入测试文件
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestGeoJSONProvider_Numbers 测试整数属性和ID不经float64丢失精度
+func TestGeoJSONProvider_Numbers(t *testing.T) {
+	path := writeTestFile(t, t.TempDir(), "ids.geojson", `{"type": "Feature", "id": 9007199254740993,
+		"geometry": {"type": "Point", "coordinates": [116, 39]},
+		"properties": {"osm_id": 9223372036854775807, "hash": 18446744073709551615, "lanes": 4, "width": 7.5, "big": 1e400,
+			"tags": {"ref": 9007199254740995, "levels": [1, 2.5]}}}`)
+
+	provider, err := NewGeoJSONProvider(path)
+	if err != nil {
+		t.Fatalf("NewGeoJSONProvider() 错误 = %v", err)
+	}
+	f := provider.GetDataByTile(NewTile(0, 0, 0))[0].Features[0]
+	if f.ID != int64(9007199254740993) {
+		t.Errorf("要素ID = %v(%T), want 9007199254740993", f.ID, f.ID)
+	}
+	props := f.Properties
+	expected := map[string]interface{}{
+		"osm_id": int64(9223372036854775807),
+		"hash":   uint64(18446744073709551615),
+		"lanes":  int64(4),
+		"width":  7.5,
+		"big":    "1e400",
+	}
+	for k, want := range expected {
+		if props[k] != want {
+			t.Errorf("属性%s = %v(%T), want %v(%T)", k, props[k], props[k], want, want)
+		}
+	}
+	tags := props["tags"].(map[string]interface{})
+	if levels := tags["levels"].([]interface{}); tags["ref"] != int64(9007199254740995) || levels[0] != int64(1) || levels[1] != 2.5 {
+		t.Errorf("嵌套属性 = %v", tags)
+	}
+}
+
+// TestGeoJSONProvider 测试读取FeatureCollection和GeoJSONSeq文件
+func TestGeoJSONProvider(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "roads.geojson", `{
+		"type": "FeatureCollection",
+		"features": [
+			{"type": "Feature", "id": 7, "geometry": {"type": "LineString", "coordinates": [[116, 39], [117, 40]]}, "properties": {"name": "r1"}},
+			{"type": "Feature", "geometry": null, "properties": {"name": "r2"}}
+		]
+	}`)
+	writeTestFile(t, dir, "pois.geojsonl", "\x1e"+`{"type": "Feature", "id": "a", "geometry": {"type": "Point", "coordinates": [-0.1, 51.5]}, "properties": {"name": "p1"}}`+"\n"+
+		"\x1e"+`{"type": "Feature", "geometry": {"type": "GeometryCollection", "geometries": [{"type": "Point", "coordinates": [116.5, 39.5]}]}, "properties": {"name": "p2"}}`+"\n")
+	writeTestFile(t, dir, "readme.txt", "not geojson")
+
+	provider, err := NewGeoJSONProvider(dir)
+	if err != nil {
+		t.Fatalf("NewGeoJSONProvider() 错误 = %v", err)
+	}
+	if provider.GetSrid() != 4326 {
+		t.Errorf("GetSrid() = %v, want 4326", provider.GetSrid())
+	}
+
+	layers := provider.GetDataByTile(NewTile(0, 0, 0))
+	if len(layers) != 2 || layers[0].Name != "pois" || layers[1].Name != "roads" {
+		t.Fatalf("GetDataByTile() = %+v, want pois, roads图层", layers)
+	}
+	if names := featureNames(layers); len(names) != 3 || names[0] != "p1" || names[1] != "p2" || names[2] != "r1" {
+		t.Errorf("要素 = %v, want [p1 p2 r1]", names)
+	}
+	if id := layers[0].Features[0].ID; id != "a" {
+		t.Errorf("要素ID = %v, want a", id)
+	}
+	if id := layers[1].Features[0].ID; id != int64(7) {
+		t.Errorf("要素ID = %v, want 7", id)
+	}
+	if _, ok := layers[1].Features[0].Geometry.(basic.Line); !ok {
+		t.Errorf("几何类型 = %T, want basic.Line", layers[1].Features[0].Geometry)
+	}
+
+	beijing := provider.GetDataByTile(NewTileLatLong(8, 39.5, 116.5))
+	if names := featureNames(beijing); len(names) != 2 || names[0] != "p2" || names[1] != "r1" {
+		t.Errorf("北京瓦片要素 = %v, want [p2 r1]", names)
+	}
+}
+
+// TestGeoJSONProvider_LayerProperty 测试按属性值分图层
+func TestGeoJSONProvider_LayerProperty(t *testing.T) {
+	path := writeTestFile(t, t.TempDir(), "data.json", `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 1]}, "properties": {"name": "a", "kind": "water"}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [2, 2]}, "properties": {"name": "b"}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [3, 3]}, "properties": {"name": "c", "kind": "water"}}
+	]}`)
+
+	provider, err := NewGeoJSONProviderWithOptions(GeoJSONProviderOptions{LayerProperty: "kind"}, path)
+	if err != nil {
+		t.Fatalf("NewGeoJSONProviderWithOptions() 错误 = %v", err)
+	}
+
+	layers := provider.GetDataByTile(NewTile(0, 0, 0))
+	if len(layers) != 2 || layers[0].Name != "water" || len(layers[0].Features) != 2 || layers[1].Name != "data" {
+		t.Errorf("GetDataByTile() = %+v, want water(2个要素), data图层", layers)
+	}
+}
+
+// TestGeoJSONProvider_Invalid 测试无效的GeoJSON文件
+func TestGeoJSONProvider_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	testCases := map[string]string{
+		"syntax.geojson":   `{"type": "FeatureCollection", "features": [`,
+		"geometry.geojson": `{"type": "Feature", "geometry": {"type": "Circle", "coordinates": [1, 1]}}`,
+		"type.geojson":     `{"type": "Topology"}`,
+	}
+	for name, content := range testCases {
+		path := writeTestFile(t, dir, name, content)
+		if _, err := NewGeoJSONProvider(path); err == nil {
+			t.Errorf("NewGeoJSONProvider(%s) 应返回错误", name)
+		}
+	}
+
+	if _, err := NewGeoJSONProvider(filepath.Join(dir, "missing.geojson")); err == nil {
+		t.Error("NewGeoJSONProvider() 文件不存在时应返回错误")
+	}
+}
@@ -0,0 +1,16 @@
+package tile
+
+// ringPoint 环的坐标点，几何坐标[]float64或basic.Point
+type ringPoint interface {
+	~[]float64 | ~[2]float64
+}
+
+// ringSignedArea 返回环的有向面积，y轴向上时逆时针为正，y轴向下的像素坐标下顺时针为正
+func ringSignedArea[P ringPoint](ring []P) float64 {
+	var area float64
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		area += ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+	}
+	return area / 2
+}
@@ -12,7 +12,11 @@ require (
 	github.com/flywave/go3d v0.0.0-20250314015505-bf0fda02e242
 	github.com/gdey/tbltest v0.0.0-20180914212833-1865222d591f
 	github.com/go-test/deep v1.0.7
+	github.com/google/flatbuffers v24.3.25+incompatible
+	github.com/mattn/go-sqlite3 v1.14.52
 	github.com/pborman/uuid v1.2.1
+	golang.org/x/text v0.21.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
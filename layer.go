@@ -8,8 +8,82 @@ type Layer struct {
 	Name     string
 	Features []*geom.Feature
 	SRID     int
+	// Extent 图层的瓦片范围，为0时使用Config.TileExtent
+	Extent uint64
 }
 
 func (l *Layer) GetName() string {
 	return l.Name
 }
+
+// LayerOptions 单个图层的处理选项，以Layer.Name为键注册到Config.LayerOptions
+// 零值字段使用Config中的全局设置
+type LayerOptions struct {
+	// MinZoom 图层出现的最小级别
+	MinZoom int
+	// MaxZoom 图层出现的最大级别，为nil时不限制
+	MaxZoom *int
+	// Buffer 瓦片缓冲区，为0时使用Config.TileBuffer
+	Buffer uint64
+	// Tolerance 简化容差，为0时使用DefaultEpislon
+	Tolerance float64
+	// Simplify 是否简化几何，为nil时使用Config.SimplifyGeometries
+	Simplify *bool
+	// Extent 瓦片范围，为0时使用Config.TileExtent
+	Extent uint64
+	// FeatureZoom 返回要素的可见级别范围，为nil时使用DefaultFeatureZoom读取要素属性
+	FeatureZoom FeatureZoomFunc
+	// Filter 返回false的要素不输出，为nil时不筛选
+	Filter func(f *geom.Feature) bool
+	// Filters 生成Filter的筛选条件，由配置文件设置，用于断点续传时判断筛选条件是否变化
+	Filters []FeatureFilter
+}
+
+// layerSettings 合并全局配置后的图层处理参数
+type layerSettings struct {
+	buffer    uint64
+	extent    uint64
+	tolerance float64
+	simplify  bool
+}
+
+// visible 判断图层在给定级别是否输出
+func (o *LayerOptions) visible(z uint32) bool {
+	if o == nil {
+		return true
+	}
+	if int(z) < o.MinZoom {
+		return false
+	}
+	return o.MaxZoom == nil || int(z) <= *o.MaxZoom
+}
+
+// layerSettings 返回图层在给定级别的处理参数，图层在该级别不输出时返回false
+// 不输出时仍返回完整的处理参数，供自底向上生成时处理更低级别使用
+func (m *Tiler) layerSettings(name string, z uint32) (layerSettings, bool) {
+	s := layerSettings{
+		buffer:    m.config.TileBuffer,
+		extent:    m.config.TileExtent,
+		tolerance: DefaultEpislon,
+		simplify:  m.config.SimplifyGeometries,
+	}
+
+	opts := m.config.LayerOptions[name]
+	if opts == nil {
+		return s, true
+	}
+
+	if opts.Buffer != 0 {
+		s.buffer = opts.Buffer
+	}
+	if opts.Extent != 0 {
+		s.extent = opts.Extent
+	}
+	if opts.Tolerance != 0 {
+		s.tolerance = opts.Tolerance
+	}
+	if opts.Simplify != nil {
+		s.simplify = *opts.Simplify
+	}
+	return s, opts.visible(z)
+}
@@ -0,0 +1,132 @@
+package tile
+
+import (
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// TestTiler_layerSettings 测试图层选项与全局配置的合并
+func TestTiler_layerSettings(t *testing.T) {
+	off, maxZoom, zero := false, 8, 0
+	tiler := NewTiler(&Config{
+		TileExtent:         4096,
+		TileBuffer:         64,
+		SimplifyGeometries: true,
+		LayerOptions: map[string]*LayerOptions{
+			"buildings": {MinZoom: 13},
+			"roads":     {Buffer: 256, Tolerance: 2},
+			"water":     {MaxZoom: &maxZoom, Simplify: &off, Extent: 512},
+			"countries": {MaxZoom: &zero},
+		},
+	})
+	defer tiler.Stop()
+
+	testCases := []struct {
+		name     string
+		layer    string
+		zoom     uint32
+		visible  bool
+		expected layerSettings
+	}{
+		{"未注册的图层", "places", 5, true, layerSettings{buffer: 64, extent: 4096, tolerance: DefaultEpislon, simplify: true}},
+		{"低于MinZoom", "buildings", 12, false, layerSettings{}},
+		{"达到MinZoom", "buildings", 13, true, layerSettings{buffer: 64, extent: 4096, tolerance: DefaultEpislon, simplify: true}},
+		{"覆盖缓冲区和容差", "roads", 3, true, layerSettings{buffer: 256, extent: 4096, tolerance: 2, simplify: true}},
+		{"覆盖简化和范围", "water", 8, true, layerSettings{buffer: 64, extent: 512, tolerance: DefaultEpislon, simplify: false}},
+		{"高于MaxZoom", "water", 9, false, layerSettings{}},
+		{"MaxZoom为0时只在0级输出", "countries", 0, true, layerSettings{buffer: 64, extent: 4096, tolerance: DefaultEpislon, simplify: true}},
+		{"高于为0的MaxZoom", "countries", 1, false, layerSettings{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			settings, visible := tiler.layerSettings(tc.layer, tc.zoom)
+			if visible != tc.visible {
+				t.Fatalf("visible = %v, want %v", visible, tc.visible)
+			}
+			if visible && settings != tc.expected {
+				t.Errorf("layerSettings() = %+v, want %+v", settings, tc.expected)
+			}
+		})
+	}
+}
+
+// TestTiler_processTileLayerOptions 测试按图层级别范围过滤图层
+func TestTiler_processTileLayerOptions(t *testing.T) {
+	newLayer := func(name string) *Layer {
+		return &Layer{
+			Name:     name,
+			Features: []*geom.Feature{{Geometry: basic.Point{0, 0}}},
+		}
+	}
+
+	exporter := &MockExporter{}
+	tiler := NewTiler(&Config{
+		Provider: &MockProvider{
+			layers: []*Layer{newLayer("roads"), newLayer("buildings")},
+			srid:   4326,
+		},
+		Exporter:   exporter,
+		TileExtent: 4096,
+		LayerOptions: map[string]*LayerOptions{
+			"buildings": {MinZoom: 13},
+			"roads":     {Extent: 8192},
+		},
+	})
+	defer tiler.Stop()
+
+	tiler.processTile(&tileTask{z: 1, x: 0, y: 0})
+	tiler.processTile(&tileTask{z: 13, x: 0, y: 0})
+
+	saved := exporter.GetSavedTiles()
+	if len(saved) != 2 {
+		t.Fatalf("导出瓦片数量 = %v, want 2", len(saved))
+	}
+	for _, s := range saved {
+		names := map[string]*Layer{}
+		for _, l := range s.Layers {
+			names[l.Name] = l
+		}
+		if _, ok := names["buildings"]; ok != (s.Tile.Z >= 13) {
+			t.Errorf("z=%d buildings图层输出 = %v", s.Tile.Z, ok)
+		}
+		if roads := names["roads"]; roads == nil || roads.Extent != 8192 {
+			t.Errorf("z=%d roads图层 = %+v, want Extent 8192", s.Tile.Z, roads)
+		}
+	}
+}
+
+// TestTiler_processTileFilter 测试按图层的筛选函数过滤要素
+func TestTiler_processTileFilter(t *testing.T) {
+	newFeature := func(name string, pop int) *geom.Feature {
+		return &geom.Feature{Geometry: basic.Point{0, 0}, Properties: map[string]interface{}{"name": name, "pop": pop}}
+	}
+	layers := []*Layer{
+		{Name: "places", Features: []*geom.Feature{newFeature("city", 1000), newFeature("village", 10)}},
+		{Name: "pois", Features: []*geom.Feature{newFeature("shop", 0)}},
+	}
+
+	exporter := &MockExporter{}
+	tiler := NewTiler(&Config{
+		Provider:   &MockProvider{layers: layers, srid: 4326},
+		Exporter:   exporter,
+		TileExtent: 4096,
+		LayerOptions: map[string]*LayerOptions{
+			"places": {Filter: func(f *geom.Feature) bool { return f.Properties["pop"].(int) >= 100 }},
+			"pois":   {Filter: func(*geom.Feature) bool { return false }},
+		},
+	})
+	defer tiler.Stop()
+
+	tiler.processTile(&tileTask{z: 2, x: 0, y: 0})
+	saved := exporter.GetSavedTiles()
+	if len(saved) != 1 {
+		t.Fatalf("导出瓦片数量 = %v, want 1", len(saved))
+	}
+	if layers := saved[0].Layers; len(layers) != 1 || layers[0].Name != "places" ||
+		len(layers[0].Features) != 1 || layers[0].Features[0].Properties["name"] != "city" {
+		t.Errorf("图层 = %+v, want 只有places中的city", layers)
+	}
+}
@@ -0,0 +1,517 @@
+package tile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/flywave/go-mapbox/mvt"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// MBTilesOptions MBTiles导出选项
+type MBTilesOptions struct {
+	// Name 元数据中的名称，为空时使用文件名
+	Name string
+	// Description 元数据中的描述
+	Description string
+	// Attribution 元数据中的版权信息
+	Attribution string
+	// Bounds 经纬度范围[minLon, minLat, maxLon, maxLat]，为nil时由已写入的瓦片计算
+	Bounds *[4]float64
+	// Gzip 是否gzip压缩瓦片数据
+	Gzip bool
+	// BatchSize 每个事务写入的瓦片数
+	BatchSize int
+	// Proto 协议版本
+	Proto mvt.ProtoType
+}
+
+// DefaultMBTilesOptions 默认MBTiles选项
+var DefaultMBTilesOptions = MBTilesOptions{
+	Gzip:      true,
+	BatchSize: 1000,
+	Proto:     mvt.PROTO_MAPBOX,
+}
+
+// mbtilesSchema MBTiles 1.3表结构，瓦片内容存储在images中，map记录瓦片到内容的引用以去除重复瓦片
+const mbtilesSchema = `
+CREATE TABLE IF NOT EXISTS metadata (name TEXT, value TEXT);
+CREATE UNIQUE INDEX IF NOT EXISTS metadata_name ON metadata (name);
+CREATE TABLE IF NOT EXISTS map (zoom_level INTEGER, tile_column INTEGER, tile_row INTEGER, tile_id TEXT);
+CREATE UNIQUE INDEX IF NOT EXISTS map_index ON map (zoom_level, tile_column, tile_row);
+CREATE TABLE IF NOT EXISTS images (tile_data BLOB, tile_id TEXT);
+CREATE UNIQUE INDEX IF NOT EXISTS images_id ON images (tile_id);
+CREATE VIEW IF NOT EXISTS tiles AS
+	SELECT map.zoom_level AS zoom_level, map.tile_column AS tile_column, map.tile_row AS tile_row, images.tile_data AS tile_data
+	FROM map JOIN images ON images.tile_id = map.tile_id;
+`
+
+// mbtilesOp 写入协程处理的操作，delete为true时删除瓦片
+type mbtilesOp struct {
+	z, x, y uint32
+	id      string
+	data    []byte
+	delete  bool
+}
+
+// MBTilesExporter 将瓦片写入SQLite MBTiles文件的导出器，也可作为TileSink与其他编码器组合
+// 瓦片由单个写入协程按批次在事务中写入，可被多个工作协程并发调用。
+// 写入完成后需要调用Close写入元数据并关闭文件，作为Config.Exporter时由Tiler调用
+type MBTilesExporter struct {
+	// Options 导出选项
+	Options MBTilesOptions
+
+	path    string
+	db      *sql.DB
+	encoder *MVTExporter
+	layers  vectorLayerSet
+
+	// mu 保护closed和meta，发送操作时持有读锁，关闭时持有写锁
+	mu     sync.RWMutex
+	closed bool
+	meta   TilesetMetadata
+	ops    chan mbtilesOp
+	done   chan struct{}
+
+	// errMu 保护err，记录写入协程的第一个错误
+	errMu sync.Mutex
+	err   error
+}
+
+// NewMBTilesExporter 创建写入path的MBTiles导出器，文件已存在时在原有内容上继续写入
+func NewMBTilesExporter(path string) (*MBTilesExporter, error) {
+	return NewMBTilesExporterWithOptions(path, DefaultMBTilesOptions)
+}
+
+// NewMBTilesExporterWithOptions 使用自定义选项创建MBTiles导出器
+func NewMBTilesExporterWithOptions(path string, options MBTilesOptions) (*MBTilesExporter, error) {
+	if path == "" {
+		return nil, ErrInvalidPath
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultMBTilesOptions.BatchSize
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_sync=NORMAL")
+	if err != nil {
+		return nil, fmt.Errorf("打开MBTiles文件失败: %w", err)
+	}
+	// 所有写入都在同一个连接上进行
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(mbtilesSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建MBTiles表失败: %w", err)
+	}
+
+	encoderOptions := DefaultMVTOptions
+	encoderOptions.Proto = options.Proto
+	e := &MBTilesExporter{
+		Options: options,
+		path:    path,
+		db:      db,
+		encoder: NewMVTExporterWithOptions(encoderOptions),
+		ops:     make(chan mbtilesOp, options.BatchSize),
+		done:    make(chan struct{}),
+	}
+	if err := e.loadLayers(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	go e.write()
+	return e, nil
+}
+
+// loadLayers 读取已有文件中的vector_layers，继续写入时与新写入的图层合并
+func (e *MBTilesExporter) loadLayers() error {
+	var value string
+	err := e.db.QueryRow(`SELECT value FROM metadata WHERE name = 'json'`).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取MBTiles元数据失败: %w", err)
+	}
+	var meta struct {
+		VectorLayers []VectorLayer `json:"vector_layers"`
+	}
+	if err := json.Unmarshal([]byte(value), &meta); err != nil {
+		return fmt.Errorf("解析MBTiles元数据失败: %w", err)
+	}
+	e.layers.merge(meta.VectorLayers)
+	return nil
+}
+
+// Open 记录瓦片集元数据，元数据中的名称和格式在Options未设置名称时使用
+func (e *MBTilesExporter) Open(ctx context.Context, meta TilesetMetadata) error {
+	if meta.TileMatrixSet != nil && meta.TileMatrixSet != WebMercatorQuad {
+		return ErrNotWebMercator
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return ErrExporterClosed
+	}
+	e.meta = meta
+	return nil
+}
+
+// SaveTile 编码瓦片并交给写入协程，path被忽略
+// 写入是异步的，写入协程的错误由之后的SaveTile或Close返回
+func (e *MBTilesExporter) SaveTile(res []*Layer, tile *Tile, path string) error {
+	_, err := e.SaveTileSize(res, tile, path)
+	return err
+}
+
+// SaveTileSize 保存瓦片并返回编码后的字节数
+func (e *MBTilesExporter) SaveTileSize(res []*Layer, tile *Tile, path string) (int64, error) {
+	if tile == nil {
+		return 0, ErrInvalidTile
+	}
+	if tile.MatrixSet() != WebMercatorQuad {
+		return 0, ErrNotWebMercator
+	}
+
+	data, err := e.encoder.GenerateMVT(res, tile)
+	if err != nil {
+		return 0, err
+	}
+	if err := e.WriteTile(context.Background(), tile.Z, tile.X, tile.Y, data); err != nil {
+		return 0, err
+	}
+	e.layers.add(tile.Z, res)
+	return int64(len(data)), nil
+}
+
+// WriteTile 将编码后的瓦片交给写入协程，Options.Gzip为true时先压缩
+func (e *MBTilesExporter) WriteTile(ctx context.Context, z, x, y uint32, data []byte) error {
+	if err := e.writeErr(); err != nil {
+		return err
+	}
+	if e.Options.Gzip {
+		var err error
+		if data, err = gzipData(data); err != nil {
+			return err
+		}
+	}
+	sum := md5.Sum(data)
+	return e.send(mbtilesOp{z: z, x: x, y: y, id: hex.EncodeToString(sum[:]), data: data})
+}
+
+// RemoveTile 删除已写入的瓦片，不再被引用的瓦片内容在Close时清理
+func (e *MBTilesExporter) RemoveTile(tile *Tile, path string) error {
+	if tile == nil {
+		return ErrInvalidTile
+	}
+	return e.DeleteTile(context.Background(), tile.Z, tile.X, tile.Y)
+}
+
+// DeleteTile 删除已写入的瓦片
+func (e *MBTilesExporter) DeleteTile(ctx context.Context, z, x, y uint32) error {
+	if err := e.writeErr(); err != nil {
+		return err
+	}
+	return e.send(mbtilesOp{z: z, x: x, y: y, delete: true})
+}
+
+// UpdateMetadata 合并元数据中的vector_layers，在Close时写入
+func (e *MBTilesExporter) UpdateMetadata(meta TilesetMetadata) error {
+	e.layers.merge(meta.VectorLayers)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.meta = meta
+	return nil
+}
+
+// send 将操作交给写入协程
+func (e *MBTilesExporter) send(op mbtilesOp) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return ErrExporterClosed
+	}
+	e.ops <- op
+	return nil
+}
+
+// Extension 返回文件扩展名
+func (e *MBTilesExporter) Extension() string {
+	return "mbtiles"
+}
+
+// RelativeTilePath 瓦片不写入单独的文件，返回空字符串
+func (e *MBTilesExporter) RelativeTilePath(zoom, x, y int) string {
+	return ""
+}
+
+// Close 等待写入完成，写入元数据并关闭文件，返回写入过程中的第一个错误
+// 重复调用时不做任何操作
+func (e *MBTilesExporter) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	close(e.ops)
+	e.mu.Unlock()
+	<-e.done
+
+	err := e.writeErr()
+	if err == nil {
+		err = e.finish()
+	}
+	if cerr := e.db.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("关闭MBTiles文件失败: %w", cerr)
+	}
+	return err
+}
+
+// writeErr 返回写入协程的第一个错误
+func (e *MBTilesExporter) writeErr() error {
+	e.errMu.Lock()
+	defer e.errMu.Unlock()
+	return e.err
+}
+
+// setErr 记录写入协程的第一个错误
+func (e *MBTilesExporter) setErr(err error) {
+	e.errMu.Lock()
+	defer e.errMu.Unlock()
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// write 写入协程，每BatchSize个操作提交一次事务
+// 出错后继续接收操作但不再写入，避免SaveTile阻塞
+func (e *MBTilesExporter) write() {
+	defer close(e.done)
+
+	var batch *mbtilesBatch
+	n := 0
+	for op := range e.ops {
+		if e.writeErr() != nil {
+			continue
+		}
+		if batch == nil {
+			var err error
+			if batch, err = e.beginBatch(); err != nil {
+				e.setErr(err)
+				continue
+			}
+		}
+		if err := batch.apply(op); err != nil {
+			batch.rollback()
+			batch = nil
+			e.setErr(fmt.Errorf("写入瓦片 %d/%d/%d 失败: %w", op.z, op.x, op.y, err))
+			continue
+		}
+		if n++; n >= e.Options.BatchSize {
+			if err := batch.commit(); err != nil {
+				e.setErr(err)
+			}
+			batch, n = nil, 0
+		}
+	}
+	if batch != nil {
+		if err := batch.commit(); err != nil {
+			e.setErr(err)
+		}
+	}
+}
+
+// mbtilesBatch 一个写入事务和其中使用的语句
+type mbtilesBatch struct {
+	tx                     *sql.Tx
+	insertImage, insertMap *sql.Stmt
+	deleteMap              *sql.Stmt
+}
+
+// beginBatch 开始新的写入事务
+func (e *MBTilesExporter) beginBatch() (*mbtilesBatch, error) {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开始MBTiles事务失败: %w", err)
+	}
+	b := &mbtilesBatch{tx: tx}
+	for _, s := range []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&b.insertImage, `INSERT OR IGNORE INTO images (tile_id, tile_data) VALUES (?, ?)`},
+		{&b.insertMap, `INSERT OR REPLACE INTO map (zoom_level, tile_column, tile_row, tile_id) VALUES (?, ?, ?, ?)`},
+		{&b.deleteMap, `DELETE FROM map WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?`},
+	} {
+		if *s.stmt, err = tx.Prepare(s.query); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("准备MBTiles语句失败: %w", err)
+		}
+	}
+	return b, nil
+}
+
+// apply 在事务中执行操作，行号按TMS方案自下而上编号
+func (b *mbtilesBatch) apply(op mbtilesOp) error {
+	row := (uint32(1) << op.z) - 1 - op.y
+	if op.delete {
+		_, err := b.deleteMap.Exec(op.z, op.x, row)
+		return err
+	}
+	if _, err := b.insertImage.Exec(op.id, op.data); err != nil {
+		return err
+	}
+	_, err := b.insertMap.Exec(op.z, op.x, row, op.id)
+	return err
+}
+
+// commit 提交事务
+func (b *mbtilesBatch) commit() error {
+	if err := b.tx.Commit(); err != nil {
+		return fmt.Errorf("提交MBTiles事务失败: %w", err)
+	}
+	return nil
+}
+
+// rollback 回滚事务
+func (b *mbtilesBatch) rollback() {
+	b.tx.Rollback()
+}
+
+// finish 清理不再被引用的瓦片内容并写入元数据
+func (e *MBTilesExporter) finish() error {
+	if _, err := e.db.Exec(`DELETE FROM images WHERE tile_id NOT IN (SELECT tile_id FROM map)`); err != nil {
+		return fmt.Errorf("清理MBTiles瓦片失败: %w", err)
+	}
+
+	meta, err := e.metadata()
+	if err != nil {
+		return err
+	}
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始MBTiles事务失败: %w", err)
+	}
+	for name, value := range meta {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO metadata (name, value) VALUES (?, ?)`, name, value); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("写入MBTiles元数据失败: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交MBTiles事务失败: %w", err)
+	}
+	return nil
+}
+
+// metadata 生成metadata表的内容，级别和范围由文件中的瓦片计算
+func (e *MBTilesExporter) metadata() (map[string]string, error) {
+	e.mu.RLock()
+	tileset := e.meta
+	e.mu.RUnlock()
+
+	name := e.Options.Name
+	if name == "" {
+		name = tileset.Name
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(e.path), filepath.Ext(e.path))
+	}
+	// MBTiles中矢量瓦片的格式为pbf
+	format := tileset.Format
+	if format == "" || format == "mvt" {
+		format = "pbf"
+	}
+	meta := map[string]string{
+		"name":   name,
+		"format": format,
+		"type":   "overlay",
+	}
+	if e.Options.Description != "" {
+		meta["description"] = e.Options.Description
+	}
+	if e.Options.Attribution != "" {
+		meta["attribution"] = e.Options.Attribution
+	}
+
+	layers, err := json.Marshal(struct {
+		VectorLayers []VectorLayer `json:"vector_layers"`
+	}{e.layers.list()})
+	if err != nil {
+		return nil, err
+	}
+	meta["json"] = string(layers)
+
+	var minZoom, maxZoom sql.NullInt64
+	if err := e.db.QueryRow(`SELECT MIN(zoom_level), MAX(zoom_level) FROM map`).Scan(&minZoom, &maxZoom); err != nil {
+		return nil, fmt.Errorf("读取MBTiles级别失败: %w", err)
+	}
+	if !minZoom.Valid {
+		return meta, nil // 没有瓦片
+	}
+	meta["minzoom"] = strconv.FormatInt(minZoom.Int64, 10)
+	meta["maxzoom"] = strconv.FormatInt(maxZoom.Int64, 10)
+
+	bounds, err := e.bounds(uint32(maxZoom.Int64))
+	if err != nil {
+		return nil, err
+	}
+	meta["bounds"] = formatFloats(bounds[:]...)
+	meta["center"] = formatFloats((bounds[0]+bounds[2])/2, (bounds[1]+bounds[3])/2, float64(minZoom.Int64))
+	return meta, nil
+}
+
+// bounds 返回Options.Bounds，未设置时返回z级瓦片覆盖的经纬度范围
+func (e *MBTilesExporter) bounds(z uint32) ([4]float64, error) {
+	if e.Options.Bounds != nil {
+		return *e.Options.Bounds, nil
+	}
+	var minX, minRow, maxX, maxRow uint32
+	err := e.db.QueryRow(`SELECT MIN(tile_column), MIN(tile_row), MAX(tile_column), MAX(tile_row) FROM map WHERE zoom_level = ?`, z).
+		Scan(&minX, &minRow, &maxX, &maxRow)
+	if err != nil {
+		return [4]float64{}, fmt.Errorf("读取MBTiles范围失败: %w", err)
+	}
+	// 行号自下而上编号，最大行号对应最北的瓦片
+	n := (uint32(1) << z) - 1
+	nw := NewTile(z, minX, n-maxRow).Bounds()
+	se := NewTile(z, maxX, n-minRow).Bounds()
+	return [4]float64{nw[0], se[1], se[2], nw[3]}, nil
+}
+
+// formatFloats 以逗号连接数值，用于bounds和center元数据
+func formatFloats(values ...float64) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+	}
+	return strings.Join(s, ",")
+}
+
+// gzipData gzip压缩瓦片数据
+func gzipData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("压缩瓦片失败: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("压缩瓦片失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
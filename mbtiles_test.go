@@ -0,0 +1,225 @@
+package tile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// mbtilesLayers 返回包含一个要素的图层
+func mbtilesLayers(name string, props map[string]interface{}) []*Layer {
+	return []*Layer{{Name: name, Features: []*geom.Feature{
+		{Geometry: basic.Point{1, 1}, Properties: props},
+	}}}
+}
+
+// readMBTiles 读取MBTiles文件中的瓦片和元数据
+func readMBTiles(t *testing.T, path string) (map[string][]byte, map[string]string, int) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tiles := map[string][]byte{}
+	rows, err := db.Query(`SELECT zoom_level, tile_column, tile_row, tile_data FROM tiles`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var z, x, row uint32
+		var data []byte
+		if err := rows.Scan(&z, &x, &row, &data); err != nil {
+			t.Fatal(err)
+		}
+		tiles[fmt.Sprintf("%d/%d/%d", z, x, row)] = data
+	}
+	rows.Close()
+
+	meta := map[string]string{}
+	rows, err = db.Query(`SELECT name, value FROM metadata`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			t.Fatal(err)
+		}
+		meta[name] = value
+	}
+	rows.Close()
+
+	var images int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM images`).Scan(&images); err != nil {
+		t.Fatal(err)
+	}
+	return tiles, meta, images
+}
+
+// TestMBTilesExporter 测试并发写入、TMS行号、去重和元数据
+func TestMBTilesExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "roads.mbtiles")
+	exporter, err := NewMBTilesExporterWithOptions(path, MBTilesOptions{Gzip: true, BatchSize: 2, Attribution: "test"})
+	if err != nil {
+		t.Fatalf("NewMBTilesExporterWithOptions() 错误 = %v", err)
+	}
+
+	// 2级的4个瓦片内容相同
+	var wg sync.WaitGroup
+	for x := uint32(0); x < 2; x++ {
+		for y := uint32(0); y < 2; y++ {
+			wg.Add(1)
+			go func(x, y uint32) {
+				defer wg.Done()
+				if err := exporter.SaveTile(mbtilesLayers("roads", map[string]interface{}{"name": "a"}), NewTile(2, x, y), ""); err != nil {
+					t.Errorf("SaveTile() 错误 = %v", err)
+				}
+			}(x, y)
+		}
+	}
+	wg.Wait()
+	pois := mbtilesLayers("pois", map[string]interface{}{"rank": 1, "open": true})
+	if err := exporter.SaveTile(pois, NewTile(1, 0, 0), ""); err != nil {
+		t.Fatalf("SaveTile() 错误 = %v", err)
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("Close() 错误 = %v", err)
+	}
+
+	tiles, meta, images := readMBTiles(t, path)
+	if len(tiles) != 5 || images != 2 {
+		t.Fatalf("瓦片数 = %d, 内容数 = %d, want 5, 2", len(tiles), images)
+	}
+	// 1/0/0在TMS中的行号为1
+	data, ok := tiles["1/0/1"]
+	if !ok {
+		t.Fatalf("瓦片 = %v, want 行号翻转后的1/0/1", tiles)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("瓦片数据不是gzip格式: %v", err)
+	}
+	raw, _ := io.ReadAll(r)
+	want, _ := NewMVTExporter().GenerateMVT(pois, NewTile(1, 0, 0))
+	if !bytes.Equal(raw, want) {
+		t.Errorf("解压后的瓦片 = %v, want %v", raw, want)
+	}
+
+	if meta["name"] != "roads" || meta["format"] != "pbf" || meta["attribution"] != "test" ||
+		meta["minzoom"] != "1" || meta["maxzoom"] != "2" {
+		t.Errorf("元数据 = %v", meta)
+	}
+	if meta["bounds"] != "-180,0,0,85.051129" || meta["center"] != "-90,42.525564,1" {
+		t.Errorf("bounds = %v, center = %v", meta["bounds"], meta["center"])
+	}
+	var layers struct {
+		VectorLayers []VectorLayer `json:"vector_layers"`
+	}
+	if err := json.Unmarshal([]byte(meta["json"]), &layers); err != nil {
+		t.Fatalf("json元数据解析失败: %v", err)
+	}
+	if len(layers.VectorLayers) != 2 {
+		t.Fatalf("vector_layers = %+v", layers.VectorLayers)
+	}
+	if l := layers.VectorLayers[0]; l.ID != "pois" || l.Fields["rank"] != "Number" || l.Fields["open"] != "Boolean" || l.MinZoom != 1 || l.MaxZoom != 1 {
+		t.Errorf("pois = %+v", l)
+	}
+	if l := layers.VectorLayers[1]; l.ID != "roads" || l.Fields["name"] != "String" || l.MinZoom != 2 || l.MaxZoom != 2 {
+		t.Errorf("roads = %+v", l)
+	}
+
+	if err := exporter.SaveTile(nil, NewTile(0, 0, 0), ""); !errors.Is(err, ErrExporterClosed) {
+		t.Errorf("关闭后SaveTile() 错误 = %v, want %v", err, ErrExporterClosed)
+	}
+}
+
+// TestMBTilesExporter_Reopen 测试在已有文件上继续写入和删除瓦片
+func TestMBTilesExporter_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tiles.mbtiles")
+	exporter, err := NewMBTilesExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter.SaveTile(mbtilesLayers("a", map[string]interface{}{"v": 1}), NewTile(3, 1, 1), "")
+	exporter.SaveTile(mbtilesLayers("b", nil), NewTile(3, 2, 2), "")
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("Close() 错误 = %v", err)
+	}
+
+	exporter, err = NewMBTilesExporterWithOptions(path, MBTilesOptions{Name: "reopened", Bounds: &[4]float64{1, 2, 3, 4}})
+	if err != nil {
+		t.Fatalf("重新打开错误 = %v", err)
+	}
+	layers := append(mbtilesLayers("a", map[string]interface{}{"v": "x"}), mbtilesLayers("c", nil)...)
+	exporter.SaveTile(layers, NewTile(4, 2, 2), "")
+	exporter.RemoveTile(NewTile(3, 2, 2), "")
+	// 空内容的瓦片照常写入，而不是被当作删除
+	if err := exporter.WriteTile(context.Background(), 4, 3, 3, nil); err != nil {
+		t.Errorf("WriteTile(nil) 错误 = %v", err)
+	}
+	if err := exporter.SaveTile(nil, NewTileInMatrixSet(WorldCRS84Quad, 0, 0, 0, 0, 4096, 0), ""); !errors.Is(err, ErrNotWebMercator) {
+		t.Errorf("SaveTile() 错误 = %v, want %v", err, ErrNotWebMercator)
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("Close() 错误 = %v", err)
+	}
+
+	tiles, meta, images := readMBTiles(t, path)
+	if _, ok := tiles["4/3/12"]; !ok || len(tiles) != 3 || images != 3 || tiles["3/1/6"] == nil || tiles["4/2/13"] == nil {
+		t.Errorf("瓦片 = %d个 %v, 内容数 = %d", len(tiles), tiles, images)
+	}
+	if meta["name"] != "reopened" || meta["bounds"] != "1,2,3,4" || meta["minzoom"] != "3" || meta["maxzoom"] != "4" {
+		t.Errorf("元数据 = %v", meta)
+	}
+	// 已删除瓦片中的图层b仍保留在记录中，a的字段类型合并为Mixed
+	want := `{"vector_layers":[{"id":"a","fields":{"v":"Mixed"},"minzoom":3,"maxzoom":4},{"id":"b","fields":{},"minzoom":3,"maxzoom":3},{"id":"c","fields":{},"minzoom":4,"maxzoom":4}]}`
+	if meta["json"] != want {
+		t.Errorf("json = %v, want %v", meta["json"], want)
+	}
+}
+
+// TestTiler_MBTilesExporter 测试Tiler将瓦片写入MBTiles并关闭，不创建瓦片目录
+func TestTiler_MBTilesExporter(t *testing.T) {
+	provider, err := NewMemoryProvider(4326, []*Layer{{Name: "cities", Features: []*geom.Feature{
+		{Geometry: basic.Point{116.4, 39.9}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cities.mbtiles")
+	exporter, err := NewMBTilesExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tiler结束时关闭导出器并写入元数据
+	if err := NewTiler(&Config{Provider: provider, Exporter: exporter, OutputDir: dir, MaxZoom: 3}).Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	tiles, meta, _ := readMBTiles(t, path)
+	if len(tiles) != 4 || meta["minzoom"] != "0" || meta["maxzoom"] != "3" {
+		t.Errorf("瓦片数 = %d, 元数据 = %v", len(tiles), meta)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if e.IsDir() {
+			t.Errorf("不应创建目录 %s", e.Name())
+		}
+	}
+}
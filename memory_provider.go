@@ -0,0 +1,261 @@
+package tile
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+	"github.com/flywave/go-vector-tiler/maths/webmercator"
+	"github.com/flywave/go-vector-tiler/util"
+)
+
+// rtreeNodeSize R树节点的最大子节点数
+const rtreeNodeSize = 16
+
+// MemoryProvider 基于内存数据的Provider
+// 创建时为每个图层的要素外包框建立R树索引，按瓦片查询时只返回与带缓冲区瓦片范围相交的要素。
+// 支持任意PROJ可识别的坐标系，创建后不应修改传入的要素
+type MemoryProvider struct {
+	srid   uint64
+	layers []*memoryLayer
+}
+
+// memoryLayer 带空间索引的图层
+type memoryLayer struct {
+	name     string
+	features []*geom.Feature
+	index    *rtree
+}
+
+// NewMemoryProvider 由图层创建MemoryProvider
+// srid 为要素坐标的空间参考，几何为空的要素不会被查询到
+func NewMemoryProvider(srid uint64, layers []*Layer) (*MemoryProvider, error) {
+	if srid == 0 {
+		return nil, ErrInvalidSRID
+	}
+	if err := basic.CheckSRID(srid); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSRID, err)
+	}
+
+	p := &MemoryProvider{srid: srid}
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		ml := &memoryLayer{name: layer.Name}
+		var boxes [][4]float64
+		for i, f := range layer.Features {
+			if f == nil || f.Geometry == nil {
+				continue
+			}
+			box, ok, err := mercatorBounds(srid, f.Geometry)
+			if err != nil {
+				return nil, fmt.Errorf("图层 %s 要素 %d: %w", layer.Name, i, err)
+			}
+			if !ok {
+				continue
+			}
+			ml.features = append(ml.features, f)
+			boxes = append(boxes, box)
+		}
+		ml.index = newRTree(boxes)
+		p.layers = append(p.layers, ml)
+	}
+	return p, nil
+}
+
+// GetSrid 返回要素坐标的空间参考
+func (p *MemoryProvider) GetSrid() uint64 {
+	return p.srid
+}
+
+// GetDataByTile 返回与带缓冲区瓦片范围相交的要素
+func (p *MemoryProvider) GetDataByTile(t *Tile) []*Layer {
+	layers, _ := CollectLayers(context.Background(), p, t)
+	return layers
+}
+
+// Features 依次回调与带缓冲区瓦片范围相交的要素，同一图层内保持要素的原始顺序
+func (p *MemoryProvider) Features(ctx context.Context, t *Tile, fn func(layer string, f *geom.Feature) error) error {
+	box := tileMercatorBounds(t)
+	var hits []int
+	for _, layer := range p.layers {
+		hits = hits[:0]
+		layer.index.search(box, func(i int) {
+			hits = append(hits, i)
+		})
+		sort.Ints(hits)
+
+		for _, i := range hits {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(layer.name, layer.features[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tileMercatorBounds 返回带缓冲区瓦片的Web墨卡托范围 minx, miny, maxx, maxy
+// 网格坐标系无法转换到Web墨卡托时返回整个墨卡托范围
+func tileMercatorBounds(t *Tile) [4]float64 {
+	box, err := tileBoundsInSRID(t, util.WebMercator)
+	if err != nil {
+		max := webmercator.MaxXExtent
+		return [4]float64{-max, -max, max, max}
+	}
+	return box
+}
+
+// tileBoundsInSRID 返回带缓冲区瓦片在指定坐标系下的范围 minx, miny, maxx, maxy
+func tileBoundsInSRID(t *Tile, srid uint64) ([4]float64, error) {
+	ext := t.GetExtent()
+	minx, maxx := min(ext.MinX(), ext.MaxX()), max(ext.MinX(), ext.MaxX())
+	miny, maxy := min(ext.MinY(), ext.MaxY()), max(ext.MinY(), ext.MaxY())
+
+	var margin float64
+	if t.Extent > 0 {
+		margin = (maxx - minx) * t.Buffer / t.Extent
+	}
+	box := [4]float64{minx - margin, miny - margin, maxx + margin, maxy + margin}
+	return transformBounds(t.SRID(), srid, box)
+}
+
+// geometryBounds 计算几何的外包框 minx, miny, maxx, maxy，几何没有坐标时返回false
+func geometryBounds(g geom.Geometry) ([4]float64, bool, error) {
+	box := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	_, err := mapCoords(g, func(pt []float64) []float64 {
+		if len(pt) >= 2 {
+			box[0], box[1] = min(box[0], pt[0]), min(box[1], pt[1])
+			box[2], box[3] = max(box[2], pt[0]), max(box[3], pt[1])
+		}
+		return pt
+	})
+	if err != nil {
+		return box, false, err
+	}
+	return box, box[0] <= box[2], nil
+}
+
+// mercatorBounds 计算几何在Web墨卡托下的外包框，几何没有坐标时返回false
+func mercatorBounds(srid uint64, g geom.Geometry) ([4]float64, bool, error) {
+	box, ok, err := geometryBounds(g)
+	if err != nil || !ok {
+		return box, false, err
+	}
+
+	if srid == util.WGS84 {
+		// 经纬度到Web墨卡托在两个方向上都是单调的，转换外包框的角点即可；
+		// 超出墨卡托纬度范围的坐标截断到范围边界
+		clamp := func(lat float64) float64 {
+			return max(-webmercator.MAX_LATITUDE, min(webmercator.MAX_LATITUDE, lat))
+		}
+		sw, err := toWebMercator(util.WGS84, [2]float64{box[0], clamp(box[1])})
+		if err != nil {
+			return box, false, err
+		}
+		ne, err := toWebMercator(util.WGS84, [2]float64{box[2], clamp(box[3])})
+		if err != nil {
+			return box, false, err
+		}
+		box = [4]float64{sw[0], sw[1], ne[0], ne[1]}
+	} else if srid != util.WebMercator {
+		// 其他投影不保证单调，转换全部坐标后重新计算
+		merc, err := basic.ToWebMercator(srid, g)
+		if err != nil {
+			return box, false, err
+		}
+		return geometryBounds(merc)
+	}
+	return box, true, nil
+}
+
+// rtree 按STR(Sort-Tile-Recursive)算法批量构建的静态R树
+type rtree struct {
+	root *rtreeNode
+}
+
+// rtreeNode R树节点，children为空时为叶子项
+type rtreeNode struct {
+	box      [4]float64
+	children []*rtreeNode
+	// item 叶子项对应的序号
+	item int
+}
+
+// newRTree 由外包框创建R树，search回调外包框在boxes中的序号
+func newRTree(boxes [][4]float64) *rtree {
+	if len(boxes) == 0 {
+		return &rtree{}
+	}
+	nodes := make([]*rtreeNode, len(boxes))
+	for i, b := range boxes {
+		nodes[i] = &rtreeNode{box: b, item: i}
+	}
+	// 逐层打包，直到只剩根节点
+	for len(nodes) > 1 || nodes[0].children == nil {
+		nodes = packNodes(nodes)
+	}
+	return &rtree{root: nodes[0]}
+}
+
+// packNodes 将一层节点按STR算法打包为上一层节点
+func packNodes(nodes []*rtreeNode) []*rtreeNode {
+	centerX := func(n *rtreeNode) float64 { return n.box[0] + n.box[2] }
+	centerY := func(n *rtreeNode) float64 { return n.box[1] + n.box[3] }
+
+	parents := int(math.Ceil(float64(len(nodes)) / rtreeNodeSize))
+	slices := int(math.Ceil(math.Sqrt(float64(parents))))
+	sliceSize := slices * rtreeNodeSize
+
+	sort.Slice(nodes, func(i, j int) bool { return centerX(nodes[i]) < centerX(nodes[j]) })
+
+	result := make([]*rtreeNode, 0, parents)
+	for start := 0; start < len(nodes); start += sliceSize {
+		slice := nodes[start:min(start+sliceSize, len(nodes))]
+		sort.Slice(slice, func(i, j int) bool { return centerY(slice[i]) < centerY(slice[j]) })
+
+		for i := 0; i < len(slice); i += rtreeNodeSize {
+			children := append([]*rtreeNode(nil), slice[i:min(i+rtreeNodeSize, len(slice))]...)
+			parent := &rtreeNode{box: children[0].box, children: children}
+			for _, c := range children[1:] {
+				parent.box = [4]float64{
+					min(parent.box[0], c.box[0]), min(parent.box[1], c.box[1]),
+					max(parent.box[2], c.box[2]), max(parent.box[3], c.box[3]),
+				}
+			}
+			result = append(result, parent)
+		}
+	}
+	return result
+}
+
+// search 回调与box相交的所有外包框的序号
+func (t *rtree) search(box [4]float64, fn func(int)) {
+	if t.root != nil {
+		t.root.search(box, fn)
+	}
+}
+
+func (n *rtreeNode) search(box [4]float64, fn func(int)) {
+	for _, c := range n.children {
+		if !boxesIntersect(c.box, box) {
+			continue
+		}
+		if c.children == nil {
+			fn(c.item)
+		} else {
+			c.search(box, fn)
+		}
+	}
+}
+
+// boxesIntersect 判断两个外包框是否相交，边界接触也视为相交
+func boxesIntersect(a, b [4]float64) bool {
+	return a[0] <= b[2] && a[2] >= b[0] && a[1] <= b[3] && a[3] >= b[1]
+}
@@ -0,0 +1,154 @@
+package tile
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// TestMemoryProvider 测试按瓦片范围查询要素
+func TestMemoryProvider(t *testing.T) {
+	named := func(name string, g geom.Geometry) *geom.Feature {
+		return &geom.Feature{Geometry: g, Properties: map[string]interface{}{"name": name}}
+	}
+	provider, err := NewMemoryProvider(4326, []*Layer{
+		{Name: "cities", Features: []*geom.Feature{
+			named("beijing", basic.Point{116.4, 39.9}),
+			named("london", basic.Point{-0.1, 51.5}),
+			{},
+		}},
+		{Name: "areas", Features: []*geom.Feature{
+			named("china", square(100, 20, 20)),
+			named("pole", basic.Line{{0, 80}, {10, 90}}),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewMemoryProvider() 错误 = %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		tile     *Tile
+		expected []string
+	}{
+		{"全球", NewTile(0, 0, 0), []string{"beijing", "london", "china", "pole"}},
+		{"北京", NewTileLatLong(6, 39.9, 116.4), []string{"beijing", "china"}},
+		{"伦敦", NewTileLatLong(6, 51.5, -0.1), []string{"london"}},
+		{"超出墨卡托纬度范围", NewTileLatLong(4, 85, 5), []string{"pole"}},
+		{"空白区域", NewTileLatLong(6, -40, -120), nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			names := featureNames(provider.GetDataByTile(tc.tile))
+			if len(names) != len(tc.expected) {
+				t.Fatalf("GetDataByTile() = %v, want %v", names, tc.expected)
+			}
+			for i := range names {
+				if names[i] != tc.expected[i] {
+					t.Fatalf("GetDataByTile() = %v, want %v", names, tc.expected)
+				}
+			}
+		})
+	}
+}
+
+// TestMemoryProvider_Buffer 测试返回缓冲区内的要素
+func TestMemoryProvider_Buffer(t *testing.T) {
+	tile := NewTileWithOptions(2, 1, 1, 64, 4096, DefaultEpislon)
+	ext := tile.GetExtent()
+	margin := (ext.MaxX() - ext.MinX()) * 64 / 4096
+	y := (ext.MinY() + ext.MaxY()) / 2
+
+	provider, err := NewMemoryProvider(3857, []*Layer{{Name: "points", Features: []*geom.Feature{
+		{Geometry: basic.Point{ext.MaxX() + margin/2, y}, Properties: map[string]interface{}{"name": "inside"}},
+		{Geometry: basic.Point{ext.MaxX() + margin*2, y}, Properties: map[string]interface{}{"name": "outside"}},
+	}}})
+	if err != nil {
+		t.Fatalf("NewMemoryProvider() 错误 = %v", err)
+	}
+
+	if names := featureNames(provider.GetDataByTile(tile)); len(names) != 1 || names[0] != "inside" {
+		t.Errorf("GetDataByTile() = %v, want [inside]", names)
+	}
+}
+
+// TestMemoryProvider_SRID 测试WGS84和Web墨卡托以外的坐标系
+func TestMemoryProvider_SRID(t *testing.T) {
+	if _, err := NewMemoryProvider(0, nil); !errors.Is(err, ErrInvalidSRID) {
+		t.Errorf("NewMemoryProvider() 错误 = %v, want %v", err, ErrInvalidSRID)
+	}
+	provider, err := NewMemoryProvider(4490, nil)
+	if err != nil {
+		t.Fatalf("NewMemoryProvider() 错误 = %v", err)
+	}
+	if provider.GetSrid() != 4490 {
+		t.Errorf("GetSrid() = %v, want 4490", provider.GetSrid())
+	}
+}
+
+// TestRTree_search 测试R树查询结果与逐个比较一致
+func TestRTree_search(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	boxes := make([][4]float64, 1000)
+	for i := range boxes {
+		x, y := r.Float64()*1000, r.Float64()*1000
+		boxes[i] = [4]float64{x, y, x + r.Float64()*20, y + r.Float64()*20}
+	}
+	tree := newRTree(boxes)
+
+	for n := 0; n < 100; n++ {
+		x, y := r.Float64()*1000, r.Float64()*1000
+		query := [4]float64{x, y, x + r.Float64()*100, y + r.Float64()*100}
+
+		var got, want []int
+		tree.search(query, func(i int) { got = append(got, i) })
+		for i, b := range boxes {
+			if boxesIntersect(b, query) {
+				want = append(want, i)
+			}
+		}
+		sort.Ints(got)
+		if len(got) != len(want) {
+			t.Fatalf("search(%v) = %v, want %v", query, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("search(%v) = %v, want %v", query, got, want)
+			}
+		}
+	}
+
+	newRTree(nil).search([4]float64{0, 0, 1, 1}, func(int) {
+		t.Error("空R树不应返回结果")
+	})
+}
+
+// TestTiler_MemoryProvider 测试只导出包含要素的瓦片
+func TestTiler_MemoryProvider(t *testing.T) {
+	provider, err := NewMemoryProvider(4326, []*Layer{{Name: "cities", Features: []*geom.Feature{
+		{Geometry: basic.Point{116.4, 39.9}},
+	}}})
+	if err != nil {
+		t.Fatalf("NewMemoryProvider() 错误 = %v", err)
+	}
+	exporter := &MockExporter{}
+	tiler := NewTiler(&Config{Provider: provider, Exporter: exporter, MaxZoom: 3})
+
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	saved := exporter.GetSavedTiles()
+	if len(saved) != 4 {
+		t.Fatalf("导出瓦片数 = %v, want 每级1个", len(saved))
+	}
+	for _, s := range saved {
+		if want := NewTileLatLong(s.Tile.Z, 39.9, 116.4); s.Tile.X != want.X || s.Tile.Y != want.Y {
+			t.Errorf("导出瓦片 = %v, want %v", s.Tile.ToString(), want.ToString())
+		}
+	}
+}
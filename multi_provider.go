@@ -0,0 +1,86 @@
+package tile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// MultiProvider 按图层合并多个Provider的数据，各Provider中同名的图层合并为一个图层
+// 坐标系与第一个Provider不同的要素在读取时转换到第一个Provider的坐标系
+type MultiProvider struct {
+	providers []Provider
+	srid      uint64
+}
+
+// NewMultiProvider 由一个或多个Provider创建MultiProvider
+func NewMultiProvider(providers ...Provider) (*MultiProvider, error) {
+	if len(providers) == 0 {
+		return nil, ErrNoProvider
+	}
+	for _, p := range providers {
+		if p == nil {
+			return nil, ErrNoProvider
+		}
+	}
+	return &MultiProvider{providers: providers, srid: providers[0].GetSrid()}, nil
+}
+
+// GetSrid 返回第一个Provider的坐标系
+func (p *MultiProvider) GetSrid() uint64 {
+	return p.srid
+}
+
+// GetDataByTile 返回各Provider在瓦片内的要素，读取出错时返回nil
+func (p *MultiProvider) GetDataByTile(t *Tile) []*Layer {
+	layers, _ := CollectLayers(context.Background(), p, t)
+	return layers
+}
+
+// Features 依次回调各Provider在瓦片内的要素
+func (p *MultiProvider) Features(ctx context.Context, t *Tile, fn func(layer string, f *geom.Feature) error) error {
+	for _, provider := range p.providers {
+		sp, ok := provider.(StreamingProvider)
+		if !ok {
+			sp = NewStreamingAdapter(provider)
+		}
+
+		srid := provider.GetSrid()
+		cb := fn
+		if srid != p.srid {
+			cb = func(layer string, f *geom.Feature) error {
+				if f == nil || f.Geometry == nil {
+					return fn(layer, f)
+				}
+				g, err := basic.Reproject(srid, p.srid, f.Geometry)
+				if err != nil {
+					return fmt.Errorf("图层 %s 坐标转换失败: %w", layer, err)
+				}
+				nf := *f
+				nf.Geometry = g
+				return fn(layer, &nf)
+			}
+		}
+		if err := sp.Features(ctx, t, cb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭实现了io.Closer的Provider
+func (p *MultiProvider) Close() error {
+	var errs []error
+	for _, provider := range p.providers {
+		if c, ok := provider.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
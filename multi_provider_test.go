@@ -0,0 +1,56 @@
+package tile
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// TestMultiProvider 测试按图层合并多个Provider并转换坐标系
+func TestMultiProvider(t *testing.T) {
+	wgs84, err := NewMemoryProvider(4326, []*Layer{
+		{Name: "places", Features: []*geom.Feature{{Geometry: basic.Point{116.4, 39.9}, Properties: map[string]interface{}{"name": "a"}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mercator, err := NewMemoryProvider(3857, []*Layer{
+		{Name: "places", Features: []*geom.Feature{{Geometry: basic.Point{12957468.2, 4852834.1}, Properties: map[string]interface{}{"name": "b"}}}},
+		{Name: "roads", Features: []*geom.Feature{{Geometry: basic.Line{{12950000, 4850000}, {12960000, 4850000}}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewMultiProvider(wgs84, mercator)
+	if err != nil {
+		t.Fatalf("NewMultiProvider() 错误 = %v", err)
+	}
+	if p.GetSrid() != 4326 {
+		t.Errorf("GetSrid() = %d, want 4326", p.GetSrid())
+	}
+
+	layers, err := CollectLayers(context.Background(), p, NewTile(8, 210, 97))
+	if err != nil {
+		t.Fatalf("CollectLayers() 错误 = %v", err)
+	}
+	if len(layers) != 2 || layers[0].Name != "places" || len(layers[0].Features) != 2 || layers[1].Name != "roads" {
+		t.Fatalf("图层 = %+v", layers)
+	}
+	pt, ok := layers[0].Features[1].Geometry.(geom.Point)
+	if !ok || math.Abs(pt.X()-116.4) > 0.02 || math.Abs(pt.Y()-39.9) > 0.02 {
+		t.Errorf("转换后的点 = %#v, want (116.4, 39.9)", layers[0].Features[1].Geometry)
+	}
+	// 原要素不被修改
+	if _, ok := mercator.GetDataByTile(NewTile(8, 210, 97))[0].Features[0].Geometry.(basic.Point); !ok {
+		t.Error("原要素的几何被修改")
+	}
+
+	if _, err := NewMultiProvider(); !errors.Is(err, ErrNoProvider) {
+		t.Errorf("无Provider错误 = %v, want %v", err, ErrNoProvider)
+	}
+}
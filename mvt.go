@@ -65,28 +65,37 @@ func NewMVTExporterWithOptions(options MVTOptions) *MVTExporter {
 
 // SaveTile 保存瓦片为MVT格式
 func (s *MVTExporter) SaveTile(res []*Layer, tile *Tile, path string) error {
+	_, err := s.SaveTileSize(res, tile, path)
+	return err
+}
+
+// SaveTileSize 保存瓦片并返回编码后的字节数
+func (s *MVTExporter) SaveTileSize(res []*Layer, tile *Tile, path string) (int64, error) {
 	if tile == nil {
-		return ErrInvalidTile
+		return 0, ErrInvalidTile
 	}
 
 	if path == "" {
-		return ErrInvalidPath
+		return 0, ErrInvalidPath
 	}
 
 	// 确保目录存在
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, s.Options.DirMode); err != nil {
-		return fmt.Errorf("创建目录失败: %w", err)
+		return 0, fmt.Errorf("创建目录失败: %w", err)
 	}
 
 	// 生成MVT数据
 	mvtData, err := s.GenerateMVT(res, tile)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// 写入文件
-	return os.WriteFile(path, mvtData, s.Options.FileMode)
+	if err := os.WriteFile(path, mvtData, s.Options.FileMode); err != nil {
+		return 0, err
+	}
+	return int64(len(mvtData)), nil
 }
 
 // GenerateMVT 生成MVT数据
@@ -111,6 +120,11 @@ func (s *MVTExporter) GenerateMVT(layers []*Layer, tile *Tile) ([]byte, error) {
 			Name:   layer.Name,
 			Proto:  s.Options.Proto,
 		}
+		// 图层按LayerOptions.Extent转换坐标时写入相同的范围
+		if layer.Extent != 0 {
+			config.Extent = int32(layer.Extent)
+			config.ExtentBool = true
+		}
 
 		mvtLayer := mvt.NewLayerConfig(config)
 
@@ -158,6 +172,11 @@ func (s *MVTExporter) GenerateMVT(layers []*Layer, tile *Tile) ([]byte, error) {
 	return mvtData, nil
 }
 
+// Encode 实现Encoder接口，与GenerateMVT相同
+func (s *MVTExporter) Encode(layers []*Layer, tile *Tile) ([]byte, error) {
+	return s.GenerateMVT(layers, tile)
+}
+
 // SaveTileToWriter 将瓦片数据写入io.Writer
 func (s *MVTExporter) SaveTileToWriter(res []*Layer, tile *Tile, writer io.Writer) error {
 	if writer == nil {
@@ -175,6 +194,14 @@ func (s *MVTExporter) SaveTileToWriter(res []*Layer, tile *Tile, writer io.Write
 	return err
 }
 
+// RemoveTile 删除已导出的瓦片文件
+func (s *MVTExporter) RemoveTile(tile *Tile, path string) error {
+	if tile == nil {
+		return ErrInvalidTile
+	}
+	return removeTileFile(path)
+}
+
 // Extension 返回文件扩展名
 func (s *MVTExporter) Extension() string {
 	return "mvt"
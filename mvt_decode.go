@@ -0,0 +1,479 @@
+package tile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/flywave/go-geom"
+	gen "github.com/flywave/go-geom/general"
+)
+
+// MVTDecodeOptions MVT解码选项
+type MVTDecodeOptions struct {
+	// SRID 输出坐标系，0表示图层范围(Layer.Extent)下的瓦片像素坐标，
+	// 其他值通过Tile.FromPixel转换，如4326(WGS84)或3857(Web墨卡托)
+	SRID int
+}
+
+// DefaultMVTDecodeOptions 默认解码选项，输出像素坐标
+var DefaultMVTDecodeOptions = MVTDecodeOptions{}
+
+// MVT几何类型
+const (
+	mvtPoint      = 1
+	mvtLineString = 2
+	mvtPolygon    = 3
+)
+
+// MVT几何命令
+const (
+	mvtMoveTo    = 1
+	mvtLineTo    = 2
+	mvtClosePath = 7
+)
+
+// mvtDefaultExtent MVT图层未设置extent时的默认值
+const mvtDefaultExtent = 4096
+
+// DecodeMVT 将MVT瓦片解码为图层，几何为图层范围下的像素坐标(y轴向下)，tile可以为nil
+func DecodeMVT(data []byte, tile *Tile) ([]*Layer, error) {
+	return DecodeMVTWithOptions(data, tile, DefaultMVTDecodeOptions)
+}
+
+// DecodeMVTWithOptions 将MVT瓦片解码为图层
+// 多边形的环按面积符号区分外环和内环，每个外环开始一个新的多边形，环的首尾点相同；
+// 属性值按类型解码为string、float64、int64、uint64或bool，要素ID为uint64
+func DecodeMVTWithOptions(data []byte, tile *Tile, options MVTDecodeOptions) ([]*Layer, error) {
+	if options.SRID != 0 && tile == nil {
+		return nil, ErrInvalidTile
+	}
+
+	var layers []*Layer
+	r := pbReader{buf: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+		if field != 3 || wire != pbBytes {
+			if err := r.skip(wire); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		b, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		layer, err := decodeMVTLayer(b, tile, options)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// mvtRawFeature 尚未解码属性和几何的要素
+type mvtRawFeature struct {
+	id       uint64
+	hasID    bool
+	tags     []uint64
+	typ      uint64
+	geometry []uint64
+}
+
+// decodeMVTLayer 解码图层，要素在读取完键值表后解码
+func decodeMVTLayer(data []byte, tile *Tile, options MVTDecodeOptions) (*Layer, error) {
+	layer := &Layer{Extent: mvtDefaultExtent, SRID: options.SRID}
+	var keys []string
+	var values []interface{}
+	var raw []mvtRawFeature
+
+	r := pbReader{buf: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 1 && wire == pbBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			layer.Name = string(b)
+		case field == 2 && wire == pbBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			f, err := decodeMVTRawFeature(b)
+			if err != nil {
+				return nil, err
+			}
+			raw = append(raw, f)
+		case field == 3 && wire == pbBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, string(b))
+		case field == 4 && wire == pbBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeMVTValue(b)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		case field == 5 && wire == pbVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			layer.Extent = v
+		default:
+			if err := r.skip(wire); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if layer.Extent == 0 {
+		return nil, fmt.Errorf("%w: 图层 %s 的extent为0", ErrInvalidMVT, layer.Name)
+	}
+
+	// 转换坐标时使用图层范围作为瓦片像素范围
+	convert := func(x, y float64) ([]float64, error) { return []float64{x, y}, nil }
+	if options.SRID != 0 {
+		t := tile.Clone()
+		t.SetExtent(float64(layer.Extent))
+		convert = func(x, y float64) ([]float64, error) {
+			pt, err := t.FromPixel(options.SRID, [2]float64{x, y})
+			if err != nil {
+				return nil, err
+			}
+			return []float64{pt[0], pt[1]}, nil
+		}
+	}
+
+	for i, f := range raw {
+		feature := &geom.Feature{Type: "Feature", Properties: make(map[string]interface{}, len(f.tags)/2)}
+		if f.hasID {
+			feature.ID = f.id
+		}
+		if len(f.tags)%2 != 0 {
+			return nil, fmt.Errorf("%w: 图层 %s 要素 %d 的tags长度为奇数", ErrInvalidMVT, layer.Name, i)
+		}
+		for j := 0; j < len(f.tags); j += 2 {
+			k, v := f.tags[j], f.tags[j+1]
+			if k >= uint64(len(keys)) || v >= uint64(len(values)) {
+				return nil, fmt.Errorf("%w: 图层 %s 要素 %d 的属性索引越界", ErrInvalidMVT, layer.Name, i)
+			}
+			feature.Properties[keys[k]] = values[v]
+		}
+		g, err := decodeMVTGeometry(f.typ, f.geometry, convert)
+		if err != nil {
+			return nil, fmt.Errorf("图层 %s 要素 %d: %w", layer.Name, i, err)
+		}
+		feature.Geometry = g
+		layer.Features = append(layer.Features, feature)
+	}
+	return layer, nil
+}
+
+// decodeMVTRawFeature 读取要素的字段
+func decodeMVTRawFeature(data []byte) (mvtRawFeature, error) {
+	var f mvtRawFeature
+	r := pbReader{buf: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return f, err
+		}
+		switch {
+		case field == 1 && wire == pbVarint:
+			f.id, err = r.varint()
+			f.hasID = true
+		case field == 2:
+			f.tags, err = r.uint64s(wire, f.tags)
+		case field == 3 && wire == pbVarint:
+			f.typ, err = r.varint()
+		case field == 4:
+			f.geometry, err = r.uint64s(wire, f.geometry)
+		default:
+			err = r.skip(wire)
+		}
+		if err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// decodeMVTValue 解码属性值
+func decodeMVTValue(data []byte) (interface{}, error) {
+	var value interface{}
+	r := pbReader{buf: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 1 && wire == pbBytes:
+			var b []byte
+			b, err = r.bytes()
+			value = string(b)
+		case field == 2 && wire == pbFixed32:
+			var v uint32
+			v, err = r.fixed32()
+			value = float64(math.Float32frombits(v))
+		case field == 3 && wire == pbFixed64:
+			var v uint64
+			v, err = r.fixed64()
+			value = math.Float64frombits(v)
+		case field == 4 && wire == pbVarint:
+			var v uint64
+			v, err = r.varint()
+			value = int64(v)
+		case field == 5 && wire == pbVarint:
+			value, err = r.varint()
+		case field == 6 && wire == pbVarint:
+			var v uint64
+			v, err = r.varint()
+			value = unzigzag(v)
+		case field == 7 && wire == pbVarint:
+			var v uint64
+			v, err = r.varint()
+			value = v != 0
+		default:
+			err = r.skip(wire)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// decodeMVTGeometry 解码命令编码的几何，convert将像素坐标转换为输出坐标
+func decodeMVTGeometry(typ uint64, cmds []uint64, convert func(x, y float64) ([]float64, error)) (geom.Geometry, error) {
+	// 按MoveTo分段读取像素坐标，ClosePath闭合多边形的环
+	var parts [][][]float64
+	var cx, cy int64
+	for i := 0; i < len(cmds); {
+		id, count := cmds[i]&0x7, int(cmds[i]>>3)
+		i++
+		switch id {
+		case mvtMoveTo, mvtLineTo:
+			if count > (len(cmds)-i)/2 {
+				return nil, fmt.Errorf("%w: 几何命令参数不足", ErrInvalidMVT)
+			}
+			for j := 0; j < count; j++ {
+				cx += unzigzag(cmds[i])
+				cy += unzigzag(cmds[i+1])
+				i += 2
+				if id == mvtMoveTo && (typ != mvtPoint || len(parts) == 0) {
+					parts = append(parts, nil)
+				}
+				if len(parts) == 0 {
+					return nil, fmt.Errorf("%w: LineTo之前缺少MoveTo", ErrInvalidMVT)
+				}
+				parts[len(parts)-1] = append(parts[len(parts)-1], []float64{float64(cx), float64(cy)})
+			}
+		case mvtClosePath:
+			if len(parts) == 0 || len(parts[len(parts)-1]) == 0 {
+				return nil, fmt.Errorf("%w: ClosePath之前缺少MoveTo", ErrInvalidMVT)
+			}
+			ring := parts[len(parts)-1]
+			parts[len(parts)-1] = append(ring, []float64{ring[0][0], ring[0][1]})
+		default:
+			return nil, fmt.Errorf("%w: 未知的几何命令 %d", ErrInvalidMVT, id)
+		}
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	if typ == mvtPolygon {
+		// 先在像素坐标下区分外环和内环，再转换坐标
+		polygons := mvtPolygons(parts)
+		for _, polygon := range polygons {
+			if err := convertParts(polygon, convert); err != nil {
+				return nil, err
+			}
+		}
+		if len(polygons) == 1 {
+			return gen.NewPolygon(polygons[0]), nil
+		}
+		return gen.NewMultiPolygon(polygons), nil
+	}
+	if err := convertParts(parts, convert); err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case mvtPoint:
+		if len(parts[0]) == 1 {
+			return gen.NewPoint(parts[0][0]), nil
+		}
+		return gen.NewMultiPoint(parts[0]), nil
+	case mvtLineString:
+		if len(parts) == 1 {
+			return gen.NewLineString(parts[0]), nil
+		}
+		return gen.NewMultiLineString(parts), nil
+	}
+	return nil, fmt.Errorf("%w: 未知的几何类型 %d", ErrInvalidMVT, typ)
+}
+
+// convertParts 原地转换各部分的坐标
+func convertParts(parts [][][]float64, convert func(x, y float64) ([]float64, error)) error {
+	for _, part := range parts {
+		for i, pt := range part {
+			npt, err := convert(pt[0], pt[1])
+			if err != nil {
+				return err
+			}
+			part[i] = npt
+		}
+	}
+	return nil
+}
+
+// mvtPolygons 按环的面积符号组合多边形，像素坐标(y轴向下)下外环面积为正，
+// 每个外环开始一个新的多边形，面积为0的环被忽略
+func mvtPolygons(rings [][][]float64) [][][][]float64 {
+	var polygons [][][][]float64
+	for _, ring := range rings {
+		area := ringSignedArea(ring)
+		switch {
+		case area == 0:
+			continue
+		case area > 0 || len(polygons) == 0:
+			polygons = append(polygons, [][][]float64{ring})
+		default:
+			polygons[len(polygons)-1] = append(polygons[len(polygons)-1], ring)
+		}
+	}
+	return polygons
+}
+
+// unzigzag 解码zigzag编码的整数
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// protobuf线路类型
+const (
+	pbVarint  = 0
+	pbFixed64 = 1
+	pbBytes   = 2
+	pbFixed32 = 5
+)
+
+// pbReader 读取protobuf编码的消息
+type pbReader struct {
+	buf []byte
+	pos int
+}
+
+// done 判断是否读完
+func (r *pbReader) done() bool {
+	return r.pos >= len(r.buf)
+}
+
+// key 读取字段编号和线路类型
+func (r *pbReader) key() (uint64, uint64, error) {
+	v, err := r.varint()
+	return v >> 3, v & 0x7, err
+}
+
+// varint 读取变长整数
+func (r *pbReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: 变长整数无效", ErrInvalidMVT)
+	}
+	r.pos += n
+	return v, nil
+}
+
+// bytes 读取长度前缀的数据
+func (r *pbReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.buf)-r.pos) {
+		return nil, fmt.Errorf("%w: 数据长度越界", ErrInvalidMVT)
+	}
+	b := r.buf[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+// fixed32 读取4字节定长值
+func (r *pbReader) fixed32() (uint32, error) {
+	if len(r.buf)-r.pos < 4 {
+		return 0, fmt.Errorf("%w: 数据长度越界", ErrInvalidMVT)
+	}
+	v := binary.LittleEndian.Uint32(r.buf[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+// fixed64 读取8字节定长值
+func (r *pbReader) fixed64() (uint64, error) {
+	if len(r.buf)-r.pos < 8 {
+		return 0, fmt.Errorf("%w: 数据长度越界", ErrInvalidMVT)
+	}
+	v := binary.LittleEndian.Uint64(r.buf[r.pos:])
+	r.pos += 8
+	return v, nil
+}
+
+// uint64s 读取打包或未打包的repeated整数字段并追加到dst
+func (r *pbReader) uint64s(wire uint64, dst []uint64) ([]uint64, error) {
+	if wire == pbVarint {
+		v, err := r.varint()
+		return append(dst, v), err
+	}
+	if wire != pbBytes {
+		return dst, fmt.Errorf("%w: 线路类型 %d 无效", ErrInvalidMVT, wire)
+	}
+	b, err := r.bytes()
+	if err != nil {
+		return dst, err
+	}
+	packed := pbReader{buf: b}
+	for !packed.done() {
+		v, err := packed.varint()
+		if err != nil {
+			return dst, err
+		}
+		dst = append(dst, v)
+	}
+	return dst, nil
+}
+
+// skip 跳过未知字段
+func (r *pbReader) skip(wire uint64) error {
+	var err error
+	switch wire {
+	case pbVarint:
+		_, err = r.varint()
+	case pbFixed64:
+		_, err = r.fixed64()
+	case pbBytes:
+		_, err = r.bytes()
+	case pbFixed32:
+		_, err = r.fixed32()
+	default:
+		err = fmt.Errorf("%w: 线路类型 %d 无效", ErrInvalidMVT, wire)
+	}
+	return err
+}
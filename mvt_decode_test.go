@@ -0,0 +1,294 @@
+package tile
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// pbBuilder 测试用的protobuf编码
+type pbBuilder []byte
+
+func (b pbBuilder) varint(field int, v uint64) pbBuilder {
+	b = binary.AppendUvarint(b, uint64(field)<<3|pbVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func (b pbBuilder) bytes(field int, data []byte) pbBuilder {
+	b = binary.AppendUvarint(b, uint64(field)<<3|pbBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func (b pbBuilder) packed(field int, vs []uint64) pbBuilder {
+	var data []byte
+	for _, v := range vs {
+		data = binary.AppendUvarint(data, v)
+	}
+	return b.bytes(field, data)
+}
+
+func (b pbBuilder) fixed64(field int, v uint64) pbBuilder {
+	b = binary.AppendUvarint(b, uint64(field)<<3|pbFixed64)
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+func (b pbBuilder) fixed32(field int, v uint32) pbBuilder {
+	b = binary.AppendUvarint(b, uint64(field)<<3|pbFixed32)
+	return binary.LittleEndian.AppendUint32(b, v)
+}
+
+// mvtTestFeature 测试要素，parts为像素坐标，点要素的所有点在parts[0]中
+type mvtTestFeature struct {
+	id    uint64
+	typ   uint64
+	parts [][][2]int64
+	tags  []uint64
+}
+
+// mvtCommands 将像素坐标编码为几何命令
+func mvtCommands(typ uint64, parts [][][2]int64) []uint64 {
+	zigzag := func(v int64) uint64 { return uint64(v<<1) ^ uint64(v>>63) }
+	command := func(id, count int) uint64 { return uint64(id) | uint64(count)<<3 }
+	var cmds []uint64
+	var cx, cy int64
+	add := func(pt [2]int64) {
+		cmds = append(cmds, zigzag(pt[0]-cx), zigzag(pt[1]-cy))
+		cx, cy = pt[0], pt[1]
+	}
+	if typ == mvtPoint {
+		cmds = append(cmds, command(mvtMoveTo, len(parts[0])))
+		for _, pt := range parts[0] {
+			add(pt)
+		}
+		return cmds
+	}
+	for _, part := range parts {
+		cmds = append(cmds, command(mvtMoveTo, 1))
+		add(part[0])
+		cmds = append(cmds, command(mvtLineTo, len(part)-1))
+		for _, pt := range part[1:] {
+			add(pt)
+		}
+		if typ == mvtPolygon {
+			cmds = append(cmds, command(mvtClosePath, 1))
+		}
+	}
+	return cmds
+}
+
+// mvtTestLayer 编码图层，values为已编码的Value消息
+func mvtTestLayer(name string, extent uint64, keys []string, values [][]byte, features []mvtTestFeature) []byte {
+	var b pbBuilder
+	b = b.varint(15, 2).bytes(1, []byte(name))
+	for _, f := range features {
+		var fb pbBuilder
+		if f.id != 0 {
+			fb = fb.varint(1, f.id)
+		}
+		if len(f.tags) > 0 {
+			fb = fb.packed(2, f.tags)
+		}
+		fb = fb.varint(3, f.typ).packed(4, mvtCommands(f.typ, f.parts))
+		b = b.bytes(2, fb)
+	}
+	for _, k := range keys {
+		b = b.bytes(3, []byte(k))
+	}
+	for _, v := range values {
+		b = b.bytes(4, v)
+	}
+	if extent != 0 {
+		b = b.varint(5, extent)
+	}
+	return pbBuilder(nil).bytes(3, b)
+}
+
+// geometryData 返回几何的坐标数据
+func geometryData(g geom.Geometry) interface{} {
+	switch g := g.(type) {
+	case geom.Point:
+		return g.Data()
+	case geom.MultiPoint:
+		return g.Data()
+	case geom.LineString:
+		return g.Data()
+	case geom.MultiLine:
+		return g.Data()
+	case geom.Polygon:
+		return g.Data()
+	case geom.MultiPolygon:
+		return g.Data()
+	}
+	return nil
+}
+
+// TestDecodeMVT 测试几何、属性和要素ID的解码
+func TestDecodeMVT(t *testing.T) {
+	values := [][]byte{
+		pbBuilder(nil).bytes(1, []byte("北京")),
+		pbBuilder(nil).varint(6, uint64(5)), // sint64 -3
+		pbBuilder(nil).fixed64(3, math.Float64bits(1.5)),
+		pbBuilder(nil).varint(7, 1),
+		pbBuilder(nil).varint(4, 7),
+		pbBuilder(nil).varint(5, 9),
+		pbBuilder(nil).fixed32(2, math.Float32bits(2.5)),
+	}
+	square := func(x, y, size int64, clockwise bool) [][2]int64 {
+		if clockwise {
+			return [][2]int64{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}}
+		}
+		return [][2]int64{{x, y}, {x, y + size}, {x + size, y + size}, {x + size, y}}
+	}
+	data := mvtTestLayer("pois", 0, []string{"name", "rank", "height", "open", "count", "total", "ratio"}, values, []mvtTestFeature{
+		{id: 1, typ: mvtPoint, parts: [][][2]int64{{{10, 20}}}, tags: []uint64{0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6}},
+		{typ: mvtPoint, parts: [][][2]int64{{{5, 5}, {7, -9}}}},
+		{typ: mvtLineString, parts: [][][2]int64{{{0, 0}, {10, 0}, {10, 10}}}},
+		{typ: mvtLineString, parts: [][][2]int64{{{0, 0}, {1, 1}}, {{5, 5}, {6, 8}}}},
+		{typ: mvtPolygon, parts: [][][2]int64{square(0, 0, 10, true), square(2, 2, 6, false)}},
+		{typ: mvtPolygon, parts: [][][2]int64{square(0, 0, 10, true), square(20, 20, 5, true), square(21, 21, 2, false)}},
+	})
+	data = append(data, mvtTestLayer("empty", 512, nil, nil, nil)...)
+
+	layers, err := DecodeMVT(data, nil)
+	if err != nil {
+		t.Fatalf("DecodeMVT() 错误 = %v", err)
+	}
+	if len(layers) != 2 || layers[0].Name != "pois" || layers[0].Extent != 4096 || layers[1].Name != "empty" || layers[1].Extent != 512 {
+		t.Fatalf("图层 = %+v", layers)
+	}
+	features := layers[0].Features
+	if len(features) != 6 {
+		t.Fatalf("要素数 = %d, want 6", len(features))
+	}
+
+	wantProps := map[string]interface{}{"name": "北京", "rank": int64(-3), "height": 1.5, "open": true, "count": int64(7), "total": uint64(9), "ratio": 2.5}
+	if !reflect.DeepEqual(features[0].Properties, wantProps) || features[0].ID != uint64(1) {
+		t.Errorf("属性 = %v, ID = %v", features[0].Properties, features[0].ID)
+	}
+	if features[1].ID != nil || len(features[1].Properties) != 0 {
+		t.Errorf("没有ID和属性的要素 = %+v", features[1])
+	}
+
+	closed := func(ring ...[]float64) [][]float64 { return append(ring, ring[0]) }
+	want := []interface{}{
+		[]float64{10, 20},
+		[][]float64{{5, 5}, {7, -9}},
+		[][]float64{{0, 0}, {10, 0}, {10, 10}},
+		[][][]float64{{{0, 0}, {1, 1}}, {{5, 5}, {6, 8}}},
+		[][][]float64{
+			closed([]float64{0, 0}, []float64{10, 0}, []float64{10, 10}, []float64{0, 10}),
+			closed([]float64{2, 2}, []float64{2, 8}, []float64{8, 8}, []float64{8, 2}),
+		},
+		[][][][]float64{
+			{closed([]float64{0, 0}, []float64{10, 0}, []float64{10, 10}, []float64{0, 10})},
+			{
+				closed([]float64{20, 20}, []float64{25, 20}, []float64{25, 25}, []float64{20, 25}),
+				closed([]float64{21, 21}, []float64{21, 23}, []float64{23, 23}, []float64{23, 21}),
+			},
+		},
+	}
+	for i, f := range features {
+		if got := geometryData(f.Geometry); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("要素 %d 几何 = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+// TestDecodeMVT_SRID 测试通过Tile.FromPixel转换坐标，图层范围与瓦片范围不同
+func TestDecodeMVT_SRID(t *testing.T) {
+	data := mvtTestLayer("points", 512, nil, nil, []mvtTestFeature{
+		{typ: mvtPoint, parts: [][][2]int64{{{256, 256}, {0, 0}}}},
+		{typ: mvtPolygon, parts: [][][2]int64{{{0, 0}, {512, 0}, {512, 512}, {0, 512}}}},
+	})
+	if _, err := DecodeMVTWithOptions(data, nil, MVTDecodeOptions{SRID: 4326}); !errors.Is(err, ErrInvalidTile) {
+		t.Errorf("tile为nil时错误 = %v, want %v", err, ErrInvalidTile)
+	}
+
+	tile := NewTile(0, 0, 0)
+	layers, err := DecodeMVTWithOptions(data, tile, MVTDecodeOptions{SRID: 4326})
+	if err != nil {
+		t.Fatalf("DecodeMVTWithOptions() 错误 = %v", err)
+	}
+	if layers[0].SRID != 4326 || tile.Extent != DefaultExtent {
+		t.Errorf("SRID = %d, 瓦片范围 = %v", layers[0].SRID, tile.Extent)
+	}
+	pts := geometryData(layers[0].Features[0].Geometry).([][]float64)
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+	if !near(pts[0][0], 0) || !near(pts[0][1], 0) || !near(pts[1][0], -180) || !near(pts[1][1], 85.0511287798) {
+		t.Errorf("点 = %v", pts)
+	}
+	// 坐标转换后外环仍被识别为一个多边形
+	if polygon, ok := layers[0].Features[1].Geometry.(geom.Polygon); !ok || len(polygon.Data()) != 1 {
+		t.Errorf("多边形 = %#v", layers[0].Features[1].Geometry)
+	}
+
+	layers, err = DecodeMVTWithOptions(data, tile, MVTDecodeOptions{SRID: 3857})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pts = geometryData(layers[0].Features[0].Geometry).([][]float64)
+	if !near(pts[1][0], -20037508.342789244) || !near(pts[1][1], 20037508.342789244) {
+		t.Errorf("Web墨卡托点 = %v", pts)
+	}
+}
+
+// TestDecodeMVT_Invalid 测试无效数据
+func TestDecodeMVT_Invalid(t *testing.T) {
+	valid := mvtTestLayer("a", 0, []string{"k"}, [][]byte{pbBuilder(nil).varint(7, 1)}, []mvtTestFeature{
+		{typ: mvtPoint, parts: [][][2]int64{{{1, 1}}}, tags: []uint64{0, 0}},
+	})
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"截断", valid[:len(valid)-1]},
+		{"变长整数", []byte{0xff}},
+		{"线路类型", []byte{0x1f}},
+		{"属性索引", mvtTestLayer("a", 0, []string{"k"}, nil, []mvtTestFeature{{typ: mvtPoint, parts: [][][2]int64{{{1, 1}}}, tags: []uint64{0, 0}}})},
+		{"tags长度", mvtTestLayer("a", 0, []string{"k"}, nil, []mvtTestFeature{{typ: mvtPoint, parts: [][][2]int64{{{1, 1}}}, tags: []uint64{0}}})},
+		{"几何命令", pbBuilder(nil).bytes(3, pbBuilder(nil).bytes(2, pbBuilder(nil).varint(3, mvtPoint).packed(4, []uint64{3<<3 | 1, 2})))},
+		{"LineTo", pbBuilder(nil).bytes(3, pbBuilder(nil).bytes(2, pbBuilder(nil).varint(3, mvtLineString).packed(4, []uint64{1<<3 | 2, 2, 2})))},
+		{"几何类型", pbBuilder(nil).bytes(3, pbBuilder(nil).bytes(2, pbBuilder(nil).varint(3, 9).packed(4, []uint64{1<<3 | 1, 2, 2})))},
+		{"extent", pbBuilder(nil).bytes(3, pbBuilder(nil).varint(5, 0))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeMVT(tt.data, nil); !errors.Is(err, ErrInvalidMVT) {
+				t.Errorf("DecodeMVT() 错误 = %v, want %v", err, ErrInvalidMVT)
+			}
+		})
+	}
+	if layers, err := DecodeMVT(valid, nil); err != nil || len(layers) != 1 || layers[0].Features[0].Properties["k"] != true {
+		t.Errorf("DecodeMVT() = %v, %v", layers, err)
+	}
+}
+
+// TestDecodeMVT_RoundTrip 测试解码MVTExporter生成的瓦片
+func TestDecodeMVT_RoundTrip(t *testing.T) {
+	tile := NewTile(2, 1, 1)
+	data, err := NewMVTExporter().GenerateMVT([]*Layer{
+		{Name: "roads", Features: []*geom.Feature{{Geometry: basic.Line{{0, 0}, {100, 100}}}}},
+		{Name: "water", Features: []*geom.Feature{{Geometry: basic.Point{10, 10}}}},
+	}, tile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers, err := DecodeMVT(data, tile)
+	if err != nil {
+		t.Fatalf("DecodeMVT() 错误 = %v", err)
+	}
+	if len(layers) != 2 || layers[0].Name != "roads" || layers[1].Name != "water" {
+		t.Errorf("图层 = %+v", layers)
+	}
+
+	if layers, err := DecodeMVT(mvtEmpty, tile); err != nil || len(layers) != 1 || len(layers[0].Features) != 0 {
+		t.Errorf("空瓦片 = %v, %v", layers, err)
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/flywave/go-geom"
 	"github.com/flywave/go-mapbox/mvt"
+
+	"github.com/flywave/go-vector-tiler/basic"
 )
 
 // 创建测试图层
@@ -165,6 +167,39 @@ func TestGenerateMVT(t *testing.T) {
 	}
 }
 
+// TestGenerateMVT_LayerExtent 测试图层范围写入MVT，与按该范围转换的坐标一致
+func TestGenerateMVT_LayerExtent(t *testing.T) {
+	tile := NewTile(1, 0, 0)
+	// 按LayerOptions.Extent为512处理后的像素坐标
+	layer := &Layer{Name: "cities", Extent: 512, Features: []*geom.Feature{
+		{Geometry: basic.Point{256, 256}},
+		{Geometry: basic.Line{{0, 0}, {512, 512}}},
+	}}
+	data, err := NewMVTExporter().GenerateMVT([]*Layer{layer, {Name: "roads", Features: []*geom.Feature{{Geometry: basic.Point{100, 100}}}}}, tile)
+	if err != nil {
+		t.Fatalf("GenerateMVT() 错误 = %v", err)
+	}
+
+	layers, err := DecodeMVT(data, tile)
+	if err != nil {
+		t.Fatalf("DecodeMVT() 错误 = %v", err)
+	}
+	if len(layers) != 2 || layers[0].Extent != 512 || len(layers[0].Features) != 2 {
+		t.Fatalf("图层 = %+v, want Extent 512 和2个要素", layers[0])
+	}
+	if layers[1].Extent != 4096 {
+		t.Errorf("未设置范围的图层 Extent = %v, want 4096", layers[1].Extent)
+	}
+	if p := layers[0].Features[0].Geometry.(geom.Point).Data(); p[0] != 256 || p[1] != 256 {
+		t.Errorf("点 = %v, want [256 256]", p)
+	}
+	for _, p := range layers[0].Features[1].Geometry.(geom.LineString).Data() {
+		if p[0] < 0 || p[0] > 512 || p[1] < 0 || p[1] > 512 {
+			t.Errorf("坐标 %v 超出图层范围512", p)
+		}
+	}
+}
+
 // TestGenerateMVTEmptyLayers 测试生成空图层的MVT数据
 func TestGenerateMVTEmptyLayers(t *testing.T) {
 	// 创建不使用空瓦片的导出器
@@ -321,6 +356,33 @@ func TestSaveTile(t *testing.T) {
 	}
 }
 
+// TestRemoveTile 测试删除瓦片文件
+func TestRemoveTile(t *testing.T) {
+	exporter := NewMVTExporter()
+	tile := NewTile(10, 512, 512)
+	path := filepath.Join(t.TempDir(), "10", "512", "512.mvt")
+
+	if err := exporter.SaveTile([]*Layer{createTestLayer("points", 1)}, tile, path); err != nil {
+		t.Fatalf("SaveTile 返回错误: %v", err)
+	}
+
+	if err := exporter.RemoveTile(tile, path); err != nil {
+		t.Fatalf("RemoveTile 返回错误: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("RemoveTile 没有删除文件")
+	}
+
+	// 文件不存在时不报错
+	if err := exporter.RemoveTile(tile, path); err != nil {
+		t.Errorf("删除不存在的文件不应报错，但得到 %v", err)
+	}
+
+	if err := exporter.RemoveTile(tile, ""); err != ErrInvalidPath {
+		t.Errorf("对于无效的路径，RemoveTile 应该返回 ErrInvalidPath，但得到 %v", err)
+	}
+}
+
 // TestBatchSaveTiles 测试批量保存瓦片
 func TestBatchSaveTiles(t *testing.T) {
 	exporter := NewMVTExporter()
@@ -0,0 +1,212 @@
+package tile
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// OverzoomLayers 由祖先瓦片处理后的图层生成更高级别瓦片的图层
+// layers 为ancestor瓦片像素坐标下的图层，通常是Tiler在最大级别处理后的结果；
+// tile 必须是ancestor的子孙瓦片。要素裁剪到tile的范围(含tile.Buffer缓冲区)后缩放到图层范围，
+// 不需要再次查询Provider。图层Extent为0时使用ancestor.Extent
+func OverzoomLayers(layers []*Layer, ancestor, tile *Tile) ([]*Layer, error) {
+	if ancestor == nil || tile == nil {
+		return nil, ErrInvalidTile
+	}
+	if tile.Z <= ancestor.Z {
+		return nil, ErrNotDescendant
+	}
+	d := tile.Z - ancestor.Z
+	if tile.X>>d != ancestor.X || tile.Y>>d != ancestor.Y {
+		return nil, ErrNotDescendant
+	}
+	dx, dy := tile.X-ancestor.X<<d, tile.Y-ancestor.Y<<d
+
+	var result []*Layer
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		extent := float64(layer.Extent)
+		if extent == 0 {
+			extent = ancestor.Extent
+		}
+		buffer := tile.Buffer
+		if tile.Extent > 0 {
+			buffer = tile.Buffer * extent / tile.Extent
+		}
+
+		nl, err := overzoomLayer(context.Background(), layer, extent, d, dx, dy, buffer)
+		if err != nil {
+			return nil, fmt.Errorf("图层 %s: %w", layer.Name, err)
+		}
+		if len(nl.Features) > 0 {
+			result = append(result, nl)
+		}
+	}
+	return result, nil
+}
+
+// overzoomLayer 将祖先瓦片像素坐标的图层裁剪并缩放到子孙瓦片
+// d 为级别差，dx、dy 为子孙瓦片在祖先瓦片内的列号和行号，buffer 为图层范围下的缓冲区
+func overzoomLayer(ctx context.Context, layer *Layer, extent float64, d, dx, dy uint32, buffer float64) (*Layer, error) {
+	scale := math.Exp2(float64(d))
+	offsetX := float64(dx) * extent / scale
+	offsetY := float64(dy) * extent / scale
+	region := [4]float64{-buffer, -buffer, extent + buffer, extent + buffer}
+
+	nl := &Layer{Name: layer.Name, Extent: layer.Extent}
+	for _, f := range layer.Features {
+		if f == nil || f.Geometry == nil {
+			continue
+		}
+		g, err := mapCoords(f.Geometry, func(pt []float64) []float64 {
+			return []float64{math.Round((pt[0] - offsetX) * scale), math.Round((pt[1] - offsetY) * scale)}
+		})
+		if err != nil {
+			return nil, err
+		}
+		g = clipGeometry(ctx, g, region)
+		if g == nil || countVertices(g) == 0 {
+			continue
+		}
+		nf := *f
+		nf.Geometry = g
+		nl.Features = append(nl.Features, &nf)
+	}
+	return nl, nil
+}
+
+// overzoomPlan 超出最大级别生成瓦片的级别范围
+type overzoomPlan struct {
+	// source 提供数据的级别，即生成级别中的最大级别
+	source uint32
+	// max 超级别生成到的级别
+	max uint32
+	// bounds 各超级别的瓦片范围 minx, miny, maxx, maxy
+	bounds map[uint32][4]uint32
+}
+
+// planOverzoom 计算超级别生成范围，未启用时返回nil
+func (m *Tiler) planOverzoom(zooms []int) *overzoomPlan {
+	if len(zooms) == 0 {
+		return nil
+	}
+	source := zooms[0]
+	for _, z := range zooms {
+		if z > source {
+			source = z
+		}
+	}
+	if m.config.OverzoomMaxZoom <= source {
+		return nil
+	}
+
+	p := &overzoomPlan{
+		source: uint32(source),
+		max:    uint32(m.config.OverzoomMaxZoom),
+		bounds: make(map[uint32][4]uint32),
+	}
+	for z := p.source + 1; z <= p.max; z++ {
+		minx, miny, maxx, maxy := m.TileBounds(z)
+		p.bounds[z] = [4]uint32{minx, miny, maxx, maxy}
+	}
+	return p
+}
+
+// contains 判断瓦片是否在生成范围内
+func (p *overzoomPlan) contains(task *tileTask) bool {
+	b, ok := p.bounds[task.z]
+	return ok && task.x >= b[0] && task.x <= b[2] && task.y >= b[1] && task.y <= b[3]
+}
+
+// count 返回源级别瓦片在生成范围内的子孙瓦片数
+func (p *overzoomPlan) count(task *tileTask) int64 {
+	if p == nil || task.z != p.source {
+		return 0
+	}
+	var n int64
+	for z := p.source + 1; z <= p.max; z++ {
+		d := z - p.source
+		b := p.bounds[z]
+		minx, maxx := max(task.x<<d, b[0]), min((task.x+1)<<d-1, b[2])
+		miny, maxy := max(task.y<<d, b[1]), min((task.y+1)<<d-1, b[3])
+		if minx <= maxx && miny <= maxy {
+			n += int64(maxx-minx+1) * int64(maxy-miny+1)
+		}
+	}
+	return n
+}
+
+// total 返回所有超级别的瓦片数
+func (p *overzoomPlan) total() int64 {
+	if p == nil {
+		return 0
+	}
+	var n int64
+	for _, b := range p.bounds {
+		n += int64(b[2]-b[0]+1) * int64(b[3]-b[1]+1)
+	}
+	return n
+}
+
+// overzoomDescendants 由源级别瓦片处理后的图层逐级生成超出最大级别的子孙瓦片
+func (m *Tiler) overzoomDescendants(task *tileTask, layers []*Layer) {
+	if m.overzoom == nil || task.z != m.overzoom.source {
+		return
+	}
+	m.overzoomChildren(task, layers)
+}
+
+// overzoomChildren 生成四个子瓦片并继续向下生成
+func (m *Tiler) overzoomChildren(parent *tileTask, layers []*Layer) {
+	for i := 0; i < 4; i++ {
+		if m.ctx.Err() != nil {
+			return
+		}
+
+		task := &tileTask{
+			z: parent.z + 1,
+			x: parent.x*2 + uint32(i%2),
+			y: parent.y*2 + uint32(i/2),
+		}
+		if !m.overzoom.contains(task) {
+			continue
+		}
+
+		m.advance(task)
+		timer := stageTimer{}
+		start := time.Now()
+		childLayers, err := m.overzoomChild(layers, uint32(i%2), uint32(i/2))
+		timer.since(StageOverzoom, start)
+		if err != nil {
+			m.report.addStages(timer)
+			m.reportError(newTileError(task, StageOverzoom, err))
+			continue
+		}
+
+		if task.z < m.overzoom.max {
+			m.overzoomChildren(task, childLayers)
+		}
+		m.finishTile(task, m.newTile(task), m.visibleLayers(task.z, childLayers), timer)
+		m.report.addStages(timer)
+	}
+}
+
+// overzoomChild 将图层裁剪缩放到下一级别的子瓦片
+func (m *Tiler) overzoomChild(layers []*Layer, dx, dy uint32) ([]*Layer, error) {
+	var result []*Layer
+	for _, layer := range layers {
+		settings, _ := m.layerSettings(layer.Name, m.overzoom.source)
+		nl, err := overzoomLayer(m.ctx, layer, float64(settings.extent), 1, dx, dy, float64(settings.buffer))
+		if err != nil {
+			return nil, fmt.Errorf("图层 %s: %w", layer.Name, err)
+		}
+		if len(nl.Features) > 0 {
+			result = append(result, nl)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package tile
+
+import (
+	"errors"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// TestOverzoomLayers 测试由祖先瓦片图层生成超级别瓦片
+func TestOverzoomLayers(t *testing.T) {
+	layers := []*Layer{{
+		Name:   "points",
+		Extent: 4096,
+		Features: []*geom.Feature{
+			{Geometry: basic.Point{1000, 3000}, Properties: map[string]interface{}{"id": 1}},
+			{Geometry: basic.Point{3000, 3000}, Properties: map[string]interface{}{"id": 2}},
+			{Geometry: basic.Point{2050, 3000}, Properties: map[string]interface{}{"id": 3}},
+		},
+	}}
+	ancestor := NewTile(14, 100, 200)
+
+	// 左下子瓦片，缓冲区为64像素(4096范围下)
+	tile := NewTileWithOptions(15, 200, 401, 64, 4096, DefaultEpislon)
+	result, err := OverzoomLayers(layers, ancestor, tile)
+	if err != nil {
+		t.Fatalf("OverzoomLayers() 错误 = %v", err)
+	}
+	if len(result) != 1 || result[0].Extent != 4096 {
+		t.Fatalf("OverzoomLayers() = %+v, want 1个图层", result)
+	}
+
+	expected := map[int][2]float64{
+		1: {2000, 1904},
+		3: {4100, 1904},
+	}
+	if len(result[0].Features) != len(expected) {
+		t.Fatalf("要素数 = %v, want %v", len(result[0].Features), len(expected))
+	}
+	for _, f := range result[0].Features {
+		id := f.Properties["id"].(int)
+		pt := f.Geometry.(geom.Point)
+		if want := expected[id]; pt.X() != want[0] || pt.Y() != want[1] {
+			t.Errorf("要素 %d 坐标 = (%v, %v), want %v", id, pt.X(), pt.Y(), want)
+		}
+	}
+
+	// 原图层不被修改
+	if pt := layers[0].Features[0].Geometry.(basic.Point); pt[0] != 1000 || pt[1] != 3000 {
+		t.Errorf("原图层要素被修改: %v", pt)
+	}
+
+	// 跨两级
+	result, err = OverzoomLayers(layers, ancestor, NewTileWithOptions(16, 400, 802, 0, 4096, DefaultEpislon))
+	if err != nil {
+		t.Fatalf("OverzoomLayers() 错误 = %v", err)
+	}
+	if len(result) != 1 || len(result[0].Features) != 1 {
+		t.Fatalf("OverzoomLayers() = %+v, want 1个要素", result)
+	}
+	if pt := result[0].Features[0].Geometry.(geom.Point); pt.X() != 4000 || pt.Y() != 3808 {
+		t.Errorf("跨两级坐标 = (%v, %v)", pt.X(), pt.Y())
+	}
+}
+
+// TestOverzoomLayers_NotDescendant 测试非子孙瓦片
+func TestOverzoomLayers_NotDescendant(t *testing.T) {
+	ancestor := NewTile(5, 3, 4)
+	for _, tile := range []*Tile{NewTile(5, 3, 4), NewTile(4, 1, 2), NewTile(6, 8, 8)} {
+		if _, err := OverzoomLayers(nil, ancestor, tile); !errors.Is(err, ErrNotDescendant) {
+			t.Errorf("OverzoomLayers(%v) 错误 = %v, want %v", tile.ToString(), err, ErrNotDescendant)
+		}
+	}
+}
+
+// TestTiler_Overzoom 测试超级别瓦片不查询Provider
+func TestTiler_Overzoom(t *testing.T) {
+	provider := &countingProvider{MockProvider: MockProvider{
+		layers: []*Layer{{Name: "test_layer", Features: []*geom.Feature{{Geometry: basic.Point{0, 0}}}}},
+		srid:   4326,
+	}}
+	exporter := &MockExporter{}
+	tiler := NewTiler(&Config{
+		Provider:        provider,
+		Exporter:        exporter,
+		MinZoom:         0,
+		MaxZoom:         1,
+		OverzoomMaxZoom: 3,
+		Bound:           &[4]float64{-10, -10, 10, 10},
+	})
+
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	for z := range provider.zooms {
+		if z > 1 {
+			t.Errorf("Provider在z=%d被查询", z)
+		}
+	}
+
+	report := tiler.Report()
+	for z := 2; z <= 3; z++ {
+		s := report.Zooms[z]
+		minx, miny, maxx, maxy := tiler.TileBounds(uint32(z))
+		want := int64((maxx - minx + 1) * (maxy - miny + 1))
+		if s == nil || s.Tiles+s.EmptyTiles != want {
+			t.Errorf("z=%d 统计 = %+v, want %d 个瓦片", z, s, want)
+		}
+	}
+}
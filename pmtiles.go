@@ -0,0 +1,566 @@
+package tile
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/flywave/go-mapbox/mvt"
+)
+
+const (
+	// pmtilesHeaderSize PMTiles v3文件头长度
+	pmtilesHeaderSize = 127
+	// pmtilesRootSize 文件头和根目录的最大长度，读取时一次请求即可获得
+	pmtilesRootSize = 16384
+
+	pmtilesCompressionNone = 1
+	pmtilesCompressionGzip = 2
+	pmtilesTileTypeMVT     = 1
+)
+
+// PMTilesOptions PMTiles导出选项
+type PMTilesOptions struct {
+	// Name 元数据中的名称，为空时使用文件名
+	Name string
+	// Description 元数据中的描述
+	Description string
+	// Attribution 元数据中的版权信息
+	Attribution string
+	// Bounds 经纬度范围[minLon, minLat, maxLon, maxLat]，为nil时由已写入的瓦片计算
+	Bounds *[4]float64
+	// Gzip 是否gzip压缩瓦片数据，目录和元数据总是gzip压缩
+	Gzip bool
+	// Proto 协议版本
+	Proto mvt.ProtoType
+}
+
+// DefaultPMTilesOptions 默认PMTiles选项
+var DefaultPMTilesOptions = PMTilesOptions{
+	Gzip:  true,
+	Proto: mvt.PROTO_MAPBOX,
+}
+
+// pmtilesContent 瓦片内容在临时文件中的位置
+type pmtilesContent struct {
+	offset uint64
+	length uint64
+}
+
+// pmtilesEntry PMTiles目录项，RunLength为0时指向叶目录
+type pmtilesEntry struct {
+	TileID    uint64
+	Offset    uint64
+	Length    uint64
+	RunLength uint64
+}
+
+// PMTilesExporter 将瓦片写入单个PMTiles v3归档的导出器，也可作为TileSink与其他编码器组合
+// 工作协程写入的瓦片内容去重后暂存在输出目录的临时文件中，
+// Finalize时按Hilbert瓦片ID排序写出目录、元数据和瓦片数据。
+// 归档只包含本次运行导出的瓦片，与Resume或Retile一起使用时Open返回错误
+type PMTilesExporter struct {
+	// Options 导出选项
+	Options PMTilesOptions
+
+	path    string
+	encoder *MVTExporter
+	layers  vectorLayerSet
+
+	// mu 保护以下字段
+	mu       sync.Mutex
+	meta     TilesetMetadata
+	tmp      *os.File
+	size     uint64
+	contents map[[md5.Size]byte]pmtilesContent
+	tiles    map[uint64]pmtilesContent
+	closed   bool
+}
+
+// NewPMTilesExporter 创建写入path的PMTiles导出器
+func NewPMTilesExporter(path string) (*PMTilesExporter, error) {
+	return NewPMTilesExporterWithOptions(path, DefaultPMTilesOptions)
+}
+
+// NewPMTilesExporterWithOptions 使用自定义选项创建PMTiles导出器
+func NewPMTilesExporterWithOptions(path string, options PMTilesOptions) (*PMTilesExporter, error) {
+	if path == "" {
+		return nil, ErrInvalidPath
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %w", err)
+	}
+
+	encoderOptions := DefaultMVTOptions
+	encoderOptions.Proto = options.Proto
+	return &PMTilesExporter{
+		Options:  options,
+		path:     path,
+		encoder:  NewMVTExporterWithOptions(encoderOptions),
+		tmp:      tmp,
+		contents: make(map[[md5.Size]byte]pmtilesContent),
+		tiles:    make(map[uint64]pmtilesContent),
+	}, nil
+}
+
+// Open 记录瓦片集元数据，元数据中的名称和格式在Options未设置名称时使用
+// 归档在Finalize时整体重写，增量生成(Resume或Retile)时返回ErrIncrementalArchive
+func (e *PMTilesExporter) Open(ctx context.Context, meta TilesetMetadata) error {
+	if meta.TileMatrixSet != nil && meta.TileMatrixSet != WebMercatorQuad {
+		return ErrNotWebMercator
+	}
+	if meta.Incremental {
+		return ErrIncrementalArchive
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return ErrExporterClosed
+	}
+	e.meta = meta
+	return nil
+}
+
+// SaveTile 编码瓦片并暂存，内容相同的瓦片只存储一次，path被忽略
+func (e *PMTilesExporter) SaveTile(res []*Layer, tile *Tile, path string) error {
+	_, err := e.SaveTileSize(res, tile, path)
+	return err
+}
+
+// SaveTileSize 保存瓦片并返回编码后的字节数
+func (e *PMTilesExporter) SaveTileSize(res []*Layer, tile *Tile, path string) (int64, error) {
+	if tile == nil {
+		return 0, ErrInvalidTile
+	}
+	if tile.MatrixSet() != WebMercatorQuad {
+		return 0, ErrNotWebMercator
+	}
+
+	data, err := e.encoder.GenerateMVT(res, tile)
+	if err != nil {
+		return 0, err
+	}
+	if err := e.WriteTile(context.Background(), tile.Z, tile.X, tile.Y, data); err != nil {
+		return 0, err
+	}
+	e.layers.add(tile.Z, res)
+	return int64(len(data)), nil
+}
+
+// WriteTile 暂存编码后的瓦片，Options.Gzip为true时先压缩
+func (e *PMTilesExporter) WriteTile(ctx context.Context, z, x, y uint32, data []byte) error {
+	if e.Options.Gzip {
+		var err error
+		if data, err = gzipData(data); err != nil {
+			return err
+		}
+	}
+	sum := md5.Sum(data)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return ErrExporterClosed
+	}
+	content, ok := e.contents[sum]
+	if !ok {
+		if _, err := e.tmp.Write(data); err != nil {
+			return fmt.Errorf("写入临时文件失败: %w", err)
+		}
+		content = pmtilesContent{offset: e.size, length: uint64(len(data))}
+		e.contents[sum] = content
+		e.size += content.length
+	}
+	e.tiles[pmtilesTileID(z, x, y)] = content
+	return nil
+}
+
+// RemoveTile 从归档中移除本次运行已导出的瓦片
+func (e *PMTilesExporter) RemoveTile(tile *Tile, path string) error {
+	if tile == nil {
+		return ErrInvalidTile
+	}
+	return e.DeleteTile(context.Background(), tile.Z, tile.X, tile.Y)
+}
+
+// DeleteTile 从归档中移除本次运行已写入的瓦片
+func (e *PMTilesExporter) DeleteTile(ctx context.Context, z, x, y uint32) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return ErrExporterClosed
+	}
+	delete(e.tiles, pmtilesTileID(z, x, y))
+	return nil
+}
+
+// UpdateMetadata 合并元数据中的vector_layers，在Finalize时写入
+func (e *PMTilesExporter) UpdateMetadata(meta TilesetMetadata) error {
+	e.layers.merge(meta.VectorLayers)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.meta = meta
+	return nil
+}
+
+// Extension 返回文件扩展名
+func (e *PMTilesExporter) Extension() string {
+	return "pmtiles"
+}
+
+// RelativeTilePath 瓦片不写入单独的文件，返回空字符串
+func (e *PMTilesExporter) RelativeTilePath(zoom, x, y int) string {
+	return ""
+}
+
+// Finalize 写出PMTiles归档并删除临时文件
+func (e *PMTilesExporter) Finalize() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return ErrExporterClosed
+	}
+	e.closed = true
+	defer e.removeTmp()
+
+	if err := e.writeArchive(); err != nil {
+		return fmt.Errorf("写入PMTiles文件失败: %w", err)
+	}
+	return nil
+}
+
+// Close 放弃未写出的瓦片并删除临时文件，Finalize之后调用时不做任何操作
+func (e *PMTilesExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.removeTmp()
+}
+
+// removeTmp 关闭并删除临时文件，调用者需持有锁
+func (e *PMTilesExporter) removeTmp() error {
+	e.tmp.Close()
+	if err := os.Remove(e.tmp.Name()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除临时文件失败: %w", err)
+	}
+	return nil
+}
+
+// writeArchive 按瓦片ID排序生成目录，依次写出文件头、根目录、元数据、叶目录和瓦片数据
+func (e *PMTilesExporter) writeArchive() error {
+	ids := make([]uint64, 0, len(e.tiles))
+	for id := range e.tiles {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// 瓦片数据按瓦片ID顺序排列，重复的内容引用第一次出现的位置
+	var entries []pmtilesEntry
+	var order []pmtilesContent
+	offsets := make(map[uint64]uint64)
+	var dataLength uint64
+	for _, id := range ids {
+		content := e.tiles[id]
+		offset, ok := offsets[content.offset]
+		if !ok {
+			offset = dataLength
+			offsets[content.offset] = offset
+			order = append(order, content)
+			dataLength += content.length
+		}
+		if n := len(entries); n > 0 && entries[n-1].Offset == offset &&
+			entries[n-1].TileID+entries[n-1].RunLength == id {
+			entries[n-1].RunLength++
+			continue
+		}
+		entries = append(entries, pmtilesEntry{TileID: id, Offset: offset, Length: content.length, RunLength: 1})
+	}
+
+	root, leaves, err := pmtilesDirectories(entries)
+	if err != nil {
+		return err
+	}
+	metadata, err := e.metadata()
+	if err != nil {
+		return err
+	}
+
+	h := e.header(ids)
+	h.rootOffset = pmtilesHeaderSize
+	h.rootLength = uint64(len(root))
+	h.metadataOffset = h.rootOffset + h.rootLength
+	h.metadataLength = uint64(len(metadata))
+	h.leavesOffset = h.metadataOffset + h.metadataLength
+	h.leavesLength = uint64(len(leaves))
+	h.dataOffset = h.leavesOffset + h.leavesLength
+	h.dataLength = dataLength
+	h.entries = uint64(len(entries))
+	h.contents = uint64(len(order))
+
+	// 先写入同目录下的临时文件再替换，写出失败时保留原有归档
+	f, err := os.CreateTemp(filepath.Dir(e.path), filepath.Base(e.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := e.writeArchiveTo(f, h, order, root, metadata, leaves); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), e.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// writeArchiveTo 将归档各部分写入f并关闭
+func (e *PMTilesExporter) writeArchiveTo(f *os.File, h pmtilesHeader, order []pmtilesContent, root, metadata, leaves []byte) error {
+	if err := f.Chmod(0644); err != nil {
+		return err
+	}
+	for _, b := range [][]byte{h.encode(), root, metadata, leaves} {
+		if _, err := f.Write(b); err != nil {
+			return err
+		}
+	}
+	for _, c := range order {
+		if _, err := io.Copy(f, io.NewSectionReader(e.tmp, int64(c.offset), int64(c.length))); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// metadata 生成gzip压缩的JSON元数据
+func (e *PMTilesExporter) metadata() ([]byte, error) {
+	name := e.Options.Name
+	if name == "" {
+		name = e.meta.Name
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(e.path), filepath.Ext(e.path))
+	}
+	meta := map[string]interface{}{
+		"name":          name,
+		"type":          "overlay",
+		"vector_layers": e.layers.list(),
+	}
+	if e.Options.Description != "" {
+		meta["description"] = e.Options.Description
+	}
+	if e.Options.Attribution != "" {
+		meta["attribution"] = e.Options.Attribution
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	return gzipData(data)
+}
+
+// pmtilesHeader PMTiles v3文件头
+type pmtilesHeader struct {
+	rootOffset, rootLength         uint64
+	metadataOffset, metadataLength uint64
+	leavesOffset, leavesLength     uint64
+	dataOffset, dataLength         uint64
+	addressed, entries, contents   uint64
+	tileCompression, tileType      uint8
+	minZoom, maxZoom               uint8
+	bounds                         [4]float64
+	centerZoom                     uint8
+	center                         [2]float64
+}
+
+// header 由瓦片ID计算级别、范围和中心，ids按升序排列
+func (e *PMTilesExporter) header(ids []uint64) pmtilesHeader {
+	h := pmtilesHeader{addressed: uint64(len(ids)), tileCompression: pmtilesCompressionNone}
+	// 其他格式的瓦片类型为未知
+	if e.meta.Format == "" || e.meta.Format == "mvt" {
+		h.tileType = pmtilesTileTypeMVT
+	}
+	if e.Options.Gzip {
+		h.tileCompression = pmtilesCompressionGzip
+	}
+	if len(ids) == 0 {
+		if e.Options.Bounds != nil {
+			h.bounds = *e.Options.Bounds
+		}
+		return h
+	}
+
+	// 瓦片ID按级别递增，首尾两个ID即为最小和最大级别
+	minZoom, _, _ := pmtilesTileZXY(ids[0])
+	maxZoom, _, _ := pmtilesTileZXY(ids[len(ids)-1])
+	h.minZoom, h.maxZoom = uint8(minZoom), uint8(maxZoom)
+	if e.Options.Bounds != nil {
+		h.bounds = *e.Options.Bounds
+	} else {
+		h.bounds = [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+		for _, id := range ids {
+			if z, x, y := pmtilesTileZXY(id); z == maxZoom {
+				b := NewTile(z, x, y).Bounds()
+				h.bounds = [4]float64{min(h.bounds[0], b[0]), min(h.bounds[1], b[1]), max(h.bounds[2], b[2]), max(h.bounds[3], b[3])}
+			}
+		}
+	}
+	h.centerZoom = h.minZoom
+	h.center = [2]float64{(h.bounds[0] + h.bounds[2]) / 2, (h.bounds[1] + h.bounds[3]) / 2}
+	return h
+}
+
+// encode 编码为127字节的文件头，经纬度以1e7倍的整数存储
+func (h pmtilesHeader) encode() []byte {
+	b := make([]byte, pmtilesHeaderSize)
+	copy(b, "PMTiles")
+	b[7] = 3
+	for i, v := range []uint64{
+		h.rootOffset, h.rootLength, h.metadataOffset, h.metadataLength,
+		h.leavesOffset, h.leavesLength, h.dataOffset, h.dataLength,
+		h.addressed, h.entries, h.contents,
+	} {
+		binary.LittleEndian.PutUint64(b[8+i*8:], v)
+	}
+	b[96] = 1 // 瓦片数据按ID排列
+	b[97] = pmtilesCompressionGzip
+	b[98] = h.tileCompression
+	b[99] = h.tileType
+	b[100] = h.minZoom
+	b[101] = h.maxZoom
+	for i, v := range h.bounds {
+		binary.LittleEndian.PutUint32(b[102+i*4:], uint32(int32(math.Round(v*1e7))))
+	}
+	b[118] = h.centerZoom
+	for i, v := range h.center {
+		binary.LittleEndian.PutUint32(b[119+i*4:], uint32(int32(math.Round(v*1e7))))
+	}
+	return b
+}
+
+// pmtilesDirectories 生成根目录和叶目录
+// 全部目录项可以放入根目录时不使用叶目录，否则按叶目录大小分组，根目录过大时增大叶目录
+func pmtilesDirectories(entries []pmtilesEntry) (root, leaves []byte, err error) {
+	if root, err = pmtilesDirectory(entries); err != nil {
+		return nil, nil, err
+	}
+	if len(root) <= pmtilesRootSize-pmtilesHeaderSize {
+		return root, nil, nil
+	}
+
+	for leafSize := 4096; ; leafSize += leafSize / 5 {
+		var rootEntries []pmtilesEntry
+		var buf bytes.Buffer
+		for i := 0; i < len(entries); i += leafSize {
+			leaf, err := pmtilesDirectory(entries[i:min(i+leafSize, len(entries))])
+			if err != nil {
+				return nil, nil, err
+			}
+			rootEntries = append(rootEntries, pmtilesEntry{
+				TileID: entries[i].TileID, Offset: uint64(buf.Len()), Length: uint64(len(leaf)),
+			})
+			buf.Write(leaf)
+		}
+		if root, err = pmtilesDirectory(rootEntries); err != nil {
+			return nil, nil, err
+		}
+		if len(root) <= pmtilesRootSize-pmtilesHeaderSize {
+			return root, buf.Bytes(), nil
+		}
+	}
+}
+
+// pmtilesDirectory 编码并gzip压缩目录
+// 依次写入项数、瓦片ID增量、重复次数、长度和偏移，偏移紧接上一项时写0，否则写偏移加1
+func pmtilesDirectory(entries []pmtilesEntry) ([]byte, error) {
+	var b []byte
+	b = binary.AppendUvarint(b, uint64(len(entries)))
+	var last uint64
+	for _, e := range entries {
+		b = binary.AppendUvarint(b, e.TileID-last)
+		last = e.TileID
+	}
+	for _, e := range entries {
+		b = binary.AppendUvarint(b, e.RunLength)
+	}
+	for _, e := range entries {
+		b = binary.AppendUvarint(b, e.Length)
+	}
+	for i, e := range entries {
+		if i > 0 && e.Offset == entries[i-1].Offset+entries[i-1].Length {
+			b = binary.AppendUvarint(b, 0)
+		} else {
+			b = binary.AppendUvarint(b, e.Offset+1)
+		}
+	}
+	return gzipData(b)
+}
+
+// pmtilesTileID 返回瓦片的PMTiles ID
+// 低级别的瓦片全部排在前面，同一级别内按Hilbert曲线编号
+func pmtilesTileID(z, x, y uint32) uint64 {
+	id := (uint64(1)<<(2*z) - 1) / 3
+	for s := uint32(1) << z >> 1; s > 0; s >>= 1 {
+		var rx, ry uint32
+		if x&s != 0 {
+			rx = 1
+		}
+		if y&s != 0 {
+			ry = 1
+		}
+		id += uint64(s) * uint64(s) * uint64((3*rx)^ry)
+		x, y = hilbertRotate(s, x, y, rx, ry)
+	}
+	return id
+}
+
+// pmtilesTileZXY 由PMTiles ID计算瓦片级别和行列号
+func pmtilesTileZXY(id uint64) (z, x, y uint32) {
+	var base uint64
+	for z = 0; ; z++ {
+		n := uint64(1) << (2 * z)
+		if id < base+n {
+			break
+		}
+		base += n
+	}
+	d := id - base
+	for s := uint32(1); s < uint32(1)<<z; s <<= 1 {
+		rx := uint32(d/2) & 1
+		ry := uint32(d^uint64(rx)) & 1
+		x, y = hilbertRotate(s, x, y, rx, ry)
+		x += s * rx
+		y += s * ry
+		d /= 4
+	}
+	return z, x, y
+}
+
+// hilbertRotate 按象限旋转坐标，n为当前象限的边长
+func hilbertRotate(n, x, y, rx, ry uint32) (uint32, uint32) {
+	if ry == 0 {
+		if rx == 1 {
+			x = n - 1 - x
+			y = n - 1 - y
+		}
+		x, y = y, x
+	}
+	return x, y
+}
@@ -0,0 +1,329 @@
+package tile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// gunzip 解压gzip数据
+func gunzip(t *testing.T, data []byte) []byte {
+	t.Helper()
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip解压失败: %v", err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("gzip解压失败: %v", err)
+	}
+	return b
+}
+
+// decodePMTilesDirectory 解码gzip压缩的目录
+func decodePMTilesDirectory(t *testing.T, data []byte) []pmtilesEntry {
+	t.Helper()
+	r := bytes.NewReader(gunzip(t, data))
+	read := func() uint64 {
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			t.Fatalf("目录解码失败: %v", err)
+		}
+		return v
+	}
+	entries := make([]pmtilesEntry, read())
+	var last uint64
+	for i := range entries {
+		last += read()
+		entries[i].TileID = last
+	}
+	for i := range entries {
+		entries[i].RunLength = read()
+	}
+	for i := range entries {
+		entries[i].Length = read()
+	}
+	for i := range entries {
+		if v := read(); v == 0 && i > 0 {
+			entries[i].Offset = entries[i-1].Offset + entries[i-1].Length
+		} else {
+			entries[i].Offset = v - 1
+		}
+	}
+	return entries
+}
+
+// readPMTiles 读取PMTiles文件中的全部瓦片，返回文件头、按瓦片ID索引的瓦片数据和元数据
+func readPMTiles(t *testing.T, path string) ([]byte, map[uint64][]byte, map[string]interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < pmtilesHeaderSize || string(data[:7]) != "PMTiles" || data[7] != 3 {
+		t.Fatalf("文件头无效: %v", data[:min(len(data), 8)])
+	}
+	h := data[:pmtilesHeaderSize]
+	field := func(i int) uint64 { return binary.LittleEndian.Uint64(h[8+i*8:]) }
+	section := func(i int) []byte { return data[field(i) : field(i)+field(i+1)] }
+	leaves, tileData := section(4), section(6)
+
+	tiles := map[uint64][]byte{}
+	var walk func(dir []byte)
+	walk = func(dir []byte) {
+		for _, e := range decodePMTilesDirectory(t, dir) {
+			if e.RunLength == 0 {
+				walk(leaves[e.Offset : e.Offset+e.Length])
+				continue
+			}
+			for i := uint64(0); i < e.RunLength; i++ {
+				tiles[e.TileID+i] = tileData[e.Offset : e.Offset+e.Length]
+			}
+		}
+	}
+	walk(section(0))
+
+	var meta map[string]interface{}
+	if err := json.Unmarshal(gunzip(t, section(2)), &meta); err != nil {
+		t.Fatalf("元数据解析失败: %v", err)
+	}
+	return h, tiles, meta
+}
+
+// TestPMTilesTileID 测试瓦片ID与行列号的转换
+func TestPMTilesTileID(t *testing.T) {
+	tests := []struct {
+		z, x, y uint32
+		id      uint64
+	}{
+		{0, 0, 0, 0},
+		{1, 0, 0, 1},
+		{1, 0, 1, 2},
+		{1, 1, 1, 3},
+		{1, 1, 0, 4},
+		{2, 0, 0, 5},
+		{12, 3423, 1763, 19078479},
+	}
+	for _, tt := range tests {
+		if got := pmtilesTileID(tt.z, tt.x, tt.y); got != tt.id {
+			t.Errorf("pmtilesTileID(%d, %d, %d) = %d, want %d", tt.z, tt.x, tt.y, got, tt.id)
+		}
+	}
+	for z := uint32(0); z < 5; z++ {
+		for x := uint32(0); x < 1<<z; x++ {
+			for y := uint32(0); y < 1<<z; y++ {
+				if gz, gx, gy := pmtilesTileZXY(pmtilesTileID(z, x, y)); gz != z || gx != x || gy != y {
+					t.Fatalf("pmtilesTileZXY(pmtilesTileID(%d, %d, %d)) = %d, %d, %d", z, x, y, gz, gx, gy)
+				}
+			}
+		}
+	}
+}
+
+// TestPMTilesDirectories 测试目录项过多时使用叶目录
+func TestPMTilesDirectories(t *testing.T) {
+	var entries []pmtilesEntry
+	for i := uint64(0); i < 20000; i++ {
+		// 间隔的偏移不能省略，使根目录超过限制
+		entries = append(entries, pmtilesEntry{TileID: i * 3, Offset: i * 1000, Length: 10 + i%7, RunLength: 1 + i%2})
+	}
+	root, leaves, err := pmtilesDirectories(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaves) == 0 || len(root) > pmtilesRootSize-pmtilesHeaderSize {
+		t.Fatalf("根目录 %d 字节, 叶目录 %d 字节", len(root), len(leaves))
+	}
+	var got []pmtilesEntry
+	for _, e := range decodePMTilesDirectory(t, root) {
+		if e.RunLength != 0 {
+			t.Fatalf("根目录项 %+v 应指向叶目录", e)
+		}
+		got = append(got, decodePMTilesDirectory(t, leaves[e.Offset:e.Offset+e.Length])...)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("目录项数 = %d, want %d", len(got), len(entries))
+	}
+	for i := range entries {
+		if got[i] != entries[i] {
+			t.Fatalf("目录项 %d = %+v, want %+v", i, got[i], entries[i])
+		}
+	}
+}
+
+// TestPMTilesExporter 测试并发写入、去重、游程编码和文件头
+func TestPMTilesExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roads.pmtiles")
+	exporter, err := NewPMTilesExporterWithOptions(path, PMTilesOptions{Gzip: false, Attribution: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2级的4个瓦片内容相同，ID连续，合并为一个目录项
+	var wg sync.WaitGroup
+	for x := uint32(0); x < 2; x++ {
+		for y := uint32(0); y < 2; y++ {
+			wg.Add(1)
+			go func(x, y uint32) {
+				defer wg.Done()
+				if err := exporter.SaveTile(mbtilesLayers("roads", map[string]interface{}{"name": "a"}), NewTile(2, x, y), ""); err != nil {
+					t.Errorf("SaveTile() 错误 = %v", err)
+				}
+			}(x, y)
+		}
+	}
+	wg.Wait()
+	pois := mbtilesLayers("pois", map[string]interface{}{"rank": 1})
+	exporter.SaveTile(pois, NewTile(1, 0, 0), "")
+	exporter.SaveTile(pois, NewTile(1, 1, 1), "")
+	exporter.RemoveTile(NewTile(1, 1, 1), "")
+	if err := exporter.SaveTile(nil, NewTileInMatrixSet(WorldCRS84Quad, 0, 0, 0, 0, 4096, 0), ""); !errors.Is(err, ErrNotWebMercator) {
+		t.Errorf("SaveTile() 错误 = %v, want %v", err, ErrNotWebMercator)
+	}
+	if err := exporter.Finalize(); err != nil {
+		t.Fatalf("Finalize() 错误 = %v", err)
+	}
+	if err := exporter.SaveTile(pois, NewTile(1, 0, 0), ""); !errors.Is(err, ErrExporterClosed) {
+		t.Errorf("Finalize后SaveTile() 错误 = %v, want %v", err, ErrExporterClosed)
+	}
+	if matches, _ := filepath.Glob(path + ".*.tmp"); len(matches) != 0 {
+		t.Errorf("临时文件未删除: %v", matches)
+	}
+
+	h, tiles, meta := readPMTiles(t, path)
+	field := func(i int) uint64 { return binary.LittleEndian.Uint64(h[8+i*8:]) }
+	if addressed, entries, contents := field(8), field(9), field(10); addressed != 5 || entries != 2 || contents != 2 {
+		t.Errorf("瓦片数 = %d, 目录项数 = %d, 内容数 = %d, want 5, 2, 2", addressed, entries, contents)
+	}
+	if h[96] != 1 || h[97] != pmtilesCompressionGzip || h[98] != pmtilesCompressionNone || h[99] != pmtilesTileTypeMVT || h[100] != 1 || h[101] != 2 {
+		t.Errorf("文件头 = %v", h[96:102])
+	}
+	bound := func(i int) int32 { return int32(binary.LittleEndian.Uint32(h[102+i*4:])) }
+	if bound(0) != -1800000000 || bound(1) != 0 || bound(2) != 0 || bound(3) != 850511288 {
+		t.Errorf("bounds = %d %d %d %d", bound(0), bound(1), bound(2), bound(3))
+	}
+
+	want, _ := NewMVTExporter().GenerateMVT(pois, NewTile(1, 0, 0))
+	if len(tiles) != 5 || !bytes.Equal(tiles[pmtilesTileID(1, 0, 0)], want) {
+		t.Errorf("瓦片 = %v, want 1/0/0 = %v", tiles, want)
+	}
+	if !bytes.Equal(tiles[pmtilesTileID(2, 0, 0)], tiles[pmtilesTileID(2, 1, 1)]) {
+		t.Error("相同内容的瓦片应引用同一数据")
+	}
+	layers, _ := meta["vector_layers"].([]interface{})
+	if meta["name"] != "roads" || meta["attribution"] != "test" || len(layers) != 2 {
+		t.Errorf("元数据 = %v", meta)
+	}
+}
+
+// TestTiler_PMTilesExporter 测试Tiler完成后写出归档，停止时不写出
+func TestTiler_PMTilesExporter(t *testing.T) {
+	provider, err := NewMemoryProvider(4326, []*Layer{{Name: "cities", Features: []*geom.Feature{
+		{Geometry: basic.Point{116.4, 39.9}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cities.pmtiles")
+	exporter, err := NewPMTilesExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewTiler(&Config{Provider: provider, Exporter: exporter, OutputDir: dir, MaxZoom: 3}).Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+	h, tiles, _ := readPMTiles(t, path)
+	if len(tiles) != 4 || h[98] != pmtilesCompressionGzip || h[100] != 0 || h[101] != 3 {
+		t.Errorf("瓦片数 = %d, 文件头 = %v", len(tiles), h[96:102])
+	}
+	for id, data := range tiles {
+		z, x, y := pmtilesTileZXY(id)
+		if want := NewTileLatLong(z, 39.9, 116.4); x != want.X || y != want.Y {
+			t.Errorf("瓦片 %d/%d/%d, want %s", z, x, y, want.ToString())
+		}
+		gunzip(t, data)
+	}
+
+	stopped := filepath.Join(dir, "stopped.pmtiles")
+	exporter, err = NewPMTilesExporter(stopped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tiler := NewTiler(&Config{Provider: provider, Exporter: exporter, OutputDir: dir, MaxZoom: 3})
+	tiler.Stop()
+	if err := tiler.Tiler(); err == nil {
+		t.Error("停止后Tiler()应返回错误")
+	}
+	if err := exporter.Close(); err != nil {
+		t.Errorf("Close() 错误 = %v", err)
+	}
+	if _, err := os.Stat(stopped); !os.IsNotExist(err) {
+		t.Errorf("停止后不应写出归档: %v", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(matches) != 0 {
+		t.Errorf("临时文件未删除: %v", matches)
+	}
+
+	// 增量生成时拒绝写出，已有归档不变
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter, err = NewPMTilesExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewTiler(&Config{Provider: provider, Exporter: exporter, OutputDir: dir, MaxZoom: 3, Resume: true}).Tiler(); !errors.Is(err, ErrIncrementalArchive) {
+		t.Errorf("Resume时Tiler() 错误 = %v, want %v", err, ErrIncrementalArchive)
+	}
+	exporter.Close()
+	exporter, err = NewPMTilesExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewTiler(&Config{Provider: provider, Exporter: exporter, OutputDir: dir, MaxZoom: 3}).Retile([]*[4]float64{{116, 39, 117, 40}}); !errors.Is(err, ErrIncrementalArchive) {
+		t.Errorf("Retile() 错误 = %v, want %v", err, ErrIncrementalArchive)
+	}
+	exporter.Close()
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+		t.Error("增量生成不应修改已有归档")
+	}
+}
+
+// TestPMTilesExporter_FinalizeError 测试写出失败时保留原有归档
+func TestPMTilesExporter_FinalizeError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roads.pmtiles")
+	if err := os.WriteFile(path, []byte("old archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exporter, err := NewPMTilesExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.SaveTile(mbtilesLayers("roads", nil), NewTile(1, 0, 0), ""); err != nil {
+		t.Fatalf("SaveTile() 错误 = %v", err)
+	}
+	// 暂存文件不可读时复制瓦片数据失败
+	exporter.tmp.Close()
+	if err := exporter.Finalize(); err == nil {
+		t.Fatal("Finalize() 应返回错误")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "old archive" {
+		t.Errorf("原有归档 = %q, %v", data, err)
+	}
+	if matches, _ := filepath.Glob(path + ".*.tmp"); len(matches) != 0 {
+		t.Errorf("临时文件未删除: %v", matches)
+	}
+}
@@ -149,56 +149,43 @@ func preparept(g geom.Point, tile *gen.Extent, pixelExtent float64) geom.Point {
 func preparelinestr(g geom.LineString, tile *gen.Extent, pixelExtent float64) geom.LineString {
 	// 参数验证
 	if g == nil || tile == nil || pixelExtent <= 0 {
-		fmt.Printf("preparelinestr: 参数无效: g=%v, tile=%v, pixelExtent=%v\n", g, tile, pixelExtent)
 		return nil
 	}
 
 	// 检查线是否有足够的点
 	if len(g.Data()) < 2 {
-		fmt.Printf("preparelinestr: 线点数不足: %d\n", len(g.Data()))
 		return nil
 	}
 
 	// 裁剪线
 	clippedLines, err := clip.LineString(g, tile)
 	if err != nil {
-		fmt.Printf("preparelinestr: 裁剪线错误: %v\n", err)
 		return nil
 	}
 	if len(clippedLines) == 0 {
-		fmt.Printf("preparelinestr: 没有裁剪结果\n")
 		return nil
 	}
 
-	// 打印裁剪后的线数量
-	fmt.Printf("preparelinestr: 裁剪后得到 %d 条线\n", len(clippedLines))
-
 	// 转换裁剪后的线到像素坐标
 	clippedLine := clippedLines[0]
-	fmt.Printf("preparelinestr: 第一条裁剪线有 %d 个点\n", len(clippedLine.Data()))
 
-	points := make([][]float64, len(clippedLine.Data()))
+	points := make([][]float64, 0, len(clippedLine.Data()))
 
-	for i, pt := range clippedLine.Data() {
-		fmt.Printf("preparelinestr: 处理裁剪线的点 %d: %v\n", i, pt)
+	for _, pt := range clippedLine.Data() {
 		ptGeom := gen.NewPoint([]float64{pt[0], pt[1]})
 		preparedPt := preparept(ptGeom, tile, pixelExtent)
 		if preparedPt == nil {
-			fmt.Printf("preparelinestr: 点 %d 转换后为nil\n", i)
+			// 裁剪在容差内保留的顶点可能在瓦片范围外，跳过
 			continue
 		}
-		points[i] = preparedPt.Data()
-		fmt.Printf("preparelinestr: 点 %d 转换后: %v\n", i, points[i])
+		points = append(points, preparedPt.Data())
 	}
 
 	if len(points) < 2 {
-		fmt.Printf("preparelinestr: 转换后点数不足: %d\n", len(points))
 		return nil
 	}
 
-	result := gen.NewLineString(points)
-	fmt.Printf("preparelinestr: 最终线: %v\n", result)
-	return result
+	return gen.NewLineString(points)
 }
 
 // preparePolygon 将多边形转换为瓦片像素坐标并确保符合MVT规范
@@ -196,6 +196,19 @@ func TestPrepareGeo(t *testing.T) {
 	}
 }
 
+// TestPreparelinestr_PointOutsideTile 测试裁剪容差内保留的瓦片外顶点被跳过，结果中不含空点
+func TestPreparelinestr_PointOutsideTile(t *testing.T) {
+	tile := &gen.Extent{116.0, 39.0, 117.0, 40.0}
+	// 第一个顶点在裁剪容差内，裁剪后保留，但转换时在瓦片范围外
+	line := gen.NewLineString([][]float64{{116.5, 40.000001}, {116.5, 39.5}, {116.75, 39.5}})
+
+	got := preparelinestr(line, tile, 4096)
+	want := gen.NewLineString([][]float64{{2048.0, 2048.0, 0.0}, {3072.0, 2048.0, 0.0}})
+	if got == nil || !isLineStringEqual(got, want) {
+		t.Errorf("preparelinestr() = %v, want %v", got, want)
+	}
+}
+
 // isGeometryEqual 比较两个几何对象是否相等
 func isGeometryEqual(g1, g2 geom.Geometry) bool {
 	// 处理nil情况
@@ -1,6 +1,72 @@
 package tile
 
+import (
+	"context"
+
+	geom "github.com/flywave/go-geom"
+)
+
 type Provider interface {
 	GetDataByTile(*Tile) []*Layer
 	GetSrid() uint64
 }
+
+// StreamingProvider 以回调方式逐个提供瓦片内的要素
+// 相比Provider支持取消和返回错误，且不需要一次加载瓦片的全部要素。
+// 只实现StreamingProvider的数据源设置到Config.StreamingProvider；
+// Config.Provider同时实现StreamingProvider时，Tiler也优先使用Features读取数据
+type StreamingProvider interface {
+	// Features 依次以图层名和要素调用fn。fn返回错误时应停止读取并返回该错误，
+	// ctx取消时应尽快返回ctx.Err()
+	Features(ctx context.Context, tile *Tile, fn func(layer string, f *geom.Feature) error) error
+	GetSrid() uint64
+}
+
+// streamingAdapter 将Provider包装为StreamingProvider
+type streamingAdapter struct {
+	Provider
+}
+
+// NewStreamingAdapter 将Provider包装为StreamingProvider
+func NewStreamingAdapter(p Provider) StreamingProvider {
+	return streamingAdapter{Provider: p}
+}
+
+// Features 依次回调GetDataByTile返回的要素
+func (a streamingAdapter) Features(ctx context.Context, tile *Tile, fn func(layer string, f *geom.Feature) error) error {
+	for _, layer := range a.GetDataByTile(tile) {
+		if layer == nil {
+			continue
+		}
+		for _, f := range layer.Features {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(layer.Name, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CollectLayers 读取StreamingProvider在瓦片内的全部要素并按图层名汇总，
+// 可用于实现Provider.GetDataByTile
+func CollectLayers(ctx context.Context, p StreamingProvider, tile *Tile) ([]*Layer, error) {
+	var layers []*Layer
+	index := make(map[string]*Layer)
+	err := p.Features(ctx, tile, func(name string, f *geom.Feature) error {
+		layer, ok := index[name]
+		if !ok {
+			layer = &Layer{Name: name}
+			index[name] = layer
+			layers = append(layers, layer)
+		}
+		layer.Features = append(layer.Features, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return layers, nil
+}
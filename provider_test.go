@@ -0,0 +1,170 @@
+package tile
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// streamFeature 流式Provider回调的一个要素
+type streamFeature struct {
+	layer   string
+	feature *geom.Feature
+}
+
+// mockStreamingProvider 模拟流式Provider，err不为空时在回调全部要素后返回该错误
+type mockStreamingProvider struct {
+	MockProvider
+	features []streamFeature
+	err      error
+}
+
+func (p *mockStreamingProvider) Features(ctx context.Context, t *Tile, fn func(string, *geom.Feature) error) error {
+	for _, sf := range p.features {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(sf.layer, sf.feature); err != nil {
+			return err
+		}
+	}
+	return p.err
+}
+
+// TestTiler_StreamingProvider 测试优先使用Features读取并按图层汇总
+func TestTiler_StreamingProvider(t *testing.T) {
+	point := func(name string) *geom.Feature {
+		return &geom.Feature{Geometry: basic.Point{0, 0}, Properties: map[string]interface{}{"name": name}}
+	}
+	provider := &mockStreamingProvider{
+		// GetDataByTile 返回的数据不应被使用
+		MockProvider: MockProvider{layers: []*Layer{{Name: "unused", Features: []*geom.Feature{point("x")}}}, srid: 4326},
+		features: []streamFeature{
+			{"roads", point("r1")},
+			{"pois", point("p1")},
+			{"roads", point("r2")},
+		},
+	}
+	exporter := &MockExporter{}
+	tiler := NewTiler(&Config{Provider: provider, Exporter: exporter})
+	defer tiler.Stop()
+
+	tiler.processTile(&tileTask{z: 3, x: 0, y: 0})
+
+	saved := exporter.GetSavedTiles()
+	if len(saved) != 1 || len(saved[0].Layers) != 2 {
+		t.Fatalf("导出瓦片 = %+v, want 1个瓦片2个图层", saved)
+	}
+	layers := saved[0].Layers
+	if layers[0].Name != "roads" || layers[1].Name != "pois" {
+		t.Errorf("图层顺序 = %v, %v, want roads, pois", layers[0].Name, layers[1].Name)
+	}
+	if names := featureNames(layers); len(names) != 3 || names[0] != "r1" || names[1] != "r2" || names[2] != "p1" {
+		t.Errorf("要素 = %v, want [r1 r2 p1]", names)
+	}
+}
+
+// TestTiler_ConfigStreamingProvider 测试只实现StreamingProvider的数据源
+func TestTiler_ConfigStreamingProvider(t *testing.T) {
+	// 嵌入接口后只保留Features和GetSrid
+	provider := struct{ StreamingProvider }{&mockStreamingProvider{
+		MockProvider: MockProvider{srid: 4326},
+		features:     []streamFeature{{"roads", &geom.Feature{Geometry: basic.Point{0, 0}}}},
+	}}
+	exporter := &MockExporter{}
+	tiler := NewTiler(&Config{
+		// Provider 的数据不应被使用
+		Provider:          &MockProvider{layers: []*Layer{{Name: "unused", Features: []*geom.Feature{{Geometry: basic.Point{0, 0}}}}}, srid: 4326},
+		StreamingProvider: provider,
+		Exporter:          exporter,
+	})
+	defer tiler.Stop()
+
+	tiler.processTile(&tileTask{z: 3, x: 0, y: 0})
+	saved := exporter.GetSavedTiles()
+	if len(saved) != 1 || len(saved[0].Layers) != 1 || saved[0].Layers[0].Name != "roads" {
+		t.Fatalf("导出瓦片 = %+v, want 1个瓦片的roads图层", saved)
+	}
+
+	if _, err := NewTileServer(&Config{StreamingProvider: provider}); err != nil {
+		t.Errorf("NewTileServer() 错误 = %v", err)
+	}
+}
+
+// TestTiler_StreamingProviderError 测试Provider错误按错误策略返回
+func TestTiler_StreamingProviderError(t *testing.T) {
+	errRead := errors.New("read failed")
+	newTiler := func(policy ErrorPolicy) *Tiler {
+		return NewTiler(&Config{
+			Provider: &mockStreamingProvider{
+				MockProvider: MockProvider{srid: 4326},
+				features:     []streamFeature{{"test_layer", &geom.Feature{Geometry: basic.Point{0, 0}}}},
+				err:          errRead,
+			},
+			Exporter:    &MockExporter{},
+			MaxZoom:     1,
+			ErrorPolicy: policy,
+		})
+	}
+
+	err := newTiler(ErrorPolicySkipTile).Tiler()
+	var report *ErrorReport
+	if !errors.As(err, &report) {
+		t.Fatalf("Tiler() 错误 = %v, want *ErrorReport", err)
+	}
+	if len(report.Errors) != 5 {
+		t.Errorf("错误数 = %v, want 5", len(report.Errors))
+	}
+	for _, e := range report.Errors {
+		if e.Stage != StageProvider || !errors.Is(e, errRead) {
+			t.Errorf("瓦片错误 = %v, want 读取阶段的 %v", e, errRead)
+		}
+	}
+
+	if err := newTiler(ErrorPolicyFailFast).Tiler(); !errors.Is(err, errRead) {
+		t.Errorf("Tiler() 错误 = %v, want %v", err, errRead)
+	}
+}
+
+// TestTiler_StreamingProviderCancel 测试停止后Provider读取被取消
+func TestTiler_StreamingProviderCancel(t *testing.T) {
+	tiler := NewTiler(&Config{
+		Provider: &mockStreamingProvider{MockProvider: MockProvider{srid: 4326}},
+		Exporter: &MockExporter{},
+		MaxZoom:  3,
+	})
+	tiler.Stop()
+
+	if err := tiler.Tiler(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Tiler() 错误 = %v, want %v", err, context.Canceled)
+	}
+	if report := tiler.errorReport(); report != nil {
+		t.Errorf("取消不应记录瓦片错误: %v", report)
+	}
+}
+
+// TestCollectLayers 测试由流式Provider汇总图层
+func TestCollectLayers(t *testing.T) {
+	provider := NewStreamingAdapter(&MockProvider{layers: []*Layer{
+		{Name: "a", Features: []*geom.Feature{{}, {}}},
+		nil,
+		{Name: "b", Features: []*geom.Feature{{}}},
+	}})
+
+	layers, err := CollectLayers(context.Background(), provider, NewTile(0, 0, 0))
+	if err != nil {
+		t.Fatalf("CollectLayers() 错误 = %v", err)
+	}
+	if len(layers) != 2 || len(layers[0].Features) != 2 || len(layers[1].Features) != 1 {
+		t.Errorf("CollectLayers() = %+v", layers)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CollectLayers(ctx, provider, NewTile(0, 0, 0)); !errors.Is(err, context.Canceled) {
+		t.Errorf("CollectLayers() 错误 = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,510 @@
+package tile
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/flywave/go-geom"
+	gen "github.com/flywave/go-geom/general"
+
+	"github.com/flywave/go-vector-tiler/maths/simplify"
+	"github.com/flywave/go-vector-tiler/maths/validate"
+)
+
+// pyramid 自底向上生成的状态
+// split级别及以下的瓦片由工作协程按子树深度优先生成，
+// split以上的级别在下一级全部完成后逐级合并
+type pyramid struct {
+	top    uint32
+	bottom uint32
+	split  uint32
+	// export 需要导出的级别
+	export map[uint32]bool
+	// bounds 各级别的瓦片范围 minx, miny, maxx, maxy
+	bounds map[uint32][4]uint32
+
+	// results 等待合并到父瓦片的已处理图层
+	results map[tileTask][]*Layer
+	// aborted 因错误被放弃、不能合并到父瓦片的瓦片
+	aborted   map[tileTask]bool
+	resultsMu sync.Mutex
+
+	// remaining 当前级别未完成的任务数，归零时通知levelDone
+	remaining int64
+	levelDone chan struct{}
+}
+
+// tilePyramid 自底向上生成瓦片，只在最大级别查询Provider
+func (m *Tiler) tilePyramid(zooms []int, totalTasks int64) error {
+	if m.config.Resume {
+		m.cancel()
+		close(m.errChan)
+		return fmt.Errorf("瓦片生成失败: %w", ErrBottomUpResume)
+	}
+	if len(zooms) == 0 {
+		return m.run(0, func() { close(m.taskQueue) }, m.processTile)
+	}
+
+	p := m.newPyramid(zooms)
+	return m.run(totalTasks,
+		func() { m.generatePyramidTasks(p) },
+		func(task *tileTask) { m.processPyramidTile(p, task) })
+}
+
+// newPyramid 计算级别范围和分割级别
+func (m *Tiler) newPyramid(zooms []int) *pyramid {
+	p := &pyramid{
+		top:       uint32(zooms[0]),
+		bottom:    uint32(zooms[0]),
+		export:    make(map[uint32]bool, len(zooms)),
+		bounds:    make(map[uint32][4]uint32),
+		results:   make(map[tileTask][]*Layer),
+		aborted:   make(map[tileTask]bool),
+		levelDone: make(chan struct{}, 1),
+	}
+	for _, zoom := range zooms {
+		z := uint32(zoom)
+		if z < p.top {
+			p.top = z
+		}
+		if z > p.bottom {
+			p.bottom = z
+		}
+		p.export[z] = true
+	}
+	for z := p.top; z <= p.bottom; z++ {
+		minx, miny, maxx, maxy := m.TileBounds(z)
+		p.bounds[z] = [4]uint32{minx, miny, maxx, maxy}
+	}
+
+	// 分割级别的任务数需足够分配给所有工作协程
+	p.split = p.bottom
+	for z := p.top; z < p.bottom; z++ {
+		if p.count(z) >= int64(m.config.Concurrency*4) {
+			p.split = z
+			break
+		}
+	}
+	return p
+}
+
+// count 返回级别的瓦片数
+func (p *pyramid) count(z uint32) int64 {
+	b := p.bounds[z]
+	return int64(b[2]-b[0]+1) * int64(b[3]-b[1]+1)
+}
+
+// contains 判断瓦片是否在生成范围内
+func (p *pyramid) contains(task *tileTask) bool {
+	b, ok := p.bounds[task.z]
+	return ok && task.x >= b[0] && task.x <= b[2] && task.y >= b[1] && task.y <= b[3]
+}
+
+// store 保存已处理的图层，等待合并到父瓦片，ok为false时记录瓦片被放弃
+func (p *pyramid) store(task tileTask, layers []*Layer, ok bool) {
+	if ok && len(layers) == 0 {
+		return
+	}
+	p.resultsMu.Lock()
+	defer p.resultsMu.Unlock()
+	if !ok {
+		p.aborted[task] = true
+		return
+	}
+	p.results[task] = layers
+}
+
+// take 取出并删除已处理的图层，瓦片被放弃时返回false
+func (p *pyramid) take(task tileTask) ([]*Layer, bool) {
+	p.resultsMu.Lock()
+	defer p.resultsMu.Unlock()
+	layers, aborted := p.results[task], p.aborted[task]
+	delete(p.results, task)
+	delete(p.aborted, task)
+	return layers, !aborted
+}
+
+// bufferScale 返回级别z的瓦片携带的缓冲区相对图层缓冲区的倍数
+// 每次合并子瓦片缓冲区缩小一半，最小级别的瓦片仍需完整的缓冲区，因此级别z携带2^(z-top)倍
+func (p *pyramid) bufferScale(z uint32) uint64 {
+	return 1 << (z - p.top)
+}
+
+// layerVisible 判断图层是否在某个生成级别输出
+func (p *pyramid) layerVisible(opts *LayerOptions) bool {
+	for z := p.top; z <= p.bottom; z++ {
+		if opts.visible(z) {
+			return true
+		}
+	}
+	return false
+}
+
+// generatePyramidTasks 从分割级别开始逐级向上生成任务，每级等待下一级完成
+func (m *Tiler) generatePyramidTasks(p *pyramid) {
+	defer close(m.taskQueue)
+
+	for z := p.split; ; z-- {
+		b := p.bounds[z]
+		atomic.StoreInt64(&p.remaining, p.count(z))
+		for y := b[1]; y <= b[3]; y++ {
+			for x := b[0]; x <= b[2]; x++ {
+				select {
+				case <-m.ctx.Done():
+					return
+				case m.taskQueue <- &tileTask{z: z, x: x, y: y}:
+				}
+			}
+		}
+
+		if z == p.top {
+			return
+		}
+		// 没有瓦片的级别不会通知levelDone
+		if p.count(z) == 0 {
+			continue
+		}
+
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-p.levelDone:
+		}
+	}
+}
+
+// processPyramidTile 处理分割级别及以上的任务
+func (m *Tiler) processPyramidTile(p *pyramid, task *tileTask) {
+	layers, ok := m.buildPyramidTile(p, task)
+	if task.z > p.top {
+		p.store(*task, layers, ok)
+	}
+	if atomic.AddInt64(&p.remaining, -1) == 0 {
+		p.levelDone <- struct{}{}
+	}
+}
+
+// buildPyramidTile 生成瓦片并返回其处理后的图层
+// 最大级别从Provider获取数据，其他级别合并四个子瓦片。
+// 返回false表示瓦片因错误或取消被放弃：放弃的瓦片不导出、不删除已有瓦片，
+// 其祖先瓦片缺少该区域的数据，也随之放弃
+func (m *Tiler) buildPyramidTile(p *pyramid, task *tileTask) ([]*Layer, bool) {
+	if m.ctx.Err() != nil {
+		return nil, false
+	}
+
+	timer := stageTimer{}
+	defer m.report.addStages(timer)
+
+	var layers []*Layer
+	var ok bool
+	if task.z == p.bottom {
+		layers, ok = m.pyramidLeaf(p, task, timer)
+		if ok {
+			m.overzoomDescendants(task, layers)
+		}
+	} else {
+		children := make([][]*Layer, 4)
+		complete := true
+		for i := range children {
+			child := &tileTask{
+				z: task.z + 1,
+				x: task.x*2 + uint32(i%2),
+				y: task.y*2 + uint32(i/2),
+			}
+			if !p.contains(child) {
+				continue
+			}
+			var childOK bool
+			if task.z >= p.split {
+				children[i], childOK = m.buildPyramidTile(p, child)
+			} else {
+				children[i], childOK = p.take(*child)
+			}
+			complete = complete && childOK
+		}
+		if complete {
+			layers, ok = m.mergeChildren(task, children, p.bufferScale(task.z), timer)
+		} else if p.export[task.z] && m.ctx.Err() == nil {
+			m.reportError(newTileError(task, StageMerge, ErrTileAborted))
+		}
+	}
+
+	if m.ctx.Err() != nil {
+		return nil, false
+	}
+	if p.export[task.z] {
+		if !ok {
+			m.advance(task)
+			return nil, false
+		}
+		m.exportPyramidTile(task, layers, p.bufferScale(task.z), timer)
+	}
+	return layers, ok
+}
+
+// pyramidLeaf 从Provider获取最大级别的数据
+// 保留在任一生成级别可见的图层和要素，导出时再按级别过滤；
+// 缓冲区放大到合并至最小级别后仍为完整的图层缓冲区
+// 返回false表示瓦片因错误被放弃
+func (m *Tiler) pyramidLeaf(p *pyramid, task *tileTask, timer stageTimer) ([]*Layer, bool) {
+	return m.streamLayers(task, timer, p.bufferScale(task.z), func(name string) (layerSettings, func(*geom.Feature) bool, bool) {
+		settings, _ := m.layerSettings(name, task.z)
+		featureZoom, filter := m.featureZoomFunc(name), m.featureFilter(name)
+		keep := func(f *geom.Feature) bool {
+			return filter(f) && featureVisibleRange(featureZoom, f, p.top, p.bottom)
+		}
+		return settings, keep, p.layerVisible(m.config.LayerOptions[name])
+	})
+}
+
+// exportPyramidTile 按级别过滤图层和要素后导出瓦片
+// bufferScale 为瓦片携带的缓冲区倍数，大于1时裁剪到图层缓冲区
+func (m *Tiler) exportPyramidTile(task *tileTask, layers []*Layer, bufferScale uint64, timer stageTimer) {
+	m.advance(task)
+	layers = m.visibleLayers(task.z, layers)
+	if bufferScale > 1 {
+		start := time.Now()
+		layers = m.trimBuffer(task.z, layers)
+		timer.since(StageClean, start)
+	}
+	m.finishTile(task, m.newTile(task), layers, timer)
+}
+
+// trimBuffer 将图层裁剪到瓦片加上图层缓冲区的范围，返回新的图层
+func (m *Tiler) trimBuffer(z uint32, layers []*Layer) []*Layer {
+	var result []*Layer
+	for _, layer := range layers {
+		settings, _ := m.layerSettings(layer.Name, z)
+		buffer, extent := float64(settings.buffer), float64(settings.extent)
+		region := [4]float64{-buffer, -buffer, extent + buffer, extent + buffer}
+		nl := &Layer{Name: layer.Name, Extent: layer.Extent}
+		for _, f := range layer.Features {
+			g := clipGeometry(m.ctx, f.Geometry, region)
+			if g == nil || countVertices(g) == 0 {
+				continue
+			}
+			nf := *f
+			nf.Geometry = g
+			nl.Features = append(nl.Features, &nf)
+		}
+		if len(nl.Features) > 0 {
+			result = append(result, nl)
+		}
+	}
+	return result
+}
+
+// visibleLayers 返回在给定级别输出的图层和要素
+func (m *Tiler) visibleLayers(z uint32, layers []*Layer) []*Layer {
+	var visible []*Layer
+	for _, layer := range layers {
+		if _, ok := m.layerSettings(layer.Name, z); !ok {
+			continue
+		}
+		featureZoom := m.featureZoomFunc(layer.Name)
+		nl := &Layer{Name: layer.Name, Extent: layer.Extent}
+		for _, f := range layer.Features {
+			if featureVisible(featureZoom, f, z) {
+				nl.Features = append(nl.Features, f)
+			}
+		}
+		if len(nl.Features) > 0 {
+			visible = append(visible, nl)
+		}
+	}
+	return visible
+}
+
+// mergeChildren 将四个子瓦片的图层合并为父瓦片的图层
+// children按左上、右上、左下、右下排列，与Tile.GetChildren一致。
+// 子瓦片坐标缩小一半后取整，按父瓦片级别重新简化，并裁剪到子瓦片所在的象限，
+// 只有父瓦片外侧保留缓冲区，避免相邻子瓦片缓冲区内的要素重复。
+// 子瓦片携带2*bufferScale倍的缓冲区，缩小后父瓦片外侧保留bufferScale倍的图层缓冲区。
+// 要素出错且策略要求放弃瓦片时返回false
+func (m *Tiler) mergeChildren(task *tileTask, children [][]*Layer, bufferScale uint64, timer stageTimer) ([]*Layer, bool) {
+	var result []*Layer
+	merged := make(map[string]*Layer)
+
+	for i, layers := range children {
+		dx, dy := float64(i%2), float64(i/2)
+		for _, layer := range layers {
+			settings, _ := m.layerSettings(layer.Name, task.z)
+			half := float64(settings.extent) / 2
+			region := quadrantRegion(dx, dy, half, float64(settings.buffer*bufferScale))
+
+			target, ok := merged[layer.Name]
+			if !ok {
+				target = &Layer{Name: layer.Name, Extent: settings.extent}
+				merged[layer.Name] = target
+				result = append(result, target)
+			}
+
+			for fi, f := range layer.Features {
+				g, err := m.mergeGeometry(task, f.Geometry, dx*half, dy*half, settings, region, timer)
+				if err != nil {
+					m.reportError(newFeatureError(task, layer.Name, fi, StageMerge, err))
+					if m.config.ErrorPolicy == ErrorPolicySkipFeature {
+						continue
+					}
+					return nil, false
+				}
+				if g == nil {
+					continue
+				}
+				nf := *f
+				nf.Geometry = g
+				target.Features = append(target.Features, &nf)
+			}
+		}
+	}
+
+	nonEmpty := result[:0]
+	for _, layer := range result {
+		if len(layer.Features) > 0 {
+			nonEmpty = append(nonEmpty, layer)
+		}
+	}
+	return nonEmpty, true
+}
+
+// quadrantRegion 返回子瓦片在父瓦片像素坐标中的范围，仅外侧加上缓冲区buffer(父瓦片像素)
+func quadrantRegion(dx, dy, half, buffer float64) [4]float64 {
+	r := [4]float64{dx * half, dy * half, (dx + 1) * half, (dy + 1) * half}
+	if dx == 0 {
+		r[0] -= buffer
+	} else {
+		r[2] += buffer
+	}
+	if dy == 0 {
+		r[1] -= buffer
+	} else {
+		r[3] += buffer
+	}
+	return r
+}
+
+// mergeGeometry 将子瓦片像素坐标的几何对象转换到父瓦片，几何对象为空时返回nil
+// offsetX、offsetY 为子瓦片左上角在父瓦片中的像素坐标
+func (m *Tiler) mergeGeometry(task *tileTask, g geom.Geometry, offsetX, offsetY float64,
+	settings layerSettings, region [4]float64, timer stageTimer) (geom.Geometry, error) {
+	if g == nil {
+		return nil, nil
+	}
+
+	// 缩放并重新量化到整数像素
+	start := time.Now()
+	scaled, err := mapCoords(g, func(pt []float64) []float64 {
+		return []float64{math.Round(pt[0]/2 + offsetX), math.Round(pt[1]/2 + offsetY)}
+	})
+	timer.since(StageMerge, start)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按父瓦片级别重新简化，容差按DefaultExtent下的像素计算
+	if task.z < uint32(m.config.SimplificationMaxZoom) && settings.simplify {
+		start := time.Now()
+		scaled = simplify.SimplifyGeometry(scaled, settings.tolerance*float64(settings.extent)/DefaultExtent)
+		timer.since(StageSimplify, start)
+		if scaled == nil {
+			return nil, nil
+		}
+	}
+
+	// 裁剪到象限
+	start = time.Now()
+	scaled = clipGeometry(m.ctx, scaled, region)
+	timer.since(StageClean, start)
+
+	if scaled == nil || countVertices(scaled) == 0 {
+		return nil, nil
+	}
+	return scaled, nil
+}
+
+// clipGeometry 将几何对象裁剪到范围内
+// 点按左闭右开过滤，避免落在象限边界上的点重复
+func clipGeometry(ctx context.Context, g geom.Geometry, r [4]float64) geom.Geometry {
+	inside := func(pt []float64) bool {
+		return pt[0] >= r[0] && pt[0] < r[2] && pt[1] >= r[1] && pt[1] < r[3]
+	}
+
+	switch gg := g.(type) {
+	case geom.Point:
+		if !inside(gg.Data()) {
+			return nil
+		}
+		return gg
+	case geom.MultiPoint:
+		var pts [][]float64
+		for _, pt := range gg.Data() {
+			if inside(pt) {
+				pts = append(pts, pt)
+			}
+		}
+		if len(pts) == 0 {
+			return nil
+		}
+		return gen.NewMultiPoint(pts)
+	}
+
+	region := gen.NewExtent([]float64{r[0], r[1]}, []float64{r[2], r[3]})
+	if cleaned, err := validate.CleanGeometry(ctx, g, region); err == nil {
+		return cleaned
+	}
+	return g
+}
+
+// mapCoords 返回对每个坐标应用f后的新几何对象，不修改原几何对象
+func mapCoords(g geom.Geometry, f func(pt []float64) []float64) (geom.Geometry, error) {
+	line := func(pts [][]float64) [][]float64 {
+		out := make([][]float64, len(pts))
+		for i, pt := range pts {
+			out[i] = f(pt)
+		}
+		return out
+	}
+	polygon := func(rings [][][]float64) [][][]float64 {
+		out := make([][][]float64, len(rings))
+		for i, ring := range rings {
+			out[i] = line(ring)
+		}
+		return out
+	}
+
+	switch gg := g.(type) {
+	case geom.Point:
+		return gen.NewPoint(f(gg.Data())), nil
+	case geom.MultiPoint:
+		return gen.NewMultiPoint(line(gg.Data())), nil
+	case geom.LineString:
+		return gen.NewLineString(line(gg.Data())), nil
+	case geom.MultiLine:
+		return gen.NewMultiLineString(polygon(gg.Data())), nil
+	case geom.Polygon:
+		return gen.NewPolygon(polygon(gg.Data())), nil
+	case geom.MultiPolygon:
+		polys := gg.Data()
+		out := make([][][][]float64, len(polys))
+		for i, p := range polys {
+			out[i] = polygon(p)
+		}
+		return gen.NewMultiPolygon(out), nil
+	case geom.Collection:
+		var geoms []geom.Geometry
+		for _, sub := range gg.Geometries() {
+			ng, err := mapCoords(sub, f)
+			if err != nil {
+				return nil, err
+			}
+			geoms = append(geoms, ng)
+		}
+		return gen.NewGeometryCollection(geoms...), nil
+	}
+	return nil, fmt.Errorf("unknown Geometry: %T", g)
+}
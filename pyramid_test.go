@@ -0,0 +1,293 @@
+package tile
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// countingProvider 记录被查询的瓦片级别
+type countingProvider struct {
+	MockProvider
+	mu    sync.Mutex
+	zooms map[uint32]int
+}
+
+func (p *countingProvider) GetDataByTile(t *Tile) []*Layer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.zooms == nil {
+		p.zooms = make(map[uint32]int)
+	}
+	p.zooms[t.Z]++
+	return p.MockProvider.GetDataByTile(t)
+}
+
+// TestTiler_mergeChildren 测试子瓦片合并到父瓦片
+func TestTiler_mergeChildren(t *testing.T) {
+	tiler := NewTiler(&Config{TileExtent: 4096, TileBuffer: 64})
+	defer tiler.Stop()
+
+	children := [][]*Layer{
+		// 左上：瓦片内的点保留，右侧缓冲区内的点属于右上象限，丢弃
+		{{Name: "points", Extent: 4096, Features: []*geom.Feature{
+			{Geometry: basic.Point{4090, 10}, Properties: map[string]interface{}{"id": 1}},
+			{Geometry: basic.Point{4100, 10}, Properties: map[string]interface{}{"id": 2}},
+		}}},
+		nil,
+		nil,
+		// 右下：外侧缓冲区内的点保留，子瓦片缓冲区以外的点丢弃
+		{{Name: "points", Extent: 4096, Features: []*geom.Feature{
+			{Geometry: basic.Point{100, 200}, Properties: map[string]interface{}{"id": 3}},
+			{Geometry: basic.Point{4150, 4150}, Properties: map[string]interface{}{"id": 4}},
+			{Geometry: basic.Point{4300, 4100}, Properties: map[string]interface{}{"id": 5}},
+		}}},
+	}
+
+	layers, ok := tiler.mergeChildren(&tileTask{z: 11, x: 0, y: 0}, children, 1, stageTimer{})
+	if !ok || len(layers) != 1 || layers[0].Name != "points" || layers[0].Extent != 4096 {
+		t.Fatalf("mergeChildren() = %+v, want 1个points图层", layers)
+	}
+
+	expected := map[int][2]float64{
+		1: {2045, 5},
+		3: {2098, 2148},
+		4: {4123, 4123},
+	}
+	features := layers[0].Features
+	if len(features) != len(expected) {
+		t.Fatalf("合并后要素数 = %v, want %v", len(features), len(expected))
+	}
+	for _, f := range features {
+		id := f.Properties["id"].(int)
+		pt, ok := f.Geometry.(geom.Point)
+		if !ok {
+			t.Fatalf("要素 %d 几何类型 = %T, want Point", id, f.Geometry)
+		}
+		if want := expected[id]; pt.X() != want[0] || pt.Y() != want[1] {
+			t.Errorf("要素 %d 坐标 = (%v, %v), want %v", id, pt.X(), pt.Y(), want)
+		}
+	}
+
+	// 子瓦片的数据不被修改
+	if pt := children[0][0].Features[0].Geometry.(basic.Point); pt[0] != 4090 {
+		t.Errorf("子瓦片要素被修改: %v", pt)
+	}
+}
+
+// TestTiler_BottomUpBuffer 测试自底向上生成时最大级别放大缓冲区，导出时裁剪回图层缓冲区
+func TestTiler_BottomUpBuffer(t *testing.T) {
+	tiler := NewTiler(&Config{TileExtent: 4096, TileBuffer: 64})
+	defer tiler.Stop()
+
+	p := &pyramid{top: 2, bottom: 5}
+	if p.bufferScale(2) != 1 || p.bufferScale(3) != 2 || p.bufferScale(5) != 8 {
+		t.Errorf("bufferScale() = %d, %d, %d, want 1, 2, 8", p.bufferScale(2), p.bufferScale(3), p.bufferScale(5))
+	}
+
+	// 最大级别的查询范围和图层裁剪范围放大8倍，合并3次后最小级别仍有64像素
+	task := &tileTask{z: 5, x: 10, y: 10}
+	if got, want := tiler.queryTile(task, p.bufferScale(5)).Buffer, tiler.queryTile(task, 1).Buffer*8; got != want {
+		t.Errorf("queryTile() 缓冲区 = %v, want %v", got, want)
+	}
+	b := &layerBuilder{m: tiler, task: task, bufferScale: p.bufferScale(5), states: map[string]*layerState{},
+		filter: func(name string) (layerSettings, func(*geom.Feature) bool, bool) {
+			settings, _ := tiler.layerSettings(name, task.z)
+			return settings, nil, true
+		}}
+	if s := b.state("roads"); s.clip.MinX() != -512 || s.clip.MaxX() != 4608 {
+		t.Errorf("图层裁剪范围 = %v, want [-512, 4608]", s.clip)
+	}
+
+	// 导出时裁剪到图层缓冲区
+	layers := tiler.trimBuffer(3, []*Layer{{Name: "roads", Extent: 4096, Features: []*geom.Feature{
+		{Geometry: basic.Point{-60, 10}, Properties: map[string]interface{}{"id": 1}},
+		{Geometry: basic.Point{-100, 10}, Properties: map[string]interface{}{"id": 2}},
+		{Geometry: basic.Point{4150, 4150}, Properties: map[string]interface{}{"id": 3}},
+		{Geometry: basic.Point{4200, 0}, Properties: map[string]interface{}{"id": 4}},
+	}}})
+	if len(layers) != 1 || len(layers[0].Features) != 2 ||
+		layers[0].Features[0].Properties["id"] != 1 || layers[0].Features[1].Properties["id"] != 3 {
+		t.Errorf("trimBuffer() = %+v, want 要素1和3", layers)
+	}
+}
+
+// TestQuadrantRegion 测试象限范围只在外侧加缓冲区
+func TestQuadrantRegion(t *testing.T) {
+	testCases := []struct {
+		dx, dy   float64
+		expected [4]float64
+	}{
+		{0, 0, [4]float64{-64, -64, 2048, 2048}},
+		{1, 0, [4]float64{2048, -64, 4160, 2048}},
+		{0, 1, [4]float64{-64, 2048, 2048, 4160}},
+		{1, 1, [4]float64{2048, 2048, 4160, 4160}},
+	}
+	for _, tc := range testCases {
+		if got := quadrantRegion(tc.dx, tc.dy, 2048, 64); got != tc.expected {
+			t.Errorf("quadrantRegion(%v, %v) = %v, want %v", tc.dx, tc.dy, got, tc.expected)
+		}
+	}
+}
+
+// TestTiler_BottomUp 测试自底向上生成只在最大级别查询Provider
+func TestTiler_BottomUp(t *testing.T) {
+	provider := &countingProvider{MockProvider: MockProvider{
+		layers: []*Layer{{Name: "test_layer", Features: []*geom.Feature{{Geometry: basic.Point{0, 0}}}}},
+		srid:   4326,
+	}}
+	tiler := NewTiler(&Config{
+		Provider:    provider,
+		Exporter:    &MockExporter{},
+		MinZoom:     0,
+		MaxZoom:     3,
+		Bound:       &[4]float64{-10, -10, 10, 10},
+		Concurrency: 2,
+		BottomUp:    true,
+	})
+
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	minx, miny, maxx, maxy := tiler.TileBounds(3)
+	leaves := int((maxx - minx + 1) * (maxy - miny + 1))
+	if len(provider.zooms) != 1 || provider.zooms[3] != leaves {
+		t.Errorf("Provider查询 = %v, want 仅z=3查询%d次", provider.zooms, leaves)
+	}
+
+	report := tiler.Report()
+	var tiles int64
+	for _, z := range report.ZoomLevels() {
+		tiles += report.Zooms[z].Tiles + report.Zooms[z].EmptyTiles
+	}
+	if want := tiler.count(tiler.getZoomLevels()); tiles != want {
+		t.Errorf("处理瓦片数 = %v, want %v", tiles, want)
+	}
+}
+
+// brokenTileProvider 在指定瓦片返回无法转换坐标的要素
+type brokenTileProvider struct {
+	MockProvider
+	broken tileTask
+}
+
+func (p *brokenTileProvider) GetDataByTile(t *Tile) []*Layer {
+	if (tileTask{z: t.Z, x: t.X, y: t.Y}) == p.broken {
+		return []*Layer{{Name: "test_layer", Features: []*geom.Feature{{Geometry: nil}}}}
+	}
+	return p.MockProvider.GetDataByTile(t)
+}
+
+// TestTiler_BottomUpAbortedTile 测试被放弃的最大级别瓦片及其祖先瓦片不导出
+func TestTiler_BottomUpAbortedTile(t *testing.T) {
+	for _, concurrency := range []int{1, 8} {
+		exporter := &MockExporter{}
+		tiler := NewTiler(&Config{
+			Provider: &brokenTileProvider{
+				MockProvider: MockProvider{
+					layers: []*Layer{{Name: "test_layer", Features: []*geom.Feature{{Geometry: basic.Point{0, 0}}}}},
+					srid:   4326,
+				},
+				broken: tileTask{z: 2, x: 1, y: 1},
+			},
+			Exporter:    exporter,
+			MinZoom:     0,
+			MaxZoom:     2,
+			Bound:       &[4]float64{-10, -10, 10, 10},
+			Concurrency: concurrency,
+			BottomUp:    true,
+			ErrorPolicy: ErrorPolicySkipTile,
+		})
+
+		var report *ErrorReport
+		if err := tiler.Tiler(); !errors.As(err, &report) {
+			t.Fatalf("Tiler() 错误 = %v, want ErrorReport", err)
+		}
+		aborted := map[tileTask]Stage{}
+		for _, e := range report.Errors {
+			aborted[tileTask{z: e.Z, x: e.X, y: e.Y}] = e.Stage
+		}
+		want := map[tileTask]Stage{{z: 2, x: 1, y: 1}: StageReproject, {z: 1}: StageMerge, {z: 0}: StageMerge}
+		if len(aborted) != len(want) {
+			t.Errorf("concurrency=%d 错误瓦片 = %v, want %v", concurrency, aborted, want)
+		}
+		for task, stage := range want {
+			if aborted[task] != stage {
+				t.Errorf("concurrency=%d 瓦片 %v 错误阶段 = %v, want %v", concurrency, task, aborted[task], stage)
+			}
+		}
+		for _, s := range exporter.GetSavedTiles() {
+			if _, ok := want[tileTask{z: s.Tile.Z, x: s.Tile.X, y: s.Tile.Y}]; ok {
+				t.Errorf("concurrency=%d 被放弃的瓦片 %s 不应导出", concurrency, s.Tile.ToString())
+			}
+		}
+
+		// 其余瓦片照常完成
+		r := tiler.Report()
+		var tiles int64
+		for _, z := range r.ZoomLevels() {
+			tiles += r.Zooms[z].Tiles + r.Zooms[z].EmptyTiles
+		}
+		if expected := tiler.count(tiler.getZoomLevels()) - int64(len(want)); tiles != expected {
+			t.Errorf("concurrency=%d 完成瓦片数 = %v, want %v", concurrency, tiles, expected)
+		}
+	}
+}
+
+// TestTiler_generatePyramidTasksEmptyLevel 测试没有瓦片的级别不阻塞任务生成
+func TestTiler_generatePyramidTasksEmptyLevel(t *testing.T) {
+	tiler := NewTiler(&Config{Concurrency: 1})
+	defer tiler.Stop()
+
+	p := &pyramid{
+		top:       0,
+		bottom:    2,
+		split:     2,
+		bounds:    map[uint32][4]uint32{0: {0, 0, 0, 0}, 1: {1, 1, 0, 0}, 2: {1, 1, 0, 0}},
+		levelDone: make(chan struct{}, 1),
+	}
+	done := make(chan struct{})
+	go func() {
+		tiler.generatePyramidTasks(p)
+		close(done)
+	}()
+
+	var tasks []tileTask
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case task, ok := <-tiler.taskQueue:
+			if !ok {
+				<-done
+				if len(tasks) != 1 || tasks[0] != (tileTask{}) {
+					t.Errorf("任务 = %v, want 仅z=0的瓦片", tasks)
+				}
+				return
+			}
+			tasks = append(tasks, *task)
+		case <-timeout:
+			t.Fatal("generatePyramidTasks() 在空级别阻塞")
+		}
+	}
+}
+
+// TestTiler_BottomUpResume 测试自底向上生成不支持断点续传
+func TestTiler_BottomUpResume(t *testing.T) {
+	tiler := NewTiler(&Config{
+		Provider:  &MockProvider{srid: 4326},
+		Exporter:  &MockExporter{},
+		OutputDir: t.TempDir(),
+		MaxZoom:   1,
+		BottomUp:  true,
+		Resume:    true,
+	})
+
+	if err := tiler.Tiler(); !errors.Is(err, ErrBottomUpResume) {
+		t.Errorf("Tiler() 错误 = %v, want %v", err, ErrBottomUpResume)
+	}
+}
@@ -0,0 +1,248 @@
+package tile
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flywave/go-geom"
+)
+
+// TilingReport 瓦片生成统计报告
+type TilingReport struct {
+	// Zooms 各缩放级别的统计
+	Zooms map[int]*ZoomStats `json:"zooms"`
+	// Stages 各处理阶段的累计耗时，为所有工作协程耗时之和
+	Stages map[Stage]time.Duration `json:"stages"`
+	// Duration 运行总耗时
+	Duration time.Duration `json:"duration"`
+}
+
+// ZoomStats 单个缩放级别的统计
+type ZoomStats struct {
+	// Tiles 导出的瓦片数
+	Tiles int64 `json:"tiles"`
+	// EmptyTiles 没有数据的瓦片数
+	EmptyTiles int64 `json:"empty_tiles"`
+	// MinBytes 最小编码大小
+	MinBytes int64 `json:"min_bytes"`
+	// MaxBytes 最大编码大小
+	MaxBytes int64 `json:"max_bytes"`
+	// TotalBytes 编码大小总和
+	TotalBytes int64 `json:"total_bytes"`
+	// Layers 各图层的要素和顶点数
+	Layers map[string]*LayerStats `json:"layers"`
+	// SimplifiedTiles 因超出预算而额外简化的瓦片数
+	SimplifiedTiles int64 `json:"simplified_tiles"`
+	// DroppedTiles 因超出预算而丢弃要素的瓦片数
+	DroppedTiles int64 `json:"dropped_tiles"`
+	// DroppedFeatures 因超出预算而丢弃的要素数
+	DroppedFeatures int64 `json:"dropped_features"`
+}
+
+// AvgBytes 返回平均编码大小
+func (s *ZoomStats) AvgBytes() float64 {
+	if s.Tiles == 0 {
+		return 0
+	}
+	return float64(s.TotalBytes) / float64(s.Tiles)
+}
+
+// LayerStats 单个图层的统计
+type LayerStats struct {
+	Features int64 `json:"features"`
+	Vertices int64 `json:"vertices"`
+}
+
+// ZoomLevels 返回报告中按升序排列的缩放级别
+func (r *TilingReport) ZoomLevels() []int {
+	zooms := make([]int, 0, len(r.Zooms))
+	for z := range r.Zooms {
+		zooms = append(zooms, z)
+	}
+	sort.Ints(zooms)
+	return zooms
+}
+
+// WriteJSON 将统计报告以JSON格式写入w
+func (r *TilingReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// reportCollector 在运行中并发收集统计数据
+type reportCollector struct {
+	mu     sync.Mutex
+	zooms  map[int]*ZoomStats
+	stages map[Stage]time.Duration
+	start  time.Time
+	end    time.Time
+}
+
+func newReportCollector() *reportCollector {
+	return &reportCollector{
+		zooms:  make(map[int]*ZoomStats),
+		stages: make(map[Stage]time.Duration),
+	}
+}
+
+// begin 记录运行开始时间
+func (c *reportCollector) begin() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.start = time.Now()
+}
+
+// finish 记录运行结束时间
+func (c *reportCollector) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.end = time.Now()
+}
+
+// zoom 返回缩放级别的统计，调用方需持有锁
+func (c *reportCollector) zoom(z uint32) *ZoomStats {
+	s, ok := c.zooms[int(z)]
+	if !ok {
+		s = &ZoomStats{Layers: make(map[string]*LayerStats)}
+		c.zooms[int(z)] = s
+	}
+	return s
+}
+
+// addTile 记录导出的瓦片及其预算处理结果
+func (c *reportCollector) addTile(z uint32, size int64, layers []*Layer, budget budgetResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.zoom(z)
+	if s.Tiles == 0 || size < s.MinBytes {
+		s.MinBytes = size
+	}
+	if size > s.MaxBytes {
+		s.MaxBytes = size
+	}
+	s.Tiles++
+	s.TotalBytes += size
+	if budget.tolerance > 0 {
+		s.SimplifiedTiles++
+	}
+	if budget.dropped > 0 {
+		s.DroppedTiles++
+		s.DroppedFeatures += int64(budget.dropped)
+	}
+
+	for _, layer := range layers {
+		ls, ok := s.Layers[layer.Name]
+		if !ok {
+			ls = &LayerStats{}
+			s.Layers[layer.Name] = ls
+		}
+		for _, f := range layer.Features {
+			ls.Features++
+			ls.Vertices += int64(countVertices(f.Geometry))
+		}
+	}
+}
+
+// addEmptyTile 记录没有数据的瓦片
+func (c *reportCollector) addEmptyTile(z uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.zoom(z).EmptyTiles++
+}
+
+// addStages 累加各阶段耗时
+func (c *reportCollector) addStages(timer stageTimer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for stage, d := range timer {
+		c.stages[stage] += d
+	}
+}
+
+// snapshot 返回当前统计的副本
+func (c *reportCollector) snapshot() *TilingReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := &TilingReport{
+		Zooms:  make(map[int]*ZoomStats, len(c.zooms)),
+		Stages: make(map[Stage]time.Duration, len(c.stages)),
+	}
+	for z, s := range c.zooms {
+		cs := *s
+		cs.Layers = make(map[string]*LayerStats, len(s.Layers))
+		for name, ls := range s.Layers {
+			cls := *ls
+			cs.Layers[name] = &cls
+		}
+		r.Zooms[z] = &cs
+	}
+	for stage, d := range c.stages {
+		r.Stages[stage] = d
+	}
+	if !c.start.IsZero() {
+		end := c.end
+		if end.IsZero() {
+			end = time.Now()
+		}
+		r.Duration = end.Sub(c.start)
+	}
+	return r
+}
+
+// stageTimer 单个瓦片内各阶段的耗时，处理完瓦片后汇总到reportCollector
+type stageTimer map[Stage]time.Duration
+
+// since 累加从start开始到现在的耗时
+func (t stageTimer) since(stage Stage, start time.Time) {
+	t[stage] += time.Since(start)
+}
+
+// countVertices 统计几何对象的顶点数
+func countVertices(g geom.Geometry) int {
+	switch gg := g.(type) {
+	case geom.Point:
+		return 1
+	case geom.MultiPoint:
+		return len(gg.Data())
+	case geom.LineString:
+		return len(gg.Data())
+	case geom.MultiLine:
+		n := 0
+		for _, l := range gg.Data() {
+			n += len(l)
+		}
+		return n
+	case geom.Polygon:
+		n := 0
+		for _, r := range gg.Data() {
+			n += len(r)
+		}
+		return n
+	case geom.MultiPolygon:
+		n := 0
+		for _, p := range gg.Data() {
+			for _, r := range p {
+				n += len(r)
+			}
+		}
+		return n
+	case geom.Collection:
+		n := 0
+		for _, sub := range gg.Geometries() {
+			n += countVertices(sub)
+		}
+		return n
+	}
+	return 0
+}
+
+// Report 返回瓦片生成统计报告，运行中调用时返回当前的统计
+func (m *Tiler) Report() *TilingReport {
+	return m.report.snapshot()
+}
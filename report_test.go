@@ -0,0 +1,112 @@
+package tile
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// TestTiler_Report 测试运行后的统计报告
+func TestTiler_Report(t *testing.T) {
+	layer := &Layer{
+		Name:     "test_layer",
+		Features: []*geom.Feature{{Geometry: basic.Point{0, 0}}},
+	}
+	tiler := NewTiler(&Config{
+		Provider:  &MockProvider{layers: []*Layer{layer}, srid: 4326},
+		Exporter:  NewGeoJSONExporter(),
+		OutputDir: t.TempDir(),
+		MinZoom:   0,
+		MaxZoom:   1,
+		Bound:     &[4]float64{-1, -1, 1, 1},
+	})
+
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	report := tiler.Report()
+	if zooms := report.ZoomLevels(); len(zooms) != 2 || zooms[0] != 0 || zooms[1] != 1 {
+		t.Fatalf("ZoomLevels() = %v, want [0 1]", zooms)
+	}
+
+	var tiles int64
+	for _, z := range report.ZoomLevels() {
+		s := report.Zooms[z]
+		tiles += s.Tiles
+		if s.MinBytes <= 0 || s.MaxBytes < s.MinBytes {
+			t.Errorf("z=%d 字节统计错误: min=%v max=%v", z, s.MinBytes, s.MaxBytes)
+		}
+		if avg := s.AvgBytes(); avg < float64(s.MinBytes) || avg > float64(s.MaxBytes) {
+			t.Errorf("z=%d AvgBytes() = %v 超出 [%v, %v]", z, avg, s.MinBytes, s.MaxBytes)
+		}
+		ls := s.Layers["test_layer"]
+		if ls == nil || ls.Features != s.Tiles {
+			t.Errorf("z=%d 图层统计 = %+v, want %v 个要素", z, ls, s.Tiles)
+		}
+	}
+	if want := tiler.count(tiler.getZoomLevels()); tiles != want {
+		t.Errorf("瓦片总数 = %v, want %v", tiles, want)
+	}
+
+	for _, stage := range []Stage{StageProvider, StageReproject, StagePrepare, StageClean, StageExport} {
+		if _, ok := report.Stages[stage]; !ok {
+			t.Errorf("缺少阶段 %v 的耗时", stage)
+		}
+	}
+	if report.Duration <= 0 {
+		t.Errorf("Duration = %v, want > 0", report.Duration)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() 错误 = %v", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		t.Error("WriteJSON() 输出不是有效的JSON")
+	}
+}
+
+// TestTiler_ReportEmptyTiles 测试空瓦片统计
+func TestTiler_ReportEmptyTiles(t *testing.T) {
+	tiler := NewTiler(&Config{
+		Provider: &MockProvider{srid: 4326},
+		Exporter: &MockExporter{},
+		MinZoom:  0,
+		MaxZoom:  0,
+	})
+
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	s := tiler.Report().Zooms[0]
+	if s == nil || s.EmptyTiles != 1 || s.Tiles != 0 {
+		t.Errorf("z=0 统计 = %+v, want 1个空瓦片", s)
+	}
+}
+
+// TestCountVertices 测试顶点计数
+func TestCountVertices(t *testing.T) {
+	testCases := []struct {
+		name     string
+		geometry geom.Geometry
+		expected int
+	}{
+		{"点", basic.Point{1, 2}, 1},
+		{"线", basic.Line{{0, 0}, {1, 1}, {2, 2}}, 3},
+		{"多边形", basic.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}, {{0.2, 0.2}, {0.5, 0.2}, {0.2, 0.5}}}, 7},
+		{"空几何", nil, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := countVertices(tc.geometry); got != tc.expected {
+				t.Errorf("countVertices() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package tile
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-vector-tiler/maths/webmercator"
+)
+
+// Retile 仅重新生成与变化区域相交的瓦片
+// dirty 为变化区域列表，坐标系与Config.Bound相同(Config.SRS)。
+// 在getZoomLevels的每个级别上，瓦片加上缓冲区后与变化区域相交即重新生成，
+// 缓冲区取全局配置和各图层LayerOptions中相对瓦片范围最大的一个；
+// 重新生成后没有数据的瓦片通过Exporter删除(需实现TileRemover)。
+// 与Tiler一样，每个Tiler实例只能运行一次
+func (m *Tiler) Retile(dirty []*[4]float64) error {
+	// 源级别瓦片的超级别子孙瓦片随之重新生成
+	m.overzoom = m.planOverzoom(m.getZoomLevels())
+	if err := m.checkMatrixSet(m.getZoomLevels()); err != nil {
+		m.cancel()
+		close(m.errChan)
+		return fmt.Errorf("瓦片生成失败: %w", err)
+	}
+
+	tasks := m.dirtyTasks(dirty)
+	m.retiling = true
+	total := int64(len(tasks))
+	for _, task := range tasks {
+		total += m.overzoom.count(task)
+	}
+	return m.run(total, func() { m.enqueueTasks(tasks) }, m.processTile)
+}
+
+// dirtyTasks 计算受变化区域影响的瓦片任务，按z、y、x排序并去重
+func (m *Tiler) dirtyTasks(dirty []*[4]float64) []*tileTask {
+	seen := make(map[tileTask]struct{})
+	var tasks []*tileTask
+	ratio := m.maxBufferRatio()
+	// 缓冲区可能覆盖的相邻瓦片数
+	grow := uint32(max(1, math.Ceil(ratio)))
+
+	for _, zoom := range m.getZoomLevels() {
+		z := uint32(zoom)
+		bminx, bminy, bmaxx, bmaxy := m.TileBounds(z)
+
+		for _, b := range dirty {
+			if b == nil {
+				continue
+			}
+
+			box, known := m.boundToGrid(*b)
+			var bd [4]uint32
+			if set := m.config.TileMatrixSet; set != nil {
+				r, ok := set.TileRange(z, box)
+				switch {
+				case !known:
+					r = [4]uint32{bminx, bminy, bmaxx, bmaxy}
+				case !ok:
+					continue
+				}
+				bd = r
+			} else {
+				rect := vec2d.Rect{Min: vec2d.T{b[0], b[1]}, Max: vec2d.T{b[2], b[3]}}
+				_, _, iter, _ := m.grid.GetAffectedLevelTiles(rect, zoom)
+				bd = iter.GetTileBound()
+			}
+
+			// 向外扩展grow个瓦片，缓冲区可能覆盖到相邻瓦片，并限制在Config.Bound范围内
+			minx, maxx := expandRange(bd[0], bd[2], grow, bminx, bmaxx)
+			miny, maxy := expandRange(bd[1], bd[3], grow, bminy, bmaxy)
+
+			for y := miny; y <= maxy && miny <= maxy; y++ {
+				for x := minx; x <= maxx && minx <= maxx; x++ {
+					task := tileTask{z: z, x: x, y: y}
+					if _, ok := seen[task]; ok {
+						continue
+					}
+					// 无法换算的坐标系保守地保留所有候选瓦片
+					if known && !bufferedTileIntersects(m.newTile(&task), box, ratio) {
+						continue
+					}
+					seen[task] = struct{}{}
+					tasks = append(tasks, &task)
+				}
+			}
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.z != b.z {
+			return a.z < b.z
+		}
+		if a.y != b.y {
+			return a.y < b.y
+		}
+		return a.x < b.x
+	})
+	return tasks
+}
+
+// expandRange 将瓦片行列号范围[lo, hi]向两侧扩展n，并限制在[bmin, bmax]内
+func expandRange(lo, hi, n, bmin, bmax uint32) (uint32, uint32) {
+	if lo > bmin+n {
+		lo -= n
+	} else {
+		lo = bmin
+	}
+	if hi+n < bmax {
+		hi += n
+	} else {
+		hi = bmax
+	}
+	return lo, hi
+}
+
+// enqueueTasks 将给定任务放入队列
+func (m *Tiler) enqueueTasks(tasks []*tileTask) {
+	defer close(m.taskQueue)
+
+	for _, task := range tasks {
+		select {
+		case <-m.ctx.Done():
+			return
+		case m.taskQueue <- task:
+		}
+	}
+}
+
+// maxBufferRatio 返回全局配置和各图层中缓冲区与瓦片范围之比的最大值
+func (m *Tiler) maxBufferRatio() float64 {
+	ratio := float64(m.config.TileBuffer) / float64(m.config.TileExtent)
+	for name := range m.config.LayerOptions {
+		s, _ := m.layerSettings(name, 0)
+		ratio = max(ratio, float64(s.buffer)/float64(s.extent))
+	}
+	return ratio
+}
+
+// bufferedTileIntersects 判断加上缓冲区的瓦片是否与网格坐标系下的范围相交，ratio为缓冲区与瓦片范围之比
+func bufferedTileIntersects(t *Tile, box [4]float64, ratio float64) bool {
+	ext := t.GetExtent()
+	minx, maxx := min(ext.MinX(), ext.MaxX()), max(ext.MinX(), ext.MaxX())
+	miny, maxy := min(ext.MinY(), ext.MaxY()), max(ext.MinY(), ext.MaxY())
+
+	margin := (maxx - minx) * ratio
+	return box[0] <= maxx+margin && box[2] >= minx-margin &&
+		box[1] <= maxy+margin && box[3] >= miny-margin
+}
+
+// boundToGrid 将Config.SRS下的范围换算为瓦片网格坐标系下的范围，无法换算时返回false
+func (m *Tiler) boundToGrid(b [4]float64) ([4]float64, bool) {
+	set := m.config.TileMatrixSet
+	if set == nil {
+		return boundToWebMercator(m.config.SRS, b)
+	}
+	srid, ok := srsSRID(m.config.SRS)
+	if !ok {
+		return b, false
+	}
+	box, err := transformBounds(srid, set.SRID, b)
+	return box, err == nil
+}
+
+// boundToWebMercator 将Config.SRS下的范围换算为Web墨卡托范围
+// 仅支持WGS84和Web墨卡托，其他坐标系返回false
+func boundToWebMercator(srs string, b [4]float64) ([4]float64, bool) {
+	switch srs {
+	case GMERC_PROJ4:
+		return b, true
+	case WGS84_PROJ4:
+		return [4]float64{
+			webmercator.LonToX(b[0]), webmercator.LatToY(b[1]),
+			webmercator.LonToX(b[2]), webmercator.LatToY(b[3]),
+		}, true
+	default:
+		return b, false
+	}
+}
@@ -0,0 +1,143 @@
+package tile
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// removingExporter 支持删除瓦片的模拟导出器
+type removingExporter struct {
+	MockExporter
+	Removed []string
+	rmu     sync.Mutex
+}
+
+func (e *removingExporter) RemoveTile(tile *Tile, path string) error {
+	e.rmu.Lock()
+	defer e.rmu.Unlock()
+	e.Removed = append(e.Removed, path)
+	return nil
+}
+
+// TestTiler_dirtyTasks 测试变化区域影响的瓦片计算
+func TestTiler_dirtyTasks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		dirty    []*[4]float64
+		layers   map[string]*LayerOptions
+		expected []tileTask
+	}{
+		{
+			name:     "远离瓦片边界",
+			dirty:    []*[4]float64{{90, 40, 91, 41}},
+			expected: []tileTask{{z: 1, x: 1, y: 0}},
+		},
+		{
+			name:     "缓冲区覆盖相邻瓦片",
+			dirty:    []*[4]float64{{-0.1, 10, -0.05, 11}},
+			expected: []tileTask{{z: 1, x: 0, y: 0}, {z: 1, x: 1, y: 0}},
+		},
+		{
+			name:     "多个区域去重",
+			dirty:    []*[4]float64{{90, 40, 91, 41}, {92, 40, 93, 41}, nil},
+			expected: []tileTask{{z: 1, x: 1, y: 0}},
+		},
+		{
+			name:     "图层缓冲区大于全局缓冲区",
+			dirty:    []*[4]float64{{-10, 60, -9, 61}},
+			layers:   map[string]*LayerOptions{"roads": {Buffer: 1024}},
+			expected: []tileTask{{z: 1, x: 0, y: 0}, {z: 1, x: 1, y: 0}},
+		},
+		{
+			name:     "图层瓦片范围小于全局范围",
+			dirty:    []*[4]float64{{-10, 60, -9, 61}},
+			layers:   map[string]*LayerOptions{"roads": {Extent: 256}},
+			expected: []tileTask{{z: 1, x: 0, y: 0}, {z: 1, x: 1, y: 0}},
+		},
+		{
+			name:     "缓冲区超过一个瓦片",
+			dirty:    []*[4]float64{{-179, 60, -178, 61}},
+			layers:   map[string]*LayerOptions{"roads": {Buffer: 6144}},
+			expected: []tileTask{{z: 1, x: 0, y: 0}, {z: 1, x: 1, y: 0}, {z: 1, x: 0, y: 1}, {z: 1, x: 1, y: 1}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tiler := NewTiler(&Config{
+				MinZoom:      1,
+				MaxZoom:      1,
+				TileExtent:   4096,
+				TileBuffer:   64,
+				LayerOptions: tc.layers,
+			})
+			defer tiler.Stop()
+
+			tasks := tiler.dirtyTasks(tc.dirty)
+			if len(tasks) != len(tc.expected) {
+				t.Fatalf("dirtyTasks() 数量 = %v, want %v", len(tasks), len(tc.expected))
+			}
+			for i, task := range tasks {
+				if *task != tc.expected[i] {
+					t.Errorf("dirtyTasks()[%d] = %+v, want %+v", i, *task, tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+// TestTiler_Retile 测试增量重新生成
+func TestTiler_Retile(t *testing.T) {
+	dirty := []*[4]float64{{100, 40, 101, 41}}
+
+	t.Run("有数据的瓦片重新导出", func(t *testing.T) {
+		layer := &Layer{
+			Name:     "test_layer",
+			Features: []*geom.Feature{{Geometry: basic.Point{0, 0}}},
+		}
+		exporter := &removingExporter{}
+		tiler := NewTiler(&Config{
+			Provider: &MockProvider{layers: []*Layer{layer}, srid: 4326},
+			Exporter: exporter,
+			MinZoom:  0,
+			MaxZoom:  2,
+		})
+
+		if err := tiler.Retile(dirty); err != nil {
+			t.Fatalf("Retile() 错误 = %v", err)
+		}
+		if got := len(exporter.GetSavedTiles()); got != 3 {
+			t.Errorf("导出瓦片数量 = %v, want 3", got)
+		}
+		if len(exporter.Removed) != 0 {
+			t.Errorf("不应删除瓦片, 删除了 %v", exporter.Removed)
+		}
+	})
+
+	t.Run("没有数据的瓦片被删除", func(t *testing.T) {
+		dir := t.TempDir()
+		exporter := &removingExporter{}
+		tiler := NewTiler(&Config{
+			Provider:  &MockProvider{srid: 4326},
+			Exporter:  exporter,
+			MinZoom:   1,
+			MaxZoom:   1,
+			OutputDir: dir,
+		})
+
+		if err := tiler.Retile(dirty); err != nil {
+			t.Fatalf("Retile() 错误 = %v", err)
+		}
+		if len(exporter.GetSavedTiles()) != 0 {
+			t.Errorf("不应导出瓦片")
+		}
+		expected := filepath.Join(dir, "1/1/0.test")
+		if len(exporter.Removed) != 1 || exporter.Removed[0] != expected {
+			t.Errorf("删除的瓦片 = %v, want [%v]", exporter.Removed, expected)
+		}
+	})
+}
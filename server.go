@@ -0,0 +1,453 @@
+package tile
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultServerCacheSize TileServer默认缓存的瓦片数
+const DefaultServerCacheSize = 1024
+
+// ServerOptions TileServer配置
+type ServerOptions struct {
+	// CacheSize 缓存的编码后瓦片数，0使用DefaultServerCacheSize，小于0不缓存
+	CacheSize int
+	// Encoders 扩展名到编码器的映射，为nil时支持mvt、geojson和svg
+	Encoders map[string]Encoder
+	// Format TileJSON中瓦片URL使用的扩展名(默认mvt)
+	Format string
+	// CacheControl 不为空时作为瓦片响应的Cache-Control头
+	CacheControl string
+}
+
+// DefaultServerOptions 默认TileServer配置
+var DefaultServerOptions = ServerOptions{
+	CacheSize: DefaultServerCacheSize,
+	Format:    "mvt",
+}
+
+// serverContentTypes 各扩展名的Content-Type
+var serverContentTypes = map[string]string{
+	"mvt":     "application/vnd.mapbox-vector-tile",
+	"pbf":     "application/vnd.mapbox-vector-tile",
+	"geojson": "application/geo+json",
+	"svg":     "image/svg+xml",
+}
+
+// TileServer 按请求实时生成瓦片的HTTP处理器
+// 路径/{z}/{x}/{y}.{ext}返回瓦片，处理流程与Tiler生成瓦片相同(包括图层选项、超级别生成和瓦片预算)，
+// 路径/tiles.json返回TileJSON。编码后的瓦片保存在LRU缓存中，
+// 响应支持ETag/If-None-Match和gzip压缩；没有数据或不在Config.Bound范围内的瓦片返回204
+type TileServer struct {
+	options ServerOptions
+	// tiler 提供配置、网格和TileJSON，每个请求使用其fork
+	tiler *Tiler
+	cache *tileCache
+	// sem 限制同时生成的瓦片数(Config.Concurrency)
+	sem chan struct{}
+}
+
+// NewTileServer 使用默认配置创建TileServer
+func NewTileServer(config *Config) (*TileServer, error) {
+	return NewTileServerWithOptions(config, DefaultServerOptions)
+}
+
+// NewTileServerWithOptions 创建TileServer，config与Tiler的配置相同，Exporter和Resume等批量生成的选项被忽略
+func NewTileServerWithOptions(config *Config, options ServerOptions) (*TileServer, error) {
+	if config == nil || (config.Provider == nil && config.StreamingProvider == nil) {
+		return nil, ErrNoProvider
+	}
+	if options.CacheSize == 0 {
+		options.CacheSize = DefaultServerCacheSize
+	}
+	if options.Format == "" {
+		options.Format = DefaultServerOptions.Format
+	}
+	if options.Encoders == nil {
+		options.Encoders = map[string]Encoder{
+			"mvt":     NewMVTExporter(),
+			"geojson": NewGeoJSONExporter(),
+			"svg":     NewSVGExporter(),
+		}
+	}
+
+	m := NewTiler(config)
+	zooms := m.getZoomLevels()
+	m.overzoom = m.planOverzoom(zooms)
+	if err := m.checkMatrixSet(zooms); err != nil {
+		m.cancel()
+		return nil, err
+	}
+	return &TileServer{
+		options: options,
+		tiler:   m,
+		cache:   newTileCache(options.CacheSize),
+		sem:     make(chan struct{}, m.config.Concurrency),
+	}, nil
+}
+
+// ServeHTTP 处理瓦片和TileJSON请求
+func (s *TileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if path == TileJSONFileName {
+		s.serveTileJSON(w, r)
+		return
+	}
+	task, ext, ok := parseTilePath(path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	encoder, ok := s.options.Encoders[ext]
+	if !ok || !s.tiler.servesZoom(task.z) {
+		http.NotFound(w, r)
+		return
+	}
+	s.serveTile(w, r, task, ext, encoder)
+}
+
+// serveTile 返回缓存的瓦片，未缓存时生成
+func (s *TileServer) serveTile(w http.ResponseWriter, r *http.Request, task *tileTask, ext string, encoder Encoder) {
+	key := fmt.Sprintf("%d/%d/%d.%s", task.z, task.x, task.y, ext)
+	entry, ok := s.cache.get(key)
+	if !ok {
+		var err error
+		entry, err = s.render(r.Context(), task, encoder)
+		if err != nil {
+			if r.Context().Err() != nil {
+				return // 客户端已断开
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.cache.add(key, entry)
+	}
+
+	h := w.Header()
+	h.Set("Vary", "Accept-Encoding")
+	if s.options.CacheControl != "" {
+		h.Set("Cache-Control", s.options.CacheControl)
+	}
+	if len(entry.data) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	data, etag := entry.data, entry.etag
+	if entry.gzipped != nil && acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		data, etag = entry.gzipped, entry.etag[:len(entry.etag)-1]+`-gzip"`
+		h.Set("Content-Encoding", "gzip")
+	}
+	h.Set("ETag", etag)
+	if etagMatch(r.Header.Get("If-None-Match"), entry.etag, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if ct, ok := serverContentTypes[ext]; ok {
+		h.Set("Content-Type", ct)
+	} else {
+		h.Set("Content-Type", "application/octet-stream")
+	}
+	h.Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+// render 生成并编码瓦片，没有数据时返回的data为空
+func (s *TileServer) render(ctx context.Context, task *tileTask, encoder Encoder) (*cachedTile, error) {
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	m := s.tiler.fork(ctx)
+	defer m.cancel()
+	layers, err := m.renderLayers(task)
+	if err != nil {
+		return nil, err
+	}
+	if len(layers) == 0 {
+		return &cachedTile{}, nil
+	}
+	data, err := encoder.Encode(layers, m.newTile(task))
+	if err != nil {
+		return nil, newTileError(task, StageExport, err)
+	}
+	s.tiler.layers.add(task.z, layers)
+	return newCachedTile(data), nil
+}
+
+// serveTileJSON 返回TileJSON，未配置瓦片URL时由请求地址生成。
+// vector_layers为已生成瓦片中出现的图层
+func (s *TileServer) serveTileJSON(w http.ResponseWriter, r *http.Request) {
+	tj := s.tiler.TileJSON()
+	if opts := s.tiler.config.TileJSON; opts == nil || len(opts.Tiles) == 0 {
+		tj.Tiles = []string{requestBaseURL(r) + "{z}/{x}/{y}." + s.options.Format}
+	}
+	data, err := json.Marshal(tj)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// fork 返回共享配置和网格的Tiler，使用独立的上下文和错误状态处理单个请求，
+// FailFast策略下的错误只取消该请求
+func (m *Tiler) fork(ctx context.Context) *Tiler {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Tiler{
+		config:    m.config,
+		ctx:       ctx,
+		cancel:    cancel,
+		errChan:   make(chan error, 1),
+		grid:      m.grid,
+		bbox:      m.bbox,
+		gridBound: m.gridBound,
+		report:    newReportCollector(),
+		overzoom:  m.overzoom,
+	}
+}
+
+// servesZoom 判断是否生成z级瓦片
+func (m *Tiler) servesZoom(z uint32) bool {
+	if m.overzoom != nil && z > m.overzoom.source && z <= m.overzoom.max {
+		return true
+	}
+	for _, zoom := range m.getZoomLevels() {
+		if uint32(zoom) == z {
+			return true
+		}
+	}
+	return false
+}
+
+// renderLayers 按processTile的流程处理单个瓦片并执行瓦片预算，不导出瓦片。
+// 超级别瓦片由源级别祖先瓦片处理后的图层逐级裁剪缩放得到。不在Config.Bound范围内时返回nil
+func (m *Tiler) renderLayers(task *tileTask) ([]*Layer, error) {
+	timer := stageTimer{}
+	var layers []*Layer
+	if m.overzoom != nil && task.z > m.overzoom.source {
+		if !m.overzoom.contains(task) {
+			return nil, nil
+		}
+		d := task.z - m.overzoom.source
+		var ok bool
+		layers, ok = m.tileLayers(&tileTask{z: m.overzoom.source, x: task.x >> d, y: task.y >> d}, timer)
+		if !ok {
+			return nil, m.renderError()
+		}
+		for z := m.overzoom.source + 1; z <= task.z; z++ {
+			shift := task.z - z
+			var err error
+			layers, err = m.overzoomChild(layers, (task.x>>shift)&1, (task.y>>shift)&1)
+			if err != nil {
+				return nil, newTileError(task, StageOverzoom, err)
+			}
+		}
+		layers = m.visibleLayers(task.z, layers)
+	} else {
+		minx, miny, maxx, maxy := m.TileBounds(task.z)
+		if task.x < minx || task.x > maxx || task.y < miny || task.y > maxy {
+			return nil, nil
+		}
+		var ok bool
+		layers, ok = m.tileLayers(task, timer)
+		if !ok {
+			return nil, m.renderError()
+		}
+	}
+
+	if m.budgetEnabled() && len(layers) > 0 {
+		var err error
+		layers, _, err = m.fitBudget(m.newTile(task), layers)
+		if err != nil {
+			return nil, newTileError(task, StageBudget, err)
+		}
+	}
+	return layers, nil
+}
+
+// renderError 返回放弃瓦片的原因
+func (m *Tiler) renderError() error {
+	if m.firstError != nil {
+		return m.firstError
+	}
+	m.tileErrorsMu.Lock()
+	defer m.tileErrorsMu.Unlock()
+	if n := len(m.tileErrors); n > 0 {
+		return m.tileErrors[n-1]
+	}
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+	return ErrTileAborted
+}
+
+// parseTilePath 解析"{z}/{x}/{y}.{ext}"
+func parseTilePath(path string) (*tileTask, string, bool) {
+	parts := strings.Split(path, "/")
+	if len(parts) != 3 {
+		return nil, "", false
+	}
+	name, ext, ok := strings.Cut(parts[2], ".")
+	if !ok {
+		return nil, "", false
+	}
+	var v [3]uint32
+	for i, s := range []string{parts[0], parts[1], name} {
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, "", false
+		}
+		v[i] = uint32(n)
+	}
+	return &tileTask{z: v[0], x: v[1], y: v[2]}, ext, true
+}
+
+// requestBaseURL 返回请求所在目录的URL，以"/"结尾。
+// 使用RequestURI，处理器挂在http.StripPrefix下时也能得到完整路径
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	path := r.URL.Path
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		path = u.Path
+	}
+	return scheme + "://" + r.Host + path[:strings.LastIndex(path, "/")+1]
+}
+
+// acceptsGzip 判断Accept-Encoding是否接受gzip
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.TrimSpace(coding)
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// etagMatch 判断If-None-Match是否包含任一ETag，使用弱比较
+func etagMatch(header string, etags ...string) bool {
+	for _, part := range strings.Split(header, ",") {
+		tag := strings.TrimPrefix(strings.TrimSpace(part), "W/")
+		if tag == "*" {
+			return true
+		}
+		for _, etag := range etags {
+			if tag == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cachedTile 缓存的编码后瓦片，没有数据的瓦片data为空
+type cachedTile struct {
+	data []byte
+	// gzipped gzip压缩后的数据，data已经压缩时为nil
+	gzipped []byte
+	etag    string
+}
+
+// newCachedTile 计算ETag和压缩后的数据
+func newCachedTile(data []byte) *cachedTile {
+	sum := md5.Sum(data)
+	entry := &cachedTile{data: data, etag: `"` + hex.EncodeToString(sum[:]) + `"`}
+	if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		if gz, err := gzipData(data); err == nil {
+			entry.gzipped = gz
+		}
+	}
+	return entry
+}
+
+// tileCache 按瓦片数限制大小的LRU缓存，可并发使用
+type tileCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// tileCacheItem 缓存链表中的元素
+type tileCacheItem struct {
+	key   string
+	entry *cachedTile
+}
+
+// newTileCache 创建最多保存size个瓦片的缓存，size小于等于0时不缓存
+func newTileCache(size int) *tileCache {
+	return &tileCache{size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get 返回缓存的瓦片并标记为最近使用
+func (c *tileCache) get(key string) (*cachedTile, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*tileCacheItem).entry, true
+}
+
+// add 缓存瓦片，超出大小时淘汰最久未使用的瓦片
+func (c *tileCache) add(key string, entry *cachedTile) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value.(*tileCacheItem).entry = entry
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&tileCacheItem{key: key, entry: entry})
+	for c.ll.Len() > c.size {
+		last := c.ll.Back()
+		c.ll.Remove(last)
+		delete(c.items, last.Value.(*tileCacheItem).key)
+	}
+}
+
+// len 返回缓存的瓦片数
+func (c *tileCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
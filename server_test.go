@@ -0,0 +1,268 @@
+package tile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// serverGet 发送GET请求，headers为成对的头名和值
+func serverGet(s http.Handler, path string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+// TestTileServer 测试瓦片、空瓦片、缓存、ETag和gzip
+func TestTileServer(t *testing.T) {
+	provider := &countingProvider{MockProvider: MockProvider{srid: 4326, layers: []*Layer{{Name: "cities", Features: []*geom.Feature{
+		{Geometry: basic.Point{116.4, 39.9}, Properties: map[string]interface{}{"name": "北京"}},
+	}}}}}
+	server, err := NewTileServer(&Config{Provider: provider, MinZoom: 1, MaxZoom: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tile := NewTileLatLong(3, 39.9, 116.4)
+	path := fmt.Sprintf("/3/%d/%d.geojson", tile.X, tile.Y)
+	w := serverGet(server, path)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/geo+json" || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("GET %s = %d %v", path, w.Code, w.Header())
+	}
+	var fc struct {
+		Features []struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &fc); err != nil || len(fc.Features) != 1 || fc.Features[0].Properties["name"] != "北京" {
+		t.Errorf("瓦片内容 = %s, %v", w.Body.Bytes(), err)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("缺少ETag")
+	}
+
+	// 缓存命中时不再查询Provider
+	queries := provider.zooms[3]
+	if w := serverGet(server, path, "If-None-Match", etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("If-None-Match = %d, want 304", w.Code)
+	}
+	if provider.zooms[3] != queries {
+		t.Errorf("Provider查询次数 = %d, want %d", provider.zooms[3], queries)
+	}
+
+	gz := serverGet(server, path, "Accept-Encoding", "br, gzip")
+	if gz.Header().Get("Content-Encoding") != "gzip" || gz.Header().Get("ETag") == etag || gz.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("gzip响应头 = %v", gz.Header())
+	}
+	if body := gunzip(t, gz.Body.Bytes()); string(body) != w.Body.String() {
+		t.Errorf("解压后 = %s, want %s", body, w.Body.String())
+	}
+	if w := serverGet(server, path, "Accept-Encoding", "gzip;q=0"); w.Header().Get("Content-Encoding") != "" {
+		t.Error("q=0时不应压缩")
+	}
+	if w := serverGet(server, path, "Accept-Encoding", "gzip", "If-None-Match", gz.Header().Get("ETag")); w.Code != http.StatusNotModified {
+		t.Errorf("gzip If-None-Match = %d, want 304", w.Code)
+	}
+
+	if w := serverGet(server, fmt.Sprintf("/3/%d/%d.mvt", tile.X, tile.Y)); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/vnd.mapbox-vector-tile" {
+		t.Errorf("mvt = %d %v", w.Code, w.Header())
+	}
+	if w := serverGet(server, fmt.Sprintf("/3/%d/%d.svg", tile.X, tile.Y)); w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "<?xml") {
+		t.Errorf("svg = %d %.20s", w.Code, w.Body.String())
+	}
+	if w := serverGet(server, fmt.Sprintf("/3/%d/%d.mvt", tile.X+3, tile.Y)); w.Code != http.StatusNoContent {
+		t.Errorf("空瓦片 = %d, want 204", w.Code)
+	}
+	if n := server.cache.len(); n != 4 {
+		t.Errorf("缓存瓦片数 = %d, want 4", n)
+	}
+
+	for _, p := range []string{"/0/0/0.mvt", "/4/0/0.mvt", "/1/0/0.png", "/1/0/a.mvt", "/1/0.mvt", "/1/0/0"} {
+		if w := serverGet(server, p); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", p, w.Code)
+		}
+	}
+	r := httptest.NewRequest(http.MethodPost, path, nil)
+	w = httptest.NewRecorder()
+	if server.ServeHTTP(w, r); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d, want 405", w.Code)
+	}
+}
+
+// TestTileServer_TileJSON 测试TileJSON中的瓦片URL和已生成的图层
+func TestTileServer_TileJSON(t *testing.T) {
+	server, err := NewTileServer(&Config{Provider: sinkProvider(t), MaxZoom: 3, OverzoomMaxZoom: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tile := NewTileLatLong(2, 39.9, 116.4)
+	serverGet(server, fmt.Sprintf("/2/%d/%d.mvt", tile.X, tile.Y))
+
+	mux := http.NewServeMux()
+	mux.Handle("/tiles/", http.StripPrefix("/tiles", server))
+	w := serverGet(mux, "/tiles/tiles.json", "X-Forwarded-Proto", "https")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("GET tiles.json = %d", w.Code)
+	}
+	var tj TileJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &tj); err != nil {
+		t.Fatal(err)
+	}
+	if len(tj.Tiles) != 1 || tj.Tiles[0] != "https://example.com/tiles/{z}/{x}/{y}.mvt" {
+		t.Errorf("Tiles = %v", tj.Tiles)
+	}
+	if tj.MinZoom != 0 || tj.MaxZoom != 5 {
+		t.Errorf("级别 = %d-%d, want 0-5", tj.MinZoom, tj.MaxZoom)
+	}
+	if len(tj.VectorLayers) != 1 || tj.VectorLayers[0].ID != "cities" || tj.VectorLayers[0].MinZoom != 2 {
+		t.Errorf("VectorLayers = %+v", tj.VectorLayers)
+	}
+
+	server, err = NewTileServer(&Config{Provider: sinkProvider(t), TileJSON: &TileJSONOptions{Tiles: []string{"https://cdn.example.com/{z}/{x}/{y}.pbf"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(serverGet(server, "/tiles.json").Body.Bytes(), &tj); err != nil || tj.Tiles[0] != "https://cdn.example.com/{z}/{x}/{y}.pbf" {
+		t.Errorf("Tiles = %v, %v", tj.Tiles, err)
+	}
+}
+
+// TestTileServer_Overzoom 测试超级别瓦片由源级别数据生成，结果与Tiler一致
+func TestTileServer_Overzoom(t *testing.T) {
+	provider := &countingProvider{MockProvider: MockProvider{srid: 4326, layers: []*Layer{{Name: "districts", Features: []*geom.Feature{
+		{Geometry: basic.Polygon{{{116.3, 39.8}, {116.5, 39.8}, {116.5, 40.0}, {116.3, 40.0}, {116.3, 39.8}}}, Properties: map[string]interface{}{"name": "北京"}},
+	}}}}}
+	config := func(exporter Exporter) *Config {
+		return &Config{Provider: provider, Exporter: exporter, MaxZoom: 3, OverzoomMaxZoom: 6, Bound: &[4]float64{116, 39, 117, 41}}
+	}
+	exporter := &MockExporter{}
+	if err := NewTiler(config(exporter)).Tiler(); err != nil {
+		t.Fatal(err)
+	}
+	exported := map[string]bool{}
+	for _, saved := range exporter.GetSavedTiles() {
+		exported[saved.Tile.ToString()] = true
+	}
+
+	server, err := NewTileServer(config(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.zooms = nil
+	for z := uint32(4); z <= 6; z++ {
+		tile := NewTileLatLong(z, 39.9, 116.4)
+		for _, x := range []uint32{tile.X, tile.X + 3} {
+			want := http.StatusNoContent
+			if exported[NewTile(z, x, tile.Y).ToString()] {
+				want = http.StatusOK
+			}
+			if w := serverGet(server, fmt.Sprintf("/%d/%d/%d.geojson", z, x, tile.Y)); w.Code != want {
+				t.Errorf("GET /%d/%d/%d = %d, want %d", z, x, tile.Y, w.Code, want)
+			}
+		}
+	}
+	if len(provider.zooms) != 1 || provider.zooms[3] == 0 {
+		t.Errorf("Provider查询级别 = %v, want 3", provider.zooms)
+	}
+	if w := serverGet(server, "/7/0/0.mvt"); w.Code != http.StatusNotFound {
+		t.Errorf("超出级别 = %d, want 404", w.Code)
+	}
+}
+
+// TestTileServer_Error 测试Provider错误只影响当前请求，错误结果不缓存
+func TestTileServer_Error(t *testing.T) {
+	provider := &mockStreamingProvider{MockProvider: MockProvider{srid: 4326}, err: errors.New("connection refused")}
+	server, err := NewTileServer(&Config{Provider: provider, MaxZoom: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if w := serverGet(server, "/1/0/0.mvt"); w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "connection refused") {
+			t.Errorf("第%d次请求 = %d %s, want 500", i+1, w.Code, w.Body.String())
+		}
+	}
+	provider.err = nil
+	if w := serverGet(server, "/1/0/0.mvt"); w.Code != http.StatusNoContent {
+		t.Errorf("恢复后 = %d, want 204", w.Code)
+	}
+	if server.cache.len() != 1 {
+		t.Errorf("缓存瓦片数 = %d, want 1", server.cache.len())
+	}
+
+	if _, err := NewTileServer(&Config{}); !errors.Is(err, ErrNoProvider) {
+		t.Errorf("NewTileServer() 错误 = %v, want %v", err, ErrNoProvider)
+	}
+}
+
+// TestTileServer_Concurrent 测试并发请求
+func TestTileServer_Concurrent(t *testing.T) {
+	server, err := NewTileServerWithOptions(&Config{Provider: sinkProvider(t), MaxZoom: 4, Concurrency: 2}, ServerOptions{CacheSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	for z := uint32(0); z <= 4; z++ {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(z uint32) {
+				defer wg.Done()
+				tile := NewTileLatLong(z, 39.9, 116.4)
+				resp, err := http.Get(fmt.Sprintf("%s/%d/%d/%d.mvt", ts.URL, z, tile.X, tile.Y))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer resp.Body.Close()
+				if body, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || len(body) == 0 {
+					t.Errorf("z=%d 状态 = %d", z, resp.StatusCode)
+				}
+			}(z)
+		}
+	}
+	wg.Wait()
+	if n := server.cache.len(); n != 5 {
+		t.Errorf("缓存瓦片数 = %d, want 5", n)
+	}
+}
+
+// TestTileCache 测试LRU淘汰
+func TestTileCache(t *testing.T) {
+	c := newTileCache(2)
+	c.add("a", &cachedTile{etag: "a"})
+	c.add("b", &cachedTile{etag: "b"})
+	c.get("a")
+	c.add("c", &cachedTile{etag: "c"})
+	if _, ok := c.get("b"); ok {
+		t.Error("最久未使用的b应被淘汰")
+	}
+	if e, ok := c.get("a"); !ok || e.etag != "a" {
+		t.Error("a应保留")
+	}
+	if c.len() != 2 {
+		t.Errorf("len() = %d, want 2", c.len())
+	}
+
+	c = newTileCache(-1)
+	c.add("a", &cachedTile{})
+	if c.len() != 0 {
+		t.Error("大小小于0时不应缓存")
+	}
+}
@@ -0,0 +1,738 @@
+package tile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	geom "github.com/flywave/go-geom"
+	gen "github.com/flywave/go-geom/general"
+	"github.com/flywave/go-vector-tiler/basic"
+	"github.com/flywave/go-vector-tiler/util"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// Shapefile几何类型
+const (
+	shpNull        = 0
+	shpPoint       = 1
+	shpPolyLine    = 3
+	shpPolygon     = 5
+	shpMultiPoint  = 8
+	shpPointZ      = 11
+	shpPolyLineZ   = 13
+	shpPolygonZ    = 15
+	shpMultiPointZ = 18
+	shpPointM      = 21
+	shpPolyLineM   = 23
+	shpPolygonM    = 25
+	shpMultiPointM = 28
+)
+
+// shpHeaderSize .shp和.shx文件头的字节数
+const shpHeaderSize = 100
+
+// ShapefileOptions Shapefile数据源选项
+type ShapefileOptions struct {
+	// LayerName 图层名，为空时取文件名(不含扩展名)
+	LayerName string
+	// Encoding DBF属性的字符编码，如"UTF-8"、"GBK"、"1252"。
+	// 为空时依次由.cpg文件和DBF文件头的语言驱动标识确定，都没有时按UTF-8处理
+	Encoding string
+	// SRID 坐标的空间参考，为0时由.prj文件确定，没有.prj文件时为WGS84；
+	// 非0时忽略.prj文件，可用于.prj无法识别的数据
+	SRID uint64
+}
+
+// ShapefileProvider 读取ESRI Shapefile的Provider
+// 读取.shp几何、.shx索引、.dbf属性和.prj坐标系，要素读入内存并建立空间索引。
+// 多边形按环的方向区分外环(顺时针)和洞(逆时针)，Z和M值被忽略，要素ID为记录号
+type ShapefileProvider struct {
+	*MemoryProvider
+}
+
+// NewShapefileProvider 由.shp文件或包含.shp文件的目录创建Provider
+func NewShapefileProvider(paths ...string) (*ShapefileProvider, error) {
+	return NewShapefileProviderWithOptions(ShapefileOptions{}, paths...)
+}
+
+// NewShapefileProviderWithOptions 使用自定义选项创建ShapefileProvider
+// 每个.shp文件对应一个图层，所有文件的坐标系必须相同
+func NewShapefileProviderWithOptions(options ShapefileOptions, paths ...string) (*ShapefileProvider, error) {
+	files, err := shapefileFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []*Layer
+	srid := options.SRID
+	for i, file := range files {
+		layer, fileSRID, err := readShapefile(file, options)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			srid = fileSRID
+		} else if fileSRID != srid {
+			return nil, fmt.Errorf("Shapefile %s 的坐标系(%d)与其他文件(%d)不同: %w", file, fileSRID, srid, ErrUnsupportedProjection)
+		}
+		layers = append(layers, layer)
+	}
+	if srid == 0 {
+		srid = util.WGS84
+	}
+
+	mp, err := NewMemoryProvider(srid, layers)
+	if err != nil {
+		return nil, err
+	}
+	return &ShapefileProvider{MemoryProvider: mp}, nil
+}
+
+// shapefileFiles 展开目录，返回需要读取的.shp文件
+func shapefileFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取Shapefile失败: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取Shapefile目录失败: %w", err)
+		}
+		var names []string
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".shp") {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			files = append(files, filepath.Join(path, name))
+		}
+	}
+	return files, nil
+}
+
+// shapefileSibling 返回与.shp文件同名的其他扩展名文件，扩展名大小写与.shp一致
+func shapefileSibling(path, ext string) string {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	if strings.ToUpper(filepath.Ext(path)) == filepath.Ext(path) {
+		ext = strings.ToUpper(ext)
+	}
+	return base + ext
+}
+
+// readShapefile 读取一个Shapefile为图层，同时返回其坐标系
+func readShapefile(path string, options ShapefileOptions) (*Layer, uint64, error) {
+	srid := options.SRID
+	if srid == 0 {
+		prj, err := os.ReadFile(shapefileSibling(path, ".prj"))
+		switch {
+		case err == nil:
+			if srid, err = prjSRID(string(prj)); err != nil {
+				return nil, 0, fmt.Errorf("Shapefile %s: %w", path, err)
+			}
+		case errors.Is(err, os.ErrNotExist):
+			srid = util.WGS84
+		default:
+			return nil, 0, fmt.Errorf("读取Shapefile %s 的.prj文件失败: %w", path, err)
+		}
+	}
+
+	shp, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取Shapefile失败: %w", err)
+	}
+	shx, err := os.ReadFile(shapefileSibling(path, ".shx"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, 0, fmt.Errorf("读取Shapefile %s 的.shx文件失败: %w", path, err)
+	}
+	records, err := shpRecords(shp, shx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Shapefile %s: %w", path, err)
+	}
+
+	var table *dbfTable
+	dbf, err := os.ReadFile(shapefileSibling(path, ".dbf"))
+	switch {
+	case err == nil:
+		enc, err := shapefileEncoding(path, options.Encoding, dbf)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Shapefile %s: %w", path, err)
+		}
+		if table, err = readDBF(dbf, enc); err != nil {
+			return nil, 0, fmt.Errorf("Shapefile %s 的.dbf文件: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, 0, fmt.Errorf("读取Shapefile %s 的.dbf文件失败: %w", path, err)
+	}
+
+	name := options.LayerName
+	if name == "" {
+		base := filepath.Base(path)
+		name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	layer := &Layer{Name: name, SRID: int(srid)}
+
+	for i, content := range records {
+		properties := map[string]interface{}{}
+		if table != nil && i < table.count {
+			var deleted bool
+			if properties, deleted = table.record(i); deleted {
+				continue
+			}
+		}
+		g, err := shpGeometry(content)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Shapefile %s 第 %d 条记录: %w", path, i+1, err)
+		}
+		layer.Features = append(layer.Features, &geom.Feature{
+			ID:         uint64(i + 1),
+			Type:       "Feature",
+			Geometry:   g,
+			Properties: properties,
+		})
+	}
+	return layer, srid, nil
+}
+
+// shpRecords 返回.shp文件中各记录的内容(不含记录头)
+// 有.shx文件时按其中的偏移量读取，否则顺序读取
+func shpRecords(shp, shx []byte) ([][]byte, error) {
+	if len(shp) < shpHeaderSize || binary.BigEndian.Uint32(shp[0:4]) != 9994 {
+		return nil, fmt.Errorf("%w: 文件头无效", ErrInvalidShapefile)
+	}
+
+	record := func(offset int) ([]byte, int, error) {
+		if offset+8 > len(shp) {
+			return nil, 0, fmt.Errorf("%w: 记录超出文件范围", ErrInvalidShapefile)
+		}
+		length := int(binary.BigEndian.Uint32(shp[offset+4:offset+8])) * 2
+		end := offset + 8 + length
+		if length < 4 || end > len(shp) {
+			return nil, 0, fmt.Errorf("%w: 记录长度无效", ErrInvalidShapefile)
+		}
+		return shp[offset+8 : end], end, nil
+	}
+
+	var records [][]byte
+	if len(shx) >= shpHeaderSize {
+		for pos := shpHeaderSize; pos+8 <= len(shx); pos += 8 {
+			content, _, err := record(int(binary.BigEndian.Uint32(shx[pos:pos+4])) * 2)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, content)
+		}
+		return records, nil
+	}
+
+	// 文件长度以16位字为单位
+	end := min(int(binary.BigEndian.Uint32(shp[24:28]))*2, len(shp))
+	for offset := shpHeaderSize; offset < end; {
+		content, next, err := record(offset)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, content)
+		offset = next
+	}
+	return records, nil
+}
+
+// shpGeometry 解析一条记录的几何，空几何返回nil
+func shpGeometry(b []byte) (geom.Geometry, error) {
+	r := shpReader{b: b}
+	switch shapeType := r.int32(0); shapeType {
+	case shpNull:
+		return nil, nil
+	case shpPoint, shpPointZ, shpPointM:
+		x, y := r.point(4)
+		if r.err != nil {
+			return nil, r.err
+		}
+		return basic.Point{x, y}, nil
+	case shpMultiPoint, shpMultiPointZ, shpMultiPointM:
+		n := r.int32(36)
+		if r.err == nil && n < 0 {
+			return nil, fmt.Errorf("%w: 点数无效", ErrInvalidShapefile)
+		}
+		if !r.check(40, n*16) {
+			return nil, r.err
+		}
+		pts := make([][]float64, n)
+		for i := range pts {
+			x, y := r.point(40 + i*16)
+			pts[i] = []float64{x, y}
+		}
+		return gen.NewMultiPoint(pts), nil
+	case shpPolyLine, shpPolyLineZ, shpPolyLineM:
+		parts, err := r.parts()
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) == 1 {
+			return parts[0], nil
+		}
+		return basic.MultiLine(parts), nil
+	case shpPolygon, shpPolygonZ, shpPolygonM:
+		parts, err := r.parts()
+		if err != nil {
+			return nil, err
+		}
+		polygons := shpPolygons(parts)
+		if len(polygons) == 1 {
+			return polygons[0], nil
+		}
+		return basic.MultiPolygon(polygons), nil
+	default:
+		return nil, fmt.Errorf("%w: 不支持的几何类型 %d", ErrInvalidShapefile, shapeType)
+	}
+}
+
+// shpReader 按小端字节序读取记录内容，越界时记录错误
+type shpReader struct {
+	b   []byte
+	err error
+}
+
+func (r *shpReader) check(offset, size int) bool {
+	if r.err == nil && (offset < 0 || offset+size > len(r.b)) {
+		r.err = fmt.Errorf("%w: 记录内容不完整", ErrInvalidShapefile)
+	}
+	return r.err == nil
+}
+
+func (r *shpReader) int32(offset int) int {
+	if !r.check(offset, 4) {
+		return 0
+	}
+	return int(int32(binary.LittleEndian.Uint32(r.b[offset:])))
+}
+
+func (r *shpReader) point(offset int) (float64, float64) {
+	if !r.check(offset, 16) {
+		return 0, 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(r.b[offset:])),
+		math.Float64frombits(binary.LittleEndian.Uint64(r.b[offset+8:]))
+}
+
+// parts 读取折线或多边形的各部分
+func (r *shpReader) parts() ([]basic.Line, error) {
+	numParts, numPoints := r.int32(36), r.int32(40)
+	if r.err == nil && (numParts < 0 || numPoints < 0) {
+		return nil, fmt.Errorf("%w: 部分数或点数无效", ErrInvalidShapefile)
+	}
+	pointsOffset := 44 + numParts*4
+	if !r.check(pointsOffset, numPoints*16) {
+		return nil, r.err
+	}
+
+	parts := make([]basic.Line, 0, numParts)
+	for i := 0; i < numParts; i++ {
+		start, end := r.int32(44+i*4), numPoints
+		if i+1 < numParts {
+			end = r.int32(44 + (i+1)*4)
+		}
+		if start < 0 || start > end || end > numPoints {
+			return nil, fmt.Errorf("%w: 部分索引无效", ErrInvalidShapefile)
+		}
+		line := make(basic.Line, 0, end-start)
+		for j := start; j < end; j++ {
+			x, y := r.point(pointsOffset + j*16)
+			line = append(line, basic.Point{x, y})
+		}
+		if len(line) > 0 {
+			parts = append(parts, line)
+		}
+	}
+	return parts, r.err
+}
+
+// shpPolygons 将多边形记录的环组合为多边形
+// 顺时针的环为外环，逆时针的环为洞，洞归属于包含它的面积最小的外环，
+// 使嵌套的外环(如湖中岛)各自得到自己的洞；找不到外环的洞作为外环处理
+func shpPolygons(rings []basic.Line) []basic.Polygon {
+	var polygons []basic.Polygon
+	var holes []basic.Line
+	for _, ring := range rings {
+		if ringSignedArea(ring) <= 0 {
+			polygons = append(polygons, basic.Polygon{ring})
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+
+	areas := make([]float64, len(polygons))
+	for i, p := range polygons {
+		areas[i] = -ringSignedArea(p[0])
+	}
+	for _, hole := range holes {
+		owner := -1
+		for i := range areas {
+			if (owner < 0 || areas[i] < areas[owner]) && ringContains(polygons[i][0], hole[0]) {
+				owner = i
+			}
+		}
+		if owner < 0 {
+			polygons = append(polygons, basic.Polygon{hole})
+		} else {
+			polygons[owner] = append(polygons[owner], hole)
+		}
+	}
+	return polygons
+}
+
+// ringContains 使用射线法判断点是否在环内
+func ringContains(ring basic.Line, pt basic.Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > pt[1]) != (b[1] > pt[1]) &&
+			pt[0] < (b[0]-a[0])*(pt[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// esriNames ESRI WKT中没有EPSG代码的常用坐标系名称
+var esriNames = map[string]uint64{
+	"GCS_WGS_1984": util.WGS84,
+	"GCS_CHINA_GEODETIC_COORDINATE_SYSTEM_2000": 4490,
+	"GCS_RGF_1993":                           4171,
+	"GCS_ETRS_1989":                          4258,
+	"GCS_NORTH_AMERICAN_1983":                4269,
+	"WGS_1984_WEB_MERCATOR_AUXILIARY_SPHERE": util.WebMercator,
+	"WGS_1984_WEB_MERCATOR":                  util.WebMercator,
+	"RGF_1993_LAMBERT_93":                    2154,
+	"BRITISH_NATIONAL_GRID":                  27700,
+}
+
+// esriZones ESRI WKT中按带号命名的坐标系，带号或中央经线从first起每隔step对应一个连续的EPSG代码
+var esriZones = []struct {
+	name        *regexp.Regexp
+	first, last uint64
+	step        uint64
+	srid        uint64
+}{
+	{regexp.MustCompile(`^WGS_1984_UTM_ZONE_(\d+)N$`), 1, 60, 1, 32601},
+	{regexp.MustCompile(`^WGS_1984_UTM_ZONE_(\d+)S$`), 1, 60, 1, 32701},
+	{regexp.MustCompile(`^ETRS_1989_UTM_ZONE_(\d+)N$`), 28, 38, 1, 25828},
+	{regexp.MustCompile(`^NAD_1983_UTM_ZONE_(\d+)N$`), 1, 23, 1, 26901},
+	{regexp.MustCompile(`^CGCS2000_GK_ZONE_(\d+)$`), 13, 23, 1, 4491},
+	{regexp.MustCompile(`^CGCS2000_GK_CM_(\d+)E$`), 75, 135, 6, 4502},
+	{regexp.MustCompile(`^CGCS2000_3_DEGREE_GK_ZONE_(\d+)$`), 25, 45, 1, 4513},
+	{regexp.MustCompile(`^CGCS2000_3_DEGREE_GK_CM_(\d+)E$`), 75, 135, 3, 4534},
+}
+
+// esriSRID 由ESRI坐标系名称确定EPSG代码
+func esriSRID(name string) (uint64, bool) {
+	if srid, ok := esriNames[name]; ok {
+		return srid, true
+	}
+	for _, zone := range esriZones {
+		m := zone.name.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		n, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil || n < zone.first || n > zone.last || (n-zone.first)%zone.step != 0 {
+			return 0, false
+		}
+		return zone.srid + (n-zone.first)/zone.step, true
+	}
+	return 0, false
+}
+
+// wktName 返回WKT根坐标系的名称
+func wktName(wkt string) string {
+	start := strings.IndexByte(wkt, '"')
+	if start < 0 || strings.ContainsAny(wkt[:start], "],") {
+		return ""
+	}
+	end := strings.IndexByte(wkt[start+1:], '"')
+	if end < 0 {
+		return ""
+	}
+	return strings.TrimSpace(wkt[start+1 : start+1+end])
+}
+
+// prjSRID 由.prj文件的WKT确定坐标系
+// 优先使用坐标系的EPSG代码，没有代码的ESRI WKT按常用坐标系名称识别，
+// 无法识别时需通过ShapefileOptions.SRID指定坐标系
+func prjSRID(wkt string) (uint64, error) {
+	s := strings.ToUpper(strings.TrimSpace(wkt))
+	code, ok := wktAuthority(s)
+	if !ok {
+		code, ok = esriSRID(wktName(s))
+	}
+	if ok {
+		if code == 900913 {
+			return util.WebMercator, nil
+		}
+		if err := basic.CheckSRID(code); err != nil {
+			return 0, fmt.Errorf("%w: %v，可通过ShapefileOptions.SRID(配置文件中的srid)指定坐标系", ErrUnsupportedProjection, err)
+		}
+		return code, nil
+	}
+	switch {
+	case strings.HasPrefix(s, "PROJCS"):
+		for _, name := range []string{"MERCATOR_AUXILIARY_SPHERE", "PSEUDO-MERCATOR", "PSEUDO_MERCATOR", "POPULAR VISUALISATION", `"EPSG","3857"`, `"EPSG","900913"`} {
+			if strings.Contains(s, name) {
+				return util.WebMercator, nil
+			}
+		}
+	case strings.HasPrefix(s, "GEOGCS"):
+		for _, name := range []string{"WGS_1984", "WGS 84", "WGS84", `"EPSG","4326"`} {
+			if strings.Contains(s, name) {
+				return util.WGS84, nil
+			}
+		}
+	}
+	name := wktName(strings.TrimSpace(wkt))
+	if name == "" {
+		name = strings.TrimSpace(wkt)
+	}
+	return 0, fmt.Errorf("%w: 无法由.prj识别坐标系%q，请通过ShapefileOptions.SRID(配置文件中的srid)指定", ErrUnsupportedProjection, name)
+}
+
+// wktAuthority 返回WKT根坐标系的EPSG代码，嵌套的基准面、椭球等的代码被忽略
+func wktAuthority(wkt string) (uint64, bool) {
+	const key = `AUTHORITY["EPSG",`
+	depth := 0
+	for i := 0; i < len(wkt); i++ {
+		switch wkt[i] {
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case 'A':
+			if depth != 1 || !strings.HasPrefix(wkt[i:], key) {
+				continue
+			}
+			rest := strings.TrimLeft(wkt[i+len(key):], ` "`)
+			end := strings.IndexAny(rest, `"]`)
+			if end < 0 {
+				return 0, false
+			}
+			code, err := strconv.ParseUint(strings.TrimSpace(rest[:end]), 10, 64)
+			return code, err == nil && code > 0
+		}
+	}
+	return 0, false
+}
+
+// dbfCodePages DBF语言驱动标识对应的代码页
+var dbfCodePages = map[byte]string{
+	0x01: "437", 0x02: "850", 0x03: "1252", 0x08: "865", 0x09: "437", 0x0A: "850",
+	0x0B: "437", 0x0D: "437", 0x0E: "850", 0x0F: "437", 0x10: "850", 0x11: "437",
+	0x12: "850", 0x13: "932", 0x14: "850", 0x15: "437", 0x16: "850", 0x17: "865",
+	0x18: "437", 0x19: "437", 0x1A: "850", 0x1B: "437", 0x1C: "863", 0x1D: "850",
+	0x1F: "852", 0x22: "852", 0x23: "852", 0x24: "860", 0x25: "850", 0x26: "866",
+	0x37: "850", 0x40: "852", 0x4D: "936", 0x4E: "949", 0x4F: "950", 0x50: "874",
+	0x57: "1252", 0x58: "1252", 0x59: "1252", 0x64: "852", 0x65: "866", 0x66: "865",
+	0x67: "861", 0x6A: "737", 0x6B: "857", 0x78: "950", 0x79: "949", 0x7A: "936",
+	0x7B: "932", 0x7C: "874", 0x7D: "1255", 0x7E: "1256", 0x87: "852", 0x88: "857",
+	0xC8: "1250", 0xC9: "1251", 0xCA: "1254", 0xCB: "1253", 0xCC: "1257",
+}
+
+// codePageNames 数字代码页对应的IANA字符集名称
+var codePageNames = map[string]string{
+	"437": "IBM437", "737": "IBM737", "850": "IBM850", "852": "IBM852", "857": "IBM857",
+	"860": "IBM860", "861": "IBM861", "863": "IBM863", "865": "IBM865", "866": "IBM866",
+	"874": "windows-874", "932": "Shift_JIS", "936": "GBK", "949": "EUC-KR", "950": "Big5",
+	"1250": "windows-1250", "1251": "windows-1251", "1252": "windows-1252", "1253": "windows-1253",
+	"1254": "windows-1254", "1255": "windows-1255", "1256": "windows-1256", "1257": "windows-1257",
+	"1258": "windows-1258", "65001": "UTF-8",
+}
+
+// shapefileEncoding 确定DBF属性的字符编码，UTF-8返回nil
+func shapefileEncoding(path, name string, dbf []byte) (encoding.Encoding, error) {
+	if name == "" {
+		if cpg, err := os.ReadFile(shapefileSibling(path, ".cpg")); err == nil {
+			name = strings.TrimSpace(string(cpg))
+		} else if len(dbf) > 29 {
+			name = dbfCodePages[dbf[29]]
+		}
+	}
+	return lookupEncoding(name)
+}
+
+// lookupEncoding 按名称或代码页查找字符编码，UTF-8和空名称返回nil
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	name = strings.TrimSpace(name)
+	// .cpg文件中常见"ANSI 1252"、"CP936"等写法
+	upper := strings.ToUpper(name)
+	for _, prefix := range []string{"ANSI ", "CP", "WINDOWS-"} {
+		if cp := strings.TrimPrefix(upper, prefix); cp != upper {
+			if _, err := strconv.Atoi(cp); err == nil {
+				name = cp
+			}
+		}
+	}
+	if n, ok := codePageNames[name]; ok {
+		name = n
+	}
+	if name == "" || strings.EqualFold(name, "UTF-8") || strings.EqualFold(name, "UTF8") {
+		return nil, nil
+	}
+
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		return nil, fmt.Errorf("不支持的字符编码 %q", name)
+	}
+	return enc, nil
+}
+
+// dbfField DBF字段描述
+type dbfField struct {
+	name     string
+	kind     byte
+	offset   int
+	length   int
+	decimals int
+}
+
+// dbfTable DBF属性表
+type dbfTable struct {
+	data         []byte
+	fields       []dbfField
+	count        int
+	headerLength int
+	recordLength int
+	decoder      *encoding.Decoder
+}
+
+// readDBF 解析DBF文件头和字段描述
+func readDBF(data []byte, enc encoding.Encoding) (*dbfTable, error) {
+	if len(data) < 32 {
+		return nil, fmt.Errorf("%w: 文件头无效", ErrInvalidShapefile)
+	}
+	t := &dbfTable{
+		data:         data,
+		count:        int(binary.LittleEndian.Uint32(data[4:8])),
+		headerLength: int(binary.LittleEndian.Uint16(data[8:10])),
+		recordLength: int(binary.LittleEndian.Uint16(data[10:12])),
+	}
+	if enc != nil {
+		t.decoder = enc.NewDecoder()
+	}
+
+	// 第一个字节为删除标记
+	offset := 1
+	for pos := 32; pos+32 <= len(data) && pos < t.headerLength && data[pos] != 0x0D; pos += 32 {
+		name := data[pos : pos+11]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		field := dbfField{
+			name:     strings.TrimSpace(t.decode(name)),
+			kind:     data[pos+11],
+			offset:   offset,
+			length:   int(data[pos+16]),
+			decimals: int(data[pos+17]),
+		}
+		t.fields = append(t.fields, field)
+		offset += field.length
+	}
+
+	if t.recordLength <= 0 || offset > t.recordLength {
+		return nil, fmt.Errorf("%w: 记录长度无效", ErrInvalidShapefile)
+	}
+	// 记录数与文件长度不符时只读取完整的记录
+	if n := max(0, (len(data)-t.headerLength)/t.recordLength); t.count > n {
+		t.count = n
+	}
+	return t, nil
+}
+
+// decode 将字节按DBF的字符编码转换为字符串
+func (t *dbfTable) decode(b []byte) string {
+	if t.decoder == nil {
+		return string(b)
+	}
+	s, err := t.decoder.Bytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return string(s)
+}
+
+// record 返回第i条记录的属性，以及记录是否已删除
+// 空值的字段不出现在属性中
+func (t *dbfTable) record(i int) (map[string]interface{}, bool) {
+	rec := t.data[t.headerLength+i*t.recordLength : t.headerLength+(i+1)*t.recordLength]
+	properties := make(map[string]interface{}, len(t.fields))
+	if rec[0] == '*' {
+		return properties, true
+	}
+	for _, f := range t.fields {
+		if v := t.value(f, rec[f.offset:f.offset+f.length]); v != nil {
+			properties[f.name] = v
+		}
+	}
+	return properties, false
+}
+
+// value 解析字段值
+func (t *dbfTable) value(f dbfField, raw []byte) interface{} {
+	switch f.kind {
+	case 'N', 'F':
+		s := strings.TrimSpace(string(raw))
+		if s == "" || strings.Trim(s, "*") == "" {
+			return nil
+		}
+		if f.kind == 'N' && f.decimals == 0 {
+			if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return v
+			}
+		}
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+		return nil
+	case 'L':
+		switch strings.TrimSpace(string(raw)) {
+		case "T", "t", "Y", "y":
+			return true
+		case "F", "f", "N", "n":
+			return false
+		}
+		return nil
+	case 'D':
+		s := strings.TrimSpace(string(raw))
+		if len(s) != 8 {
+			return nil
+		}
+		return s[0:4] + "-" + s[4:6] + "-" + s[6:8]
+	case 'I':
+		if len(raw) != 4 {
+			return nil
+		}
+		return int64(int32(binary.LittleEndian.Uint32(raw)))
+	default:
+		s := strings.TrimRight(t.decode(bytes.TrimRight(raw, "\x00")), " ")
+		if s == "" {
+			return nil
+		}
+		return s
+	}
+}
@@ -0,0 +1,357 @@
+package tile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flywave/go-vector-tiler/basic"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// shpPolyContent 生成折线或多边形记录的内容
+func shpPolyContent(shapeType int32, parts ...[][2]float64) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, shapeType)
+	binary.Write(&b, binary.LittleEndian, [4]float64{})
+	n := 0
+	for _, p := range parts {
+		n += len(p)
+	}
+	binary.Write(&b, binary.LittleEndian, int32(len(parts)))
+	binary.Write(&b, binary.LittleEndian, int32(n))
+	start := 0
+	for _, p := range parts {
+		binary.Write(&b, binary.LittleEndian, int32(start))
+		start += len(p)
+	}
+	for _, p := range parts {
+		binary.Write(&b, binary.LittleEndian, p)
+	}
+	return b.Bytes()
+}
+
+// shpPointContent 生成点记录的内容
+func shpPointContent(x, y float64) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, int32(shpPoint))
+	binary.Write(&b, binary.LittleEndian, [2]float64{x, y})
+	return b.Bytes()
+}
+
+// writeShapefile 写入.shp和.shx文件
+func writeShapefile(t *testing.T, base string, records ...[]byte) {
+	t.Helper()
+	header := func(length int) []byte {
+		h := make([]byte, shpHeaderSize)
+		binary.BigEndian.PutUint32(h[0:], 9994)
+		binary.BigEndian.PutUint32(h[24:], uint32(length/2))
+		binary.LittleEndian.PutUint32(h[28:], 1000)
+		return h
+	}
+
+	var body, index bytes.Buffer
+	for i, content := range records {
+		binary.Write(&index, binary.BigEndian, [2]int32{int32((shpHeaderSize + body.Len()) / 2), int32(len(content) / 2)})
+		binary.Write(&body, binary.BigEndian, [2]int32{int32(i + 1), int32(len(content) / 2)})
+		body.Write(content)
+	}
+	shp := append(header(shpHeaderSize+body.Len()), body.Bytes()...)
+	shx := append(header(shpHeaderSize+index.Len()), index.Bytes()...)
+	if err := os.WriteFile(base+".shp", shp, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base+".shx", shx, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// dbfColumn 测试用DBF字段
+type dbfColumn struct {
+	name     string
+	kind     byte
+	length   int
+	decimals int
+}
+
+// writeDBF 写入.dbf文件，records中以"*"开头的记录标记为删除
+func writeDBF(t *testing.T, path string, ldid byte, columns []dbfColumn, records [][]string) {
+	t.Helper()
+	recordLength := 1
+	for _, c := range columns {
+		recordLength += c.length
+	}
+	headerLength := 32 + 32*len(columns) + 1
+
+	var b bytes.Buffer
+	h := make([]byte, 32)
+	h[0] = 3
+	binary.LittleEndian.PutUint32(h[4:], uint32(len(records)))
+	binary.LittleEndian.PutUint16(h[8:], uint16(headerLength))
+	binary.LittleEndian.PutUint16(h[10:], uint16(recordLength))
+	h[29] = ldid
+	b.Write(h)
+	for _, c := range columns {
+		d := make([]byte, 32)
+		copy(d, c.name)
+		d[11] = c.kind
+		d[16] = byte(c.length)
+		d[17] = byte(c.decimals)
+		b.Write(d)
+	}
+	b.WriteByte(0x0D)
+
+	for _, r := range records {
+		flag := byte(' ')
+		if len(r) > 0 && r[0] == "*" {
+			flag, r = '*', r[1:]
+		}
+		b.WriteByte(flag)
+		for i, c := range columns {
+			v := make([]byte, c.length)
+			for j := range v {
+				v[j] = ' '
+			}
+			copy(v, r[i])
+			b.Write(v)
+		}
+	}
+	b.WriteByte(0x1A)
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestShapefileProvider 测试读取几何、属性和坐标系
+func TestShapefileProvider(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "areas")
+
+	// 外环顺时针，洞逆时针；第二个外环在洞之后出现
+	outer := [][2]float64{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}}
+	hole := [][2]float64{{2, 2}, {4, 2}, {4, 4}, {2, 4}, {2, 2}}
+	other := [][2]float64{{20, 0}, {20, 5}, {25, 5}, {25, 0}, {20, 0}}
+	writeShapefile(t, base,
+		shpPolyContent(shpPolygon, outer, hole, other),
+		shpPolyContent(shpPolygon, outer),
+		shpPolyContent(shpPolyLine, [][2]float64{{0, 0}, {1, 1}}, [][2]float64{{2, 2}, {3, 3}}),
+		shpPointContent(116.4, 39.9),
+		[]byte{0, 0, 0, 0},
+	)
+
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String("北京")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeDBF(t, base+".dbf", 0x4D, []dbfColumn{
+		{"NAME", 'C', 10, 0},
+		{"POP", 'N', 10, 0},
+		{"AREA", 'N', 10, 2},
+		{"OPEN", 'L', 1, 0},
+		{"BUILT", 'D', 8, 0},
+	}, [][]string{
+		{"a", "100", "1.50", "T", "20240131"},
+		{"*", "deleted", "", "", "", ""},
+		{"c", "", "", "?", ""},
+		{gbk, "21000000", "", "F", ""},
+		{"", "", "", "", ""},
+	})
+	if err := os.WriteFile(base+".prj", []byte(`GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewShapefileProvider(dir)
+	if err != nil {
+		t.Fatalf("NewShapefileProvider() 错误 = %v", err)
+	}
+	if provider.GetSrid() != 4326 {
+		t.Errorf("GetSrid() = %v, want 4326", provider.GetSrid())
+	}
+
+	layers := provider.GetDataByTile(NewTile(0, 0, 0))
+	if len(layers) != 1 || layers[0].Name != "areas" || len(layers[0].Features) != 3 {
+		t.Fatalf("GetDataByTile() = %+v, want areas图层3个要素", layers)
+	}
+	features := layers[0].Features
+
+	// 多边形：洞归属第一个外环
+	mp, ok := features[0].Geometry.(basic.MultiPolygon)
+	if !ok || len(mp) != 2 || len(mp[0]) != 2 || len(mp[1]) != 1 {
+		t.Errorf("多边形 = %#v, want 2个多边形, 第一个带洞", features[0].Geometry)
+	}
+	want := map[string]interface{}{"NAME": "a", "POP": int64(100), "AREA": 1.5, "OPEN": true, "BUILT": "2024-01-31"}
+	for k, v := range want {
+		if features[0].Properties[k] != v {
+			t.Errorf("属性 %s = %#v, want %#v", k, features[0].Properties[k], v)
+		}
+	}
+	if features[0].ID != uint64(1) {
+		t.Errorf("要素ID = %v, want 1", features[0].ID)
+	}
+
+	// 被删除的第2条记录被跳过
+	if _, ok := features[1].Geometry.(basic.MultiLine); !ok || features[1].ID != uint64(3) {
+		t.Errorf("第2个要素 = %T, ID %v, want MultiLine, ID 3", features[1].Geometry, features[1].ID)
+	}
+	if len(features[1].Properties) != 1 || features[1].Properties["NAME"] != "c" {
+		t.Errorf("空值属性 = %v, want 只有NAME", features[1].Properties)
+	}
+
+	// 属性按DBF语言驱动标识的GBK编码解码
+	if pt, ok := features[2].Geometry.(basic.Point); !ok || pt != (basic.Point{116.4, 39.9}) {
+		t.Errorf("点 = %#v", features[2].Geometry)
+	}
+	if name := features[2].Properties["NAME"]; name != "北京" {
+		t.Errorf("NAME = %v, want 北京", name)
+	}
+}
+
+// TestShapefileProvider_Options 测试选项和.cpg编码
+func TestShapefileProvider_Options(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "points")
+	writeShapefile(t, base, shpPointContent(1e6, 1e6))
+	writeDBF(t, base+".dbf", 0, []dbfColumn{{"NAME", 'C', 10, 0}}, [][]string{{"caf\xe9"}})
+	if err := os.WriteFile(base+".cpg", []byte("ANSI 1252\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewShapefileProviderWithOptions(ShapefileOptions{LayerName: "pois", SRID: 3857}, base+".shp")
+	if err != nil {
+		t.Fatalf("NewShapefileProviderWithOptions() 错误 = %v", err)
+	}
+	if provider.GetSrid() != 3857 {
+		t.Errorf("GetSrid() = %v, want 3857", provider.GetSrid())
+	}
+	layers := provider.GetDataByTile(NewTile(0, 0, 0))
+	if len(layers) != 1 || layers[0].Name != "pois" || layers[0].Features[0].Properties["NAME"] != "café" {
+		t.Errorf("GetDataByTile() = %+v, want pois图层, NAME=café", layers)
+	}
+}
+
+// TestPrjSRID 测试由.prj文件确定坐标系
+func TestPrjSRID(t *testing.T) {
+	tests := []struct {
+		wkt  string
+		want uint64
+	}{
+		{`GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`, 4326},
+		{`PROJCS["WGS_1984_Web_Mercator_Auxiliary_Sphere",GEOGCS["GCS_WGS_1984"],PROJECTION["Mercator_Auxiliary_Sphere"]]`, 3857},
+		{`PROJCS["RGF93 / Lambert-93",GEOGCS["RGF93",DATUM["Reseau_Geodesique_Francais_1993",SPHEROID["GRS 1980",6378137,298.257222101,AUTHORITY["EPSG","7019"]],AUTHORITY["EPSG","6171"]],AUTHORITY["EPSG","4171"]],PROJECTION["Lambert_Conformal_Conic_2SP"],AUTHORITY["EPSG","2154"]]`, 2154},
+		{`GEOGCS["China Geodetic Coordinate System 2000",DATUM["China_2000",SPHEROID["CGCS2000",6378137,298.257222101,AUTHORITY["EPSG","1024"]]],AUTHORITY["EPSG", "4490"]]`, 4490},
+		// ArcGIS导出的没有EPSG代码的.prj
+		{`PROJCS["RGF_1993_Lambert_93",GEOGCS["GCS_RGF_1993",DATUM["D_RGF_1993",SPHEROID["GRS_1980",6378137.0,298.257222101]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic"],PARAMETER["False_Easting",700000.0],PARAMETER["False_Northing",6600000.0],PARAMETER["Central_Meridian",3.0],PARAMETER["Standard_Parallel_1",49.0],PARAMETER["Standard_Parallel_2",44.0],PARAMETER["Latitude_Of_Origin",46.5],UNIT["Meter",1.0]]`, 2154},
+		{`PROJCS["WGS_1984_UTM_Zone_50N",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],PARAMETER["False_Easting",500000.0],PARAMETER["False_Northing",0.0],PARAMETER["Central_Meridian",117.0],PARAMETER["Scale_Factor",0.9996],PARAMETER["Latitude_Of_Origin",0.0],UNIT["Meter",1.0]]`, 32650},
+		{`GEOGCS["GCS_China_Geodetic_Coordinate_System_2000",DATUM["D_China_2000",SPHEROID["CGCS2000",6378137.0,298.257222101]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`, 4490},
+	}
+	for _, tt := range tests {
+		got, err := prjSRID(tt.wkt)
+		if err != nil || got != tt.want {
+			t.Errorf("prjSRID(%.30s...) = %v, %v, want %v", tt.wkt, got, err, tt.want)
+		}
+	}
+
+	// 带号坐标系按带号或中央经线换算EPSG代码
+	zones := []struct {
+		name string
+		want uint64
+	}{
+		{"WGS_1984_UTM_ZONE_31N", 32631},
+		{"WGS_1984_UTM_ZONE_60S", 32760},
+		{"NAD_1983_UTM_ZONE_10N", 26910},
+		{"CGCS2000_GK_ZONE_20", 4498},
+		{"CGCS2000_GK_CM_117E", 4509},
+		{"CGCS2000_3_DEGREE_GK_ZONE_39", 4527},
+		{"CGCS2000_3_DEGREE_GK_CM_117E", 4548},
+	}
+	for _, tt := range zones {
+		if got, ok := esriSRID(tt.name); !ok || got != tt.want {
+			t.Errorf("esriSRID(%s) = %v, %v, want %v", tt.name, got, ok, tt.want)
+		}
+	}
+	for _, name := range []string{"WGS_1984_UTM_ZONE_61N", "CGCS2000_3_DEGREE_GK_CM_118E"} {
+		if got, ok := esriSRID(name); ok {
+			t.Errorf("esriSRID(%s) = %v, want 无法识别", name, got)
+		}
+	}
+
+	// 无法识别的坐标系提示通过SRID指定
+	_, err := prjSRID(`PROJCS["Local_Grid",GEOGCS["GCS_Unknown"],PROJECTION["Transverse_Mercator"]]`)
+	if !errors.Is(err, ErrUnsupportedProjection) || !strings.Contains(err.Error(), "Local_Grid") || !strings.Contains(err.Error(), "SRID") {
+		t.Errorf("prjSRID() 错误 = %v, want %v 并提示SRID", err, ErrUnsupportedProjection)
+	}
+}
+
+// TestShapefileProvider_Invalid 测试无效的Shapefile
+func TestShapefileProvider_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	// 不支持的坐标系
+	writeShapefile(t, filepath.Join(dir, "local"), shpPointContent(500000, 4000000))
+	if err := os.WriteFile(filepath.Join(dir, "local.prj"), []byte(`PROJCS["Local_Grid",GEOGCS["GCS_WGS_1984"],PROJECTION["Transverse_Mercator"]]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewShapefileProvider(filepath.Join(dir, "local.shp")); !errors.Is(err, ErrUnsupportedProjection) {
+		t.Errorf("NewShapefileProvider() 错误 = %v, want %v", err, ErrUnsupportedProjection)
+	}
+	// 指定SRID时忽略.prj文件
+	if _, err := NewShapefileProviderWithOptions(ShapefileOptions{SRID: 32650}, filepath.Join(dir, "local.shp")); err != nil {
+		t.Errorf("NewShapefileProviderWithOptions() 错误 = %v", err)
+	}
+
+	// 截断的记录
+	writeShapefile(t, filepath.Join(dir, "broken"), shpPolyContent(shpPolygon, [][2]float64{{0, 0}, {1, 1}, {0, 1}})[:30])
+	if _, err := NewShapefileProvider(filepath.Join(dir, "broken.shp")); !errors.Is(err, ErrInvalidShapefile) {
+		t.Errorf("NewShapefileProvider() 错误 = %v, want %v", err, ErrInvalidShapefile)
+	}
+
+	// 文件头无效
+	bad := filepath.Join(dir, "bad.shp")
+	if err := os.WriteFile(bad, []byte("not a shapefile"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewShapefileProvider(bad); !errors.Is(err, ErrInvalidShapefile) {
+		t.Errorf("NewShapefileProvider() 错误 = %v, want %v", err, ErrInvalidShapefile)
+	}
+}
+
+// TestShpPolygons 测试环方向与洞的归属
+func TestShpPolygons(t *testing.T) {
+	ring := func(pts ...float64) basic.Line {
+		var l basic.Line
+		for i := 0; i+1 < len(pts); i += 2 {
+			l = append(l, basic.Point{pts[i], pts[i+1]})
+		}
+		return l
+	}
+	a := ring(0, 0, 0, 10, 10, 10, 10, 0)
+	b := ring(20, 0, 20, 10, 30, 10, 30, 0)
+	holeB := ring(22, 2, 24, 2, 24, 4, 22, 4)
+	orphan := ring(50, 50, 60, 50, 60, 60, 50, 60)
+
+	polygons := shpPolygons([]basic.Line{holeB, a, b, orphan})
+	if len(polygons) != 3 || len(polygons[0]) != 1 || len(polygons[1]) != 2 || len(polygons[2]) != 1 {
+		t.Fatalf("shpPolygons() = %v", polygons)
+	}
+	if math.Abs(ringSignedArea(polygons[1][1])) != 4 {
+		t.Errorf("洞 = %v, want holeB", polygons[1][1])
+	}
+
+	// 外环中有湖，湖中有岛，岛中有池塘：池塘属于岛，湖属于最外的环
+	outer := ring(0, 0, 0, 100, 100, 100, 100, 0)
+	lake := ring(10, 10, 90, 10, 90, 90, 10, 90)
+	island := ring(20, 20, 20, 80, 80, 80, 80, 20)
+	pond := ring(40, 40, 60, 40, 60, 60, 40, 60)
+	polygons = shpPolygons([]basic.Line{outer, lake, island, pond})
+	if len(polygons) != 2 || len(polygons[0]) != 2 || len(polygons[1]) != 2 {
+		t.Fatalf("嵌套的shpPolygons() = %v", polygons)
+	}
+	if math.Abs(ringSignedArea(polygons[0][1])) != 6400 || math.Abs(ringSignedArea(polygons[1][1])) != 400 {
+		t.Errorf("洞 = %v, %v, want lake, pond", polygons[0][1], polygons[1][1])
+	}
+}
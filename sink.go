@@ -0,0 +1,348 @@
+package tile
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SinkExporter 由编码器和存储组合成的导出器
+// 任意Encoder(MVTExporter、GeoJSONExporter、SVGExporter或自定义编码)可以与任意TileSink组合。
+// 作为Config.Exporter使用时由Tiler调用Open、Finalize和Close
+type SinkExporter struct {
+	Encoder Encoder
+	Sink    TileSink
+
+	layers vectorLayerSet
+
+	// mu 保护ctx和meta
+	mu   sync.Mutex
+	ctx  context.Context
+	meta TilesetMetadata
+}
+
+// NewSinkExporter 创建将encoder编码的瓦片写入sink的导出器
+func NewSinkExporter(encoder Encoder, sink TileSink) *SinkExporter {
+	return &SinkExporter{Encoder: encoder, Sink: sink, ctx: context.Background()}
+}
+
+// Open 打开存储，meta.Format为空时使用编码器的扩展名
+func (e *SinkExporter) Open(ctx context.Context, meta TilesetMetadata) error {
+	if meta.Format == "" {
+		meta.Format = e.Encoder.Extension()
+	}
+	e.mu.Lock()
+	e.ctx, e.meta = ctx, meta
+	e.mu.Unlock()
+	return e.Sink.Open(ctx, meta)
+}
+
+// context 返回Open时传入的上下文
+func (e *SinkExporter) context() context.Context {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.ctx
+}
+
+// SaveTile 编码瓦片并写入存储，path被忽略
+func (e *SinkExporter) SaveTile(res []*Layer, tile *Tile, path string) error {
+	_, err := e.SaveTileSize(res, tile, path)
+	return err
+}
+
+// SaveTileSize 保存瓦片并返回编码后的字节数
+func (e *SinkExporter) SaveTileSize(res []*Layer, tile *Tile, path string) (int64, error) {
+	if tile == nil {
+		return 0, ErrInvalidTile
+	}
+	data, err := e.Encoder.Encode(res, tile)
+	if err != nil {
+		return 0, err
+	}
+	if err := e.Sink.WriteTile(e.context(), tile.Z, tile.X, tile.Y, data); err != nil {
+		return 0, err
+	}
+	e.layers.add(tile.Z, res)
+	return int64(len(data)), nil
+}
+
+// RemoveTile 存储实现TileDeleter时删除瓦片，否则忽略
+func (e *SinkExporter) RemoveTile(tile *Tile, path string) error {
+	if tile == nil {
+		return ErrInvalidTile
+	}
+	deleter, ok := e.Sink.(TileDeleter)
+	if !ok {
+		return nil
+	}
+	return deleter.DeleteTile(e.context(), tile.Z, tile.X, tile.Y)
+}
+
+// Extension 返回编码器的扩展名
+func (e *SinkExporter) Extension() string {
+	return e.Encoder.Extension()
+}
+
+// RelativeTilePath 瓦片位置由存储决定，返回空字符串
+func (e *SinkExporter) RelativeTilePath(zoom, x, y int) string {
+	return ""
+}
+
+// Finalize 将包含vector_layers的元数据交给存储，存储实现Finalizer时随后调用其Finalize
+func (e *SinkExporter) Finalize() error {
+	if updater, ok := e.Sink.(MetadataUpdater); ok {
+		e.mu.Lock()
+		meta := e.meta
+		e.mu.Unlock()
+		meta.VectorLayers = e.layers.list()
+		if err := updater.UpdateMetadata(meta); err != nil {
+			return err
+		}
+	}
+	if finalizer, ok := e.Sink.(Finalizer); ok {
+		return finalizer.Finalize()
+	}
+	return nil
+}
+
+// Close 关闭存储
+func (e *SinkExporter) Close() error {
+	return e.Sink.Close()
+}
+
+// DirectorySink 将瓦片写入{Dir}/{z}/{x}/{y}.{Extension}文件
+type DirectorySink struct {
+	// Dir 输出目录
+	Dir string
+	// Extension 文件扩展名，为空时在Open时使用元数据中的格式
+	Extension string
+	// FileMode 文件权限
+	FileMode os.FileMode
+	// DirMode 目录权限
+	DirMode os.FileMode
+}
+
+// NewDirectorySink 创建写入dir的目录存储
+func NewDirectorySink(dir string) *DirectorySink {
+	return &DirectorySink{Dir: dir, FileMode: 0644, DirMode: 0755}
+}
+
+// Open 创建输出目录，未设置扩展名时使用meta.Format
+func (s *DirectorySink) Open(ctx context.Context, meta TilesetMetadata) error {
+	if s.Dir == "" {
+		return ErrInvalidPath
+	}
+	if s.Extension == "" {
+		s.Extension = meta.Format
+	}
+	if err := os.MkdirAll(s.Dir, s.DirMode); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	return nil
+}
+
+// TilePath 返回瓦片文件的路径
+func (s *DirectorySink) TilePath(z, x, y uint32) string {
+	return filepath.Join(s.Dir, fmt.Sprintf("%d", z), fmt.Sprintf("%d", x), fmt.Sprintf("%d.%s", y, s.Extension))
+}
+
+// WriteTile 写入瓦片文件
+func (s *DirectorySink) WriteTile(ctx context.Context, z, x, y uint32, data []byte) error {
+	if s.Extension == "" {
+		return ErrSinkNotOpen
+	}
+	path := s.TilePath(z, x, y)
+	if err := os.MkdirAll(filepath.Dir(path), s.DirMode); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	return os.WriteFile(path, data, s.FileMode)
+}
+
+// DeleteTile 删除瓦片文件，文件不存在时不报错
+func (s *DirectorySink) DeleteTile(ctx context.Context, z, x, y uint32) error {
+	if s.Extension == "" {
+		return ErrSinkNotOpen
+	}
+	return removeTileFile(s.TilePath(z, x, y))
+}
+
+// Close 目录存储没有需要释放的资源
+func (s *DirectorySink) Close() error {
+	return nil
+}
+
+// TarSink 将瓦片依次写入io.Writer上的tar流，条目名为{z}/{x}/{y}.{Extension}，
+// 用于标准输出、网络连接等不能随机访问的输出。条目按写入顺序排列，Close时写入tiles.json(TileJSON)并结束tar流，
+// 不关闭底层Writer。流中的瓦片不能删除，增量生成时只包含本次生成的瓦片
+type TarSink struct {
+	// Extension 文件扩展名，为空时在Open时使用元数据中的格式
+	Extension string
+	// FileMode 条目的文件权限
+	FileMode os.FileMode
+
+	w io.Writer
+	// mu 保护tw和meta，tar条目需要逐个写入
+	mu      sync.Mutex
+	tw      *tar.Writer
+	meta    TilesetMetadata
+	modTime time.Time
+}
+
+// NewTarSink 创建写入w的tar流存储
+func NewTarSink(w io.Writer) *TarSink {
+	return &TarSink{w: w, FileMode: 0644}
+}
+
+// Open 开始tar流，未设置扩展名时使用meta.Format
+func (s *TarSink) Open(ctx context.Context, meta TilesetMetadata) error {
+	if s.w == nil {
+		return ErrInvalidPath
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Extension == "" {
+		s.Extension = meta.Format
+	}
+	s.meta = meta
+	s.modTime = time.Now()
+	if s.tw == nil {
+		s.tw = tar.NewWriter(s.w)
+	}
+	return nil
+}
+
+// WriteTile 写入一个瓦片条目
+func (s *TarSink) WriteTile(ctx context.Context, z, x, y uint32, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tw == nil {
+		return ErrSinkNotOpen
+	}
+	return s.write(fmt.Sprintf("%d/%d/%d.%s", z, x, y, s.Extension), data)
+}
+
+// UpdateMetadata 记录完整元数据，在Close时写入tiles.json
+func (s *TarSink) UpdateMetadata(meta TilesetMetadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meta = meta
+	return nil
+}
+
+// Close 写入tiles.json并结束tar流，重复调用时不做任何操作
+func (s *TarSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tw == nil {
+		return nil
+	}
+	tw := s.tw
+	defer func() { s.tw = nil }()
+
+	tj := newTileJSON(s.meta, s.meta.VectorLayers)
+	tj.Name = s.meta.Name
+	tj.Tiles = []string{"{z}/{x}/{y}." + s.Extension}
+	data, err := json.MarshalIndent(tj, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := s.write(TileJSONFileName, data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("结束tar流失败: %w", err)
+	}
+	return nil
+}
+
+// write 写入一个tar条目，调用者持有mu
+func (s *TarSink) write(name string, data []byte) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(s.FileMode.Perm()),
+		Size:     int64(len(data)),
+		ModTime:  s.modTime,
+	}
+	if err := s.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("写入tar条目%s失败: %w", name, err)
+	}
+	if _, err := s.tw.Write(data); err != nil {
+		return fmt.Errorf("写入tar条目%s失败: %w", name, err)
+	}
+	return nil
+}
+
+// MemorySink 将瓦片保存在内存中，用于测试或由调用者自行处理输出
+type MemorySink struct {
+	mu    sync.RWMutex
+	meta  TilesetMetadata
+	tiles map[[3]uint32][]byte
+}
+
+// NewMemorySink 创建内存存储
+func NewMemorySink() *MemorySink {
+	return &MemorySink{tiles: make(map[[3]uint32][]byte)}
+}
+
+// Open 记录元数据
+func (s *MemorySink) Open(ctx context.Context, meta TilesetMetadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meta = meta
+	return nil
+}
+
+// WriteTile 保存瓦片数据
+func (s *MemorySink) WriteTile(ctx context.Context, z, x, y uint32, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tiles[[3]uint32{z, x, y}] = data
+	return nil
+}
+
+// DeleteTile 删除瓦片
+func (s *MemorySink) DeleteTile(ctx context.Context, z, x, y uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tiles, [3]uint32{z, x, y})
+	return nil
+}
+
+// UpdateMetadata 记录完整元数据
+func (s *MemorySink) UpdateMetadata(meta TilesetMetadata) error {
+	return s.Open(context.Background(), meta)
+}
+
+// Close 内存存储没有需要释放的资源，关闭后仍可读取瓦片
+func (s *MemorySink) Close() error {
+	return nil
+}
+
+// Tile 返回瓦片数据
+func (s *MemorySink) Tile(z, x, y uint32) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.tiles[[3]uint32{z, x, y}]
+	return data, ok
+}
+
+// Len 返回瓦片数
+func (s *MemorySink) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tiles)
+}
+
+// Metadata 返回最近一次Open或UpdateMetadata传入的元数据
+func (s *MemorySink) Metadata() TilesetMetadata {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.meta
+}
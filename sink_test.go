@@ -0,0 +1,246 @@
+package tile
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// lifecycleSink 记录调用顺序的存储
+type lifecycleSink struct {
+	*MemorySink
+	openErr error
+	calls   []string
+}
+
+func (s *lifecycleSink) Open(ctx context.Context, meta TilesetMetadata) error {
+	s.calls = append(s.calls, "open")
+	if s.openErr != nil {
+		return s.openErr
+	}
+	return s.MemorySink.Open(ctx, meta)
+}
+
+func (s *lifecycleSink) Finalize() error {
+	s.calls = append(s.calls, "finalize")
+	return nil
+}
+
+func (s *lifecycleSink) Close() error {
+	s.calls = append(s.calls, "close")
+	return nil
+}
+
+// sinkProvider 返回包含一个点的Provider
+func sinkProvider(t *testing.T) Provider {
+	t.Helper()
+	provider, err := NewMemoryProvider(4326, []*Layer{{Name: "cities", Features: []*geom.Feature{
+		{Geometry: basic.Point{116.4, 39.9}, Properties: map[string]interface{}{"name": "北京"}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+// TestSinkExporter_Memory 测试GeoJSON编码写入内存存储，以及元数据和调用顺序
+func TestSinkExporter_Memory(t *testing.T) {
+	sink := &lifecycleSink{MemorySink: NewMemorySink()}
+	exporter := NewSinkExporter(NewGeoJSONExporter(), sink)
+	tiler := NewTiler(&Config{
+		Provider: sinkProvider(t),
+		Exporter: exporter,
+		MinZoom:  1,
+		MaxZoom:  3,
+		Bound:    &[4]float64{110, 30, 120, 95},
+	})
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	if got := sink.calls; len(got) != 3 || got[0] != "open" || got[1] != "finalize" || got[2] != "close" {
+		t.Errorf("调用顺序 = %v, want [open finalize close]", got)
+	}
+	if sink.Len() != 3 {
+		t.Fatalf("瓦片数 = %d, want 3", sink.Len())
+	}
+	want := NewTileLatLong(3, 39.9, 116.4)
+	data, ok := sink.Tile(3, want.X, want.Y)
+	if !ok {
+		t.Fatalf("缺少瓦片 %s", want.ToString())
+	}
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &fc); err != nil || fc.Type != "FeatureCollection" || len(fc.Features) != 1 || fc.Features[0].Properties["name"] != "北京" {
+		t.Errorf("瓦片内容 = %s, %v", data, err)
+	}
+
+	// 报告中的大小为编码后的字节数，而不是输出路径的大小
+	report := tiler.Report()
+	for z := uint32(1); z <= 3; z++ {
+		tile := NewTileLatLong(z, 39.9, 116.4)
+		data, _ := sink.Tile(z, tile.X, tile.Y)
+		if s := report.Zooms[int(z)]; s == nil || s.TotalBytes != int64(len(data)) {
+			t.Errorf("z=%d 报告大小 = %+v, want %d", z, s, len(data))
+		}
+	}
+
+	meta := sink.Metadata()
+	if meta.Format != "geojson" || meta.MinZoom != 1 || meta.MaxZoom != 3 {
+		t.Errorf("元数据 = %+v", meta)
+	}
+	// 纬度限制在Web墨卡托范围内
+	if meta.Bounds[0] != 110 || meta.Bounds[1] != 30 || meta.Bounds[2] != 120 || meta.Bounds[3] >= 86 {
+		t.Errorf("Bounds = %v", meta.Bounds)
+	}
+	if len(meta.VectorLayers) != 1 || meta.VectorLayers[0].ID != "cities" || meta.VectorLayers[0].Fields["name"] != "String" ||
+		meta.VectorLayers[0].MinZoom != 1 || meta.VectorLayers[0].MaxZoom != 3 {
+		t.Errorf("VectorLayers = %+v", meta.VectorLayers)
+	}
+}
+
+// TestSinkExporter_Directory 测试MVT编码写入目录以及删除瓦片
+func TestSinkExporter_Directory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tiles")
+	sink := NewDirectorySink(dir)
+	exporter := NewSinkExporter(NewMVTExporter(), sink)
+	if err := sink.WriteTile(context.Background(), 0, 0, 0, nil); !errors.Is(err, ErrSinkNotOpen) {
+		t.Errorf("Open前WriteTile() 错误 = %v, want %v", err, ErrSinkNotOpen)
+	}
+	if err := NewTiler(&Config{Provider: sinkProvider(t), Exporter: exporter, MaxZoom: 1}).Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	tile := NewTileLatLong(1, 39.9, 116.4)
+	path := filepath.Join(dir, "1", "1", "0.mvt")
+	if sink.TilePath(1, tile.X, tile.Y) != path {
+		t.Fatalf("TilePath() = %v, want %v", sink.TilePath(1, tile.X, tile.Y), path)
+	}
+	if data, err := os.ReadFile(path); err != nil || len(data) == 0 {
+		t.Errorf("瓦片文件 = %v, %v", data, err)
+	}
+
+	if err := exporter.RemoveTile(tile, ""); err != nil {
+		t.Fatalf("RemoveTile() 错误 = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("RemoveTile()后文件仍存在: %v", err)
+	}
+}
+
+// TestSinkExporter_Tar 测试GeoJSON编码写入tar流
+func TestSinkExporter_Tar(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewTarSink(&buf)
+	if err := sink.WriteTile(context.Background(), 0, 0, 0, nil); !errors.Is(err, ErrSinkNotOpen) {
+		t.Errorf("Open前WriteTile() 错误 = %v, want %v", err, ErrSinkNotOpen)
+	}
+	exporter := NewSinkExporter(NewGeoJSONExporter(), sink)
+	if err := NewTiler(&Config{Provider: sinkProvider(t), Exporter: exporter, MinZoom: 1, MaxZoom: 2}).Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	entries := make(map[string][]byte)
+	var names []string
+	r := tar.NewReader(&buf)
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("读取tar流错误 = %v", err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[header.Name] = data
+		names = append(names, header.Name)
+	}
+
+	tile := NewTileLatLong(2, 39.9, 116.4)
+	name := fmt.Sprintf("2/%d/%d.geojson", tile.X, tile.Y)
+	if data, ok := entries[name]; !ok || !bytes.Contains(data, []byte("FeatureCollection")) {
+		t.Errorf("缺少瓦片条目%s: %v", name, names)
+	}
+	// tiles.json在所有瓦片之后写入，包含vector_layers
+	if len(names) == 0 || names[len(names)-1] != TileJSONFileName {
+		t.Fatalf("条目 = %v, want 最后为%s", names, TileJSONFileName)
+	}
+	var tj TileJSON
+	if err := json.Unmarshal(entries[TileJSONFileName], &tj); err != nil {
+		t.Fatalf("解析%s错误 = %v", TileJSONFileName, err)
+	}
+	if len(tj.Tiles) != 1 || tj.Tiles[0] != "{z}/{x}/{y}.geojson" || tj.MinZoom != 1 || tj.MaxZoom != 2 ||
+		len(tj.VectorLayers) != 1 || tj.VectorLayers[0].ID != "cities" {
+		t.Errorf("TileJSON = %+v", tj)
+	}
+
+	// 重复关闭不再写入
+	n := buf.Len()
+	if err := sink.Close(); err != nil || buf.Len() != n {
+		t.Errorf("重复Close() = %v, 写入%d字节", err, buf.Len()-n)
+	}
+}
+
+// TestSinkExporter_MBTiles 测试SVG编码写入MBTiles存储
+func TestSinkExporter_MBTiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "svg.mbtiles")
+	sink, err := NewMBTilesExporterWithOptions(path, MBTilesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewTiler(&Config{Provider: sinkProvider(t), Exporter: NewSinkExporter(NewSVGExporter(), sink), MaxZoom: 1}).Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	tiles, meta, _ := readMBTiles(t, path)
+	if len(tiles) != 2 || meta["format"] != "svg" || meta["maxzoom"] != "1" {
+		t.Errorf("瓦片数 = %d, 元数据 = %v", len(tiles), meta)
+	}
+	for key, data := range tiles {
+		if len(data) < 4 || string(data[:4]) != "<?xm" {
+			t.Errorf("瓦片 %s 不是SVG: %.20s", key, data)
+		}
+	}
+	if !json.Valid([]byte(meta["json"])) || meta["json"] == `{"vector_layers":[]}` {
+		t.Errorf("json = %v", meta["json"])
+	}
+}
+
+// TestTiler_ExporterLifecycle 测试Open失败和停止时的生命周期
+func TestTiler_ExporterLifecycle(t *testing.T) {
+	openErr := errors.New("open failed")
+	sink := &lifecycleSink{MemorySink: NewMemorySink(), openErr: openErr}
+	err := NewTiler(&Config{Provider: sinkProvider(t), Exporter: NewSinkExporter(NewMVTExporter(), sink), MaxZoom: 1}).Tiler()
+	if !errors.Is(err, openErr) || len(sink.calls) != 1 {
+		t.Errorf("Tiler() 错误 = %v, 调用 = %v, want %v, [open]", err, sink.calls, openErr)
+	}
+
+	// 停止时不调用Finalize，但仍然关闭
+	sink = &lifecycleSink{MemorySink: NewMemorySink()}
+	tiler := NewTiler(&Config{Provider: sinkProvider(t), Exporter: NewSinkExporter(NewMVTExporter(), sink), MaxZoom: 1})
+	tiler.Stop()
+	if err := tiler.Tiler(); err == nil {
+		t.Error("停止后Tiler()应返回错误")
+	}
+	if len(sink.calls) != 2 || sink.calls[0] != "open" || sink.calls[1] != "close" {
+		t.Errorf("调用 = %v, want [open close]", sink.calls)
+	}
+}
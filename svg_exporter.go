@@ -77,28 +77,37 @@ func NewSVGExporterWithOptions(options SVGOptions) *SVGExporter {
 
 // SaveTile 保存瓦片为SVG格式
 func (s *SVGExporter) SaveTile(res []*Layer, tile *Tile, path string) error {
+	_, err := s.SaveTileSize(res, tile, path)
+	return err
+}
+
+// SaveTileSize 保存瓦片并返回编码后的字节数
+func (s *SVGExporter) SaveTileSize(res []*Layer, tile *Tile, path string) (int64, error) {
 	if tile == nil {
-		return ErrInvalidTile
+		return 0, ErrInvalidTile
 	}
 
 	if path == "" {
-		return ErrInvalidPath
+		return 0, ErrInvalidPath
 	}
 
 	// 确保目录存在
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, s.Options.DirMode); err != nil {
-		return fmt.Errorf("创建目录失败: %w", err)
+		return 0, fmt.Errorf("创建目录失败: %w", err)
 	}
 
 	// 生成SVG数据
 	svgData, err := s.GenerateSVG(res, tile)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// 写入文件
-	return os.WriteFile(path, svgData, s.Options.FileMode)
+	if err := os.WriteFile(path, svgData, s.Options.FileMode); err != nil {
+		return 0, err
+	}
+	return int64(len(svgData)), nil
 }
 
 // GenerateSVG 生成SVG数据
@@ -233,6 +242,11 @@ func (s *SVGExporter) getFeaturePointStyle(feature *geom.Feature, defaultStyle s
 	return defaultStyle
 }
 
+// Encode 实现Encoder接口，与GenerateSVG相同
+func (s *SVGExporter) Encode(layers []*Layer, tile *Tile) ([]byte, error) {
+	return s.GenerateSVG(layers, tile)
+}
+
 // SaveTileToWriter 将瓦片数据写入io.Writer
 func (s *SVGExporter) SaveTileToWriter(res []*Layer, tile *Tile, writer io.Writer) error {
 	if writer == nil {
@@ -250,6 +264,14 @@ func (s *SVGExporter) SaveTileToWriter(res []*Layer, tile *Tile, writer io.Write
 	return err
 }
 
+// RemoveTile 删除已导出的瓦片文件
+func (s *SVGExporter) RemoveTile(tile *Tile, path string) error {
+	if tile == nil {
+		return ErrInvalidTile
+	}
+	return removeTileFile(path)
+}
+
 // Extension 返回文件扩展名
 func (s *SVGExporter) Extension() string {
 	return "svg"
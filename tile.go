@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	gen "github.com/flywave/go-geom/general"
+	"github.com/flywave/go-vector-tiler/basic"
 	"github.com/flywave/go-vector-tiler/maths/webmercator"
 	"github.com/flywave/go-vector-tiler/util"
 )
@@ -54,6 +55,9 @@ type Tile struct {
 	// 内部计算用属性
 	xspan float64 // X方向跨度
 	yspan float64 // Y方向跨度
+
+	// matrixSet 瓦片所在的瓦片矩阵集，为nil时为Web墨卡托网格
+	matrixSet *TileMatrixSet
 }
 
 // XYZFromStringId 从字符串ID解析瓦片坐标
@@ -133,6 +137,43 @@ func NewTileWithOptions(z, x, y uint32, buffer, extent, tolerance float64) *Tile
 	return t
 }
 
+// NewTileInMatrixSet 在瓦片矩阵集中创建瓦片对象，瓦片范围和像素转换使用矩阵集的网格坐标系
+// set为nil时与NewTileWithOptions相同
+func NewTileInMatrixSet(set *TileMatrixSet, z, x, y uint32, buffer, extent, tolerance float64) *Tile {
+	if set == nil {
+		return NewTileWithOptions(z, x, y, buffer, extent, tolerance)
+	}
+	t := &Tile{
+		Z:         z,
+		X:         x,
+		Y:         y,
+		Buffer:    buffer,
+		Extent:    extent,
+		Tolerance: tolerance,
+		matrixSet: set,
+	}
+	t.Init()
+	bounds := t.Bounds()
+	t.Lat, t.Long = bounds[3], bounds[0]
+	return t
+}
+
+// MatrixSet 返回瓦片所在的瓦片矩阵集
+func (t *Tile) MatrixSet() *TileMatrixSet {
+	if t.matrixSet == nil {
+		return WebMercatorQuad
+	}
+	return t.matrixSet
+}
+
+// SRID 返回瓦片网格的坐标系，GetExtent等返回的范围使用该坐标系
+func (t *Tile) SRID() uint64 {
+	if t.matrixSet == nil {
+		return util.WebMercator
+	}
+	return t.matrixSet.SRID
+}
+
 // ToString 返回瓦片的字符串表示
 func (t *Tile) ToString() string {
 	return XYZToStringId(t.X, t.Y, t.Z)
@@ -140,15 +181,19 @@ func (t *Tile) ToString() string {
 
 // Init 初始化瓦片的内部属性
 func (t *Tile) Init() {
-	max := webmercator.MaxXExtent
-
-	// 计算分辨率
-	res := (max * 2) / math.Exp2(float64(t.Z))
-	t.extent = &gen.Extent{
-		-max + (float64(t.X) * res),       // MinX
-		max - (float64(t.Y) * res),        // MinY
-		-max + (float64(t.X) * res) + res, // MaxX
-		max - (float64(t.Y) * res) - res,  // MaxY
+	if t.matrixSet != nil {
+		t.extent = t.matrixSet.tileExtent(t.Z, t.X, t.Y)
+	} else {
+		max := webmercator.MaxXExtent
+
+		// 计算分辨率
+		res := (max * 2) / math.Exp2(float64(t.Z))
+		t.extent = &gen.Extent{
+			-max + (float64(t.X) * res),       // MinX
+			max - (float64(t.Y) * res),        // MinY
+			-max + (float64(t.X) * res) + res, // MaxX
+			max - (float64(t.Y) * res) - res,  // MaxY
+		}
 	}
 	t.xspan = t.extent.MaxX() - t.extent.MinX()
 	t.yspan = t.extent.MaxY() - t.extent.MinY()
@@ -202,6 +247,14 @@ func Tile2Lat(y, z uint64) float64 {
 // Bounds 返回瓦片的地理边界
 // 返回格式为 [西经, 南纬, 东经, 北纬]
 func (t *Tile) Bounds() [4]float64 {
+	if t.matrixSet != nil {
+		ext := t.extent
+		box := [4]float64{ext.MinX(), min(ext.MinY(), ext.MaxY()), ext.MaxX(), max(ext.MinY(), ext.MaxY())}
+		if bounds, err := transformBounds(t.matrixSet.SRID, util.WGS84, box); err == nil {
+			return bounds
+		}
+		return box
+	}
 	west := Tile2Lon(uint64(t.X), uint64(t.Z))
 	north := Tile2Lat(uint64(t.Y), uint64(t.Z))
 	east := Tile2Lon(uint64(t.X+1), uint64(t.Z))
@@ -231,7 +284,7 @@ func toWebMercator(srid int, pt [2]float64) (npt [2]float64, err error) {
 		}
 		return [2]float64{tnpt[0], tnpt[1]}, nil
 	default:
-		return npt, ErrInvalidSRID
+		return projectPoint(srid, pt, basic.PointToWebMercator)
 	}
 }
 
@@ -247,13 +300,25 @@ func fromWebMercator(srid int, pt [2]float64) (npt [2]float64, err error) {
 		}
 		return [2]float64{tnpt[0], tnpt[1]}, nil
 	default:
-		return npt, ErrInvalidSRID
+		return projectPoint(srid, pt, basic.PointFromWebMercator)
 	}
 }
 
+// projectPoint 通过PROJ转换其他坐标系的点，SRID无效时返回ErrInvalidSRID
+func projectPoint(srid int, pt [2]float64, project func(uint64, [2]float64) ([2]float64, error)) ([2]float64, error) {
+	if srid <= 0 {
+		return pt, ErrInvalidSRID
+	}
+	npt, err := project(uint64(srid), pt)
+	if err != nil {
+		return pt, fmt.Errorf("%w: %v", ErrInvalidSRID, err)
+	}
+	return npt, nil
+}
+
 // ToPixel 将地理坐标转换为瓦片像素坐标
 func (t *Tile) ToPixel(srid int, pt [2]float64) (npt [2]float64, err error) {
-	spt, err := toWebMercator(srid, pt)
+	spt, err := t.toGrid(srid, pt)
 	if err != nil {
 		return npt, err
 	}
@@ -270,7 +335,37 @@ func (t *Tile) FromPixel(srid int, pt [2]float64) (npt [2]float64, err error) {
 
 	wmx := (x * t.xspan / t.Extent) + t.extent.MinX()
 	wmy := (y * t.yspan / t.Extent) + t.extent.MinY()
-	return fromWebMercator(srid, [2]float64{wmx, wmy})
+	return t.fromGrid(srid, [2]float64{wmx, wmy})
+}
+
+// toGrid 将坐标从指定SRID转换为瓦片网格坐标系
+func (t *Tile) toGrid(srid int, pt [2]float64) ([2]float64, error) {
+	if t.matrixSet == nil {
+		return toWebMercator(srid, pt)
+	}
+	if srid <= 0 {
+		return pt, ErrInvalidSRID
+	}
+	npt, err := basic.TransformPoint(uint64(srid), t.matrixSet.SRID, pt)
+	if err != nil {
+		return pt, fmt.Errorf("%w: %v", ErrInvalidSRID, err)
+	}
+	return npt, nil
+}
+
+// fromGrid 将瓦片网格坐标系的坐标转换为指定SRID
+func (t *Tile) fromGrid(srid int, pt [2]float64) ([2]float64, error) {
+	if t.matrixSet == nil {
+		return fromWebMercator(srid, pt)
+	}
+	if srid <= 0 {
+		return pt, ErrInvalidSRID
+	}
+	npt, err := basic.TransformPoint(t.matrixSet.SRID, uint64(srid), pt)
+	if err != nil {
+		return pt, fmt.Errorf("%w: %v", ErrInvalidSRID, err)
+	}
+	return npt, nil
 }
 
 // PixelBufferedBounds 返回带缓冲区的瓦片像素边界
@@ -289,6 +384,9 @@ func (t *Tile) ZLevel() uint32 {
 // 移植自: https://raw.githubusercontent.com/mapbox/postgis-vt-util/master/postgis-vt-util.sql
 // 40075016.6855785 是WGS84在z=0时的赤道长度（米）
 func (t *Tile) ZRes() float64 {
+	if t.matrixSet != nil {
+		return t.xspan / t.Extent
+	}
 	return webmercator.MaxXExtent * 2 / (t.Extent * math.Exp2(float64(t.Z)))
 }
 
@@ -323,10 +421,18 @@ func (t *Tile) IsNeighbor(other *Tile) bool {
 }
 
 // GetParent 返回当前瓦片的父瓦片（上一级缩放级别）
+// 瓦片矩阵集中的瓦片返回包含当前瓦片中心的上一级瓦片
 func (t *Tile) GetParent() *Tile {
 	if t.Z == 0 {
 		return nil // 已经是最顶层瓦片
 	}
+	if t.matrixSet != nil {
+		x, y, ok := t.matrixSet.parent(t.Z, t.X, t.Y)
+		if !ok {
+			return nil
+		}
+		return NewTileInMatrixSet(t.matrixSet, t.Z-1, x, y, DefaultTileBuffer, DefaultExtent, DefaultEpislon)
+	}
 
 	parentZ := t.Z - 1
 	parentX := t.X / 2
@@ -336,7 +442,21 @@ func (t *Tile) GetParent() *Tile {
 }
 
 // GetChildren 返回当前瓦片的四个子瓦片（下一级缩放级别）
+// 瓦片矩阵集中的瓦片返回中心落在当前瓦片内的下一级瓦片
 func (t *Tile) GetChildren() []*Tile {
+	if t.matrixSet != nil {
+		r, ok := t.matrixSet.children(t.Z, t.X, t.Y)
+		if !ok {
+			return nil
+		}
+		var children []*Tile
+		for y := r[1]; y <= r[3]; y++ {
+			for x := r[0]; x <= r[2]; x++ {
+				children = append(children, NewTileInMatrixSet(t.matrixSet, t.Z+1, x, y, DefaultTileBuffer, DefaultExtent, DefaultEpislon))
+			}
+		}
+		return children
+	}
 	if t.Z >= MaxZ {
 		return nil // 已经是最大缩放级别
 	}
@@ -383,6 +503,7 @@ func (t *Tile) Clone() *Tile {
 		Tolerance: t.Tolerance,
 		xspan:     t.xspan,
 		yspan:     t.yspan,
+		matrixSet: t.matrixSet,
 	}
 
 	// 复制extent
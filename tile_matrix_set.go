@@ -0,0 +1,360 @@
+package tile
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	gen "github.com/flywave/go-geom/general"
+
+	"github.com/flywave/go-vector-tiler/basic"
+	"github.com/flywave/go-vector-tiler/maths/webmercator"
+	"github.com/flywave/go-vector-tiler/util"
+)
+
+// TileMatrix 单个缩放级别的瓦片矩阵
+type TileMatrix struct {
+	ID string
+	// Origin 矩阵左上角在网格坐标系中的坐标
+	Origin [2]float64
+	// TileWidth、TileHeight 单个瓦片在网格坐标系中的宽度和高度
+	TileWidth  float64
+	TileHeight float64
+	// MatrixWidth、MatrixHeight 矩阵的列数和行数
+	MatrixWidth  uint32
+	MatrixHeight uint32
+}
+
+// TileMatrixSet 瓦片矩阵集，定义瓦片网格的坐标系和各级别的瓦片划分
+// 瓦片行号从上(北)向下递增，与XYZ瓦片一致
+type TileMatrixSet struct {
+	ID string
+	// SRID 网格坐标系
+	SRID uint64
+	// Matrices 各级别的瓦片矩阵，下标为缩放级别
+	Matrices []TileMatrix
+}
+
+var (
+	// WebMercatorQuad Web墨卡托网格，0级1个瓦片，默认使用的网格
+	WebMercatorQuad = NewQuadTileMatrixSet("WebMercatorQuad", util.WebMercator,
+		[4]float64{-webmercator.MaxXExtent, -webmercator.MaxXExtent, webmercator.MaxXExtent, webmercator.MaxXExtent}, 1, 1, MaxZ)
+	// WorldCRS84Quad WGS84经纬度网格，0级为东西两个瓦片
+	WorldCRS84Quad = NewQuadTileMatrixSet("WorldCRS84Quad", util.WGS84, [4]float64{-180, -90, 180, 90}, 2, 1, MaxZ)
+)
+
+// NewQuadTileMatrixSet 创建四叉树瓦片矩阵集
+// bounds 为网格范围 minx, miny, maxx, maxy，0级有width×height个瓦片，每升一级行列数加倍
+func NewQuadTileMatrixSet(id string, srid uint64, bounds [4]float64, width, height, maxZoom uint32) *TileMatrixSet {
+	s := &TileMatrixSet{ID: id, SRID: srid}
+	for z := uint32(0); z <= maxZoom; z++ {
+		n := uint32(1) << z
+		s.Matrices = append(s.Matrices, TileMatrix{
+			ID:           strconv.Itoa(int(z)),
+			Origin:       [2]float64{bounds[0], bounds[3]},
+			TileWidth:    (bounds[2] - bounds[0]) / float64(width*n),
+			TileHeight:   (bounds[3] - bounds[1]) / float64(height*n),
+			MatrixWidth:  width * n,
+			MatrixHeight: height * n,
+		})
+	}
+	return s
+}
+
+// LoadTileMatrixSet 读取OGC TileMatrixSet 2.0 JSON文件
+func LoadTileMatrixSet(path string) (*TileMatrixSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取瓦片矩阵集失败: %w", err)
+	}
+	s, err := ParseTileMatrixSet(data)
+	if err != nil {
+		return nil, fmt.Errorf("瓦片矩阵集 %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// ogcTileMatrixSet OGC TileMatrixSet 2.0 JSON
+type ogcTileMatrixSet struct {
+	ID           string          `json:"id"`
+	CRS          json.RawMessage `json:"crs"`
+	OrderedAxes  []string        `json:"orderedAxes"`
+	TileMatrices []struct {
+		ID                   string     `json:"id"`
+		ScaleDenominator     float64    `json:"scaleDenominator"`
+		CellSize             float64    `json:"cellSize"`
+		CornerOfOrigin       string     `json:"cornerOfOrigin"`
+		PointOfOrigin        [2]float64 `json:"pointOfOrigin"`
+		TileWidth            uint32     `json:"tileWidth"`
+		TileHeight           uint32     `json:"tileHeight"`
+		MatrixWidth          uint32     `json:"matrixWidth"`
+		MatrixHeight         uint32     `json:"matrixHeight"`
+		VariableMatrixWidths []struct{} `json:"variableMatrixWidths"`
+	} `json:"tileMatrices"`
+}
+
+// ParseTileMatrixSet 解析OGC TileMatrixSet 2.0 JSON
+// 瓦片矩阵按分辨率从低到高对应缩放级别0、1、2...，坐标系需为EPSG代码或OGC CRS84，
+// 不支持variableMatrixWidths
+func ParseTileMatrixSet(data []byte) (*TileMatrixSet, error) {
+	var doc ogcTileMatrixSet
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTileMatrixSet, err)
+	}
+
+	srid, latFirst, err := ogcCRS(doc.CRS)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.OrderedAxes) > 0 {
+		switch strings.ToUpper(doc.OrderedAxes[0]) {
+		case "LAT", "LATITUDE", "N", "NORTHING", "Y":
+			latFirst = true
+		default:
+			latFirst = false
+		}
+	}
+	// 由比例尺计算分辨率时每个坐标单位的米数
+	metersPerUnit := 1.0
+	if srid == util.WGS84 {
+		metersPerUnit = 2 * math.Pi * webmercator.RMajor / 360
+	}
+
+	s := &TileMatrixSet{ID: doc.ID, SRID: srid}
+	var cellSizes []float64
+	for _, tm := range doc.TileMatrices {
+		if len(tm.VariableMatrixWidths) > 0 {
+			return nil, fmt.Errorf("%w: 瓦片矩阵 %s 不支持variableMatrixWidths", ErrInvalidTileMatrixSet, tm.ID)
+		}
+		cellSize := tm.CellSize
+		if cellSize == 0 {
+			// OGC标准像素大小0.28mm
+			cellSize = tm.ScaleDenominator * 0.00028 / metersPerUnit
+		}
+		if cellSize <= 0 || tm.TileWidth == 0 || tm.TileHeight == 0 || tm.MatrixWidth == 0 || tm.MatrixHeight == 0 {
+			return nil, fmt.Errorf("%w: 瓦片矩阵 %s 的大小无效", ErrInvalidTileMatrixSet, tm.ID)
+		}
+
+		origin := tm.PointOfOrigin
+		if latFirst {
+			origin[0], origin[1] = origin[1], origin[0]
+		}
+		m := TileMatrix{
+			ID:           tm.ID,
+			Origin:       origin,
+			TileWidth:    cellSize * float64(tm.TileWidth),
+			TileHeight:   cellSize * float64(tm.TileHeight),
+			MatrixWidth:  tm.MatrixWidth,
+			MatrixHeight: tm.MatrixHeight,
+		}
+		switch strings.ToLower(tm.CornerOfOrigin) {
+		case "", "topleft":
+		case "bottomleft":
+			m.Origin[1] += m.TileHeight * float64(m.MatrixHeight)
+		default:
+			return nil, fmt.Errorf("%w: 瓦片矩阵 %s 的原点位置 %s 无效", ErrInvalidTileMatrixSet, tm.ID, tm.CornerOfOrigin)
+		}
+		s.Matrices = append(s.Matrices, m)
+		cellSizes = append(cellSizes, cellSize)
+	}
+	if len(s.Matrices) == 0 {
+		return nil, fmt.Errorf("%w: 没有瓦片矩阵", ErrInvalidTileMatrixSet)
+	}
+
+	// 按分辨率从低到高排列
+	order := make([]int, len(s.Matrices))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return cellSizes[order[i]] > cellSizes[order[j]] })
+	matrices := make([]TileMatrix, len(order))
+	for z, i := range order {
+		matrices[z] = s.Matrices[i]
+	}
+	s.Matrices = matrices
+	return s, nil
+}
+
+// ogcCRS 解析TileMatrixSet的坐标系，返回SRID和坐标系定义的轴顺序是否纬度在前
+func ogcCRS(raw json.RawMessage) (uint64, bool, error) {
+	var uri string
+	if err := json.Unmarshal(raw, &uri); err != nil {
+		var obj struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(raw, &obj); err != nil || obj.URI == "" {
+			return 0, false, fmt.Errorf("%w: 坐标系 %s 无效", ErrInvalidTileMatrixSet, raw)
+		}
+		uri = obj.URI
+	}
+
+	upper := strings.ToUpper(uri)
+	if strings.HasSuffix(upper, "CRS84") {
+		return util.WGS84, false, nil
+	}
+	i := strings.LastIndexAny(upper, "/:")
+	code, err := strconv.ParseUint(upper[i+1:], 10, 64)
+	if !strings.Contains(upper, "EPSG") || err != nil || code == 0 {
+		return 0, false, fmt.Errorf("%w: %s", ErrUnsupportedProjection, uri)
+	}
+	if err := basic.CheckSRID(code); err != nil {
+		return 0, false, fmt.Errorf("%w: %v", ErrUnsupportedProjection, err)
+	}
+	// EPSG:4326定义的轴顺序为纬度在前
+	return code, code == util.WGS84, nil
+}
+
+// MaxZoom 返回最大缩放级别
+func (s *TileMatrixSet) MaxZoom() uint32 {
+	return uint32(len(s.Matrices) - 1)
+}
+
+// Bounds 返回0级瓦片矩阵覆盖的范围 minx, miny, maxx, maxy
+func (s *TileMatrixSet) Bounds() [4]float64 {
+	m := s.Matrices[0]
+	return [4]float64{
+		m.Origin[0], m.Origin[1] - m.TileHeight*float64(m.MatrixHeight),
+		m.Origin[0] + m.TileWidth*float64(m.MatrixWidth), m.Origin[1],
+	}
+}
+
+// matrix 返回缩放级别的瓦片矩阵
+func (s *TileMatrixSet) matrix(z uint32) (*TileMatrix, bool) {
+	if int(z) >= len(s.Matrices) {
+		return nil, false
+	}
+	return &s.Matrices[z], true
+}
+
+// tileExtent 返回瓦片的范围，顺序与Tile.GetExtent相同：西、北、东、南
+func (s *TileMatrixSet) tileExtent(z, x, y uint32) *gen.Extent {
+	m, ok := s.matrix(z)
+	if !ok {
+		m = &s.Matrices[len(s.Matrices)-1]
+	}
+	minx := m.Origin[0] + float64(x)*m.TileWidth
+	maxy := m.Origin[1] - float64(y)*m.TileHeight
+	return &gen.Extent{minx, maxy, minx + m.TileWidth, maxy - m.TileHeight}
+}
+
+// TileRange 返回缩放级别上与范围相交的瓦片行列号范围 minx, miny, maxx, maxy
+// bounds 为网格坐标系下的 minx, miny, maxx, maxy，没有相交的瓦片时返回false
+func (s *TileMatrixSet) TileRange(z uint32, bounds [4]float64) ([4]uint32, bool) {
+	m, ok := s.matrix(z)
+	if !ok || bounds[0] > bounds[2] || bounds[1] > bounds[3] {
+		return [4]uint32{}, false
+	}
+
+	// 落在瓦片边界上的最大坐标属于前一个瓦片
+	minCol := math.Floor((bounds[0] - m.Origin[0]) / m.TileWidth)
+	maxCol := math.Ceil((bounds[2]-m.Origin[0])/m.TileWidth) - 1
+	minRow := math.Floor((m.Origin[1] - bounds[3]) / m.TileHeight)
+	maxRow := math.Ceil((m.Origin[1]-bounds[1])/m.TileHeight) - 1
+	maxCol, maxRow = max(maxCol, minCol), max(maxRow, minRow)
+
+	if maxCol < 0 || maxRow < 0 || minCol >= float64(m.MatrixWidth) || minRow >= float64(m.MatrixHeight) {
+		return [4]uint32{}, false
+	}
+	return [4]uint32{
+		uint32(max(minCol, 0)), uint32(max(minRow, 0)),
+		uint32(min(maxCol, float64(m.MatrixWidth-1))), uint32(min(maxRow, float64(m.MatrixHeight-1))),
+	}, true
+}
+
+// isQuadTree 判断每个瓦片是否正好由下一级的2×2个瓦片组成
+func (s *TileMatrixSet) isQuadTree() bool {
+	// 由比例尺换算的分辨率有舍入误差
+	const eps = 1e-6
+	for z := 1; z < len(s.Matrices); z++ {
+		p, c := s.Matrices[z-1], s.Matrices[z]
+		if c.MatrixWidth != p.MatrixWidth*2 || c.MatrixHeight != p.MatrixHeight*2 ||
+			math.Abs(c.TileWidth*2-p.TileWidth) > eps*p.TileWidth ||
+			math.Abs(c.TileHeight*2-p.TileHeight) > eps*p.TileHeight ||
+			math.Abs(c.Origin[0]-p.Origin[0]) > eps*p.TileWidth ||
+			math.Abs(c.Origin[1]-p.Origin[1]) > eps*p.TileHeight {
+			return false
+		}
+	}
+	return true
+}
+
+// parent 返回包含瓦片中心的上一级瓦片
+func (s *TileMatrixSet) parent(z, x, y uint32) (uint32, uint32, bool) {
+	if z == 0 {
+		return 0, 0, false
+	}
+	ext := s.tileExtent(z, x, y)
+	cx, cy := (ext.MinX()+ext.MaxX())/2, (ext.MinY()+ext.MaxY())/2
+	r, ok := s.TileRange(z-1, [4]float64{cx, cy, cx, cy})
+	return r[0], r[1], ok
+}
+
+// children 返回中心落在瓦片内的下一级瓦片范围
+func (s *TileMatrixSet) children(z, x, y uint32) ([4]uint32, bool) {
+	c, ok := s.matrix(z + 1)
+	if !ok {
+		return [4]uint32{}, false
+	}
+	ext := s.tileExtent(z, x, y)
+	// 向内收缩半个子瓦片，只保留中心在瓦片内的子瓦片
+	dx, dy := c.TileWidth/2, c.TileHeight/2
+	return s.TileRange(z+1, [4]float64{ext.MinX() + dx, ext.MaxY() + dy, ext.MaxX() - dx, ext.MinY() - dy})
+}
+
+// check 检查矩阵集是否支持生成的级别，自底向上和超级别生成要求网格为四叉树
+func (s *TileMatrixSet) check(maxZoom uint32, quadTree bool) error {
+	if maxZoom > s.MaxZoom() {
+		return fmt.Errorf("%w: %s 没有 %d 级瓦片矩阵", ErrInvalidTileMatrixSet, s.ID, maxZoom)
+	}
+	if quadTree && !s.isQuadTree() {
+		return fmt.Errorf("%w: %s 不是四叉树网格，不支持自底向上和超级别生成", ErrInvalidTileMatrixSet, s.ID)
+	}
+	return nil
+}
+
+// srsSRID 返回Config.SRS对应的SRID，支持WGS84_PROJ4、GMERC_PROJ4和"EPSG:代码"
+func srsSRID(srs string) (uint64, bool) {
+	switch srs {
+	case WGS84_PROJ4:
+		return util.WGS84, true
+	case GMERC_PROJ4:
+		return util.WebMercator, true
+	}
+	if code, ok := strings.CutPrefix(strings.ToUpper(strings.TrimSpace(srs)), "EPSG:"); ok {
+		srid, err := strconv.ParseUint(code, 10, 64)
+		return srid, err == nil && srid > 0
+	}
+	return 0, false
+}
+
+// transformBounds 将from坐标系的范围转换到to坐标系，沿四条边取样计算外包框
+// 经纬度转换到Web墨卡托时纬度截断到墨卡托的范围
+func transformBounds(from, to uint64, box [4]float64) ([4]float64, error) {
+	if from == to {
+		return box, nil
+	}
+	if from == util.WGS84 && to == util.WebMercator {
+		box[1] = max(box[1], -webmercator.MAX_LATITUDE)
+		box[3] = min(box[3], webmercator.MAX_LATITUDE)
+	}
+
+	const samples = 8
+	result := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for i := 0; i <= samples; i++ {
+		f := float64(i) / samples
+		x := box[0] + (box[2]-box[0])*f
+		y := box[1] + (box[3]-box[1])*f
+		for _, pt := range [][2]float64{{x, box[1]}, {x, box[3]}, {box[0], y}, {box[2], y}} {
+			npt, err := basic.TransformPoint(from, to, pt)
+			if err != nil {
+				return box, err
+			}
+			result = [4]float64{min(result[0], npt[0]), min(result[1], npt[1]), max(result[2], npt[0]), max(result[3], npt[1])}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,216 @@
+package tile
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// TestTileMatrixSet_WorldCRS84Quad 测试经纬度网格中的瓦片范围、像素转换和父子瓦片
+func TestTileMatrixSet_WorldCRS84Quad(t *testing.T) {
+	tile := NewTileInMatrixSet(WorldCRS84Quad, 0, 1, 0, DefaultTileBuffer, 4096, DefaultEpislon)
+	if got := tile.GetExtent().Extent(); got != [4]float64{0, 90, 180, -90} {
+		t.Errorf("GetExtent() = %v, want [0 90 180 -90]", got)
+	}
+	if got := tile.Bounds(); got != [4]float64{0, -90, 180, 90} {
+		t.Errorf("Bounds() = %v, want [0 -90 180 90]", got)
+	}
+	if tile.SRID() != 4326 || tile.Lat != 90 || tile.Long != 0 {
+		t.Errorf("SRID() = %v, Lat/Long = %v/%v", tile.SRID(), tile.Lat, tile.Long)
+	}
+	if got := tile.ZRes(); got != 180.0/4096 {
+		t.Errorf("ZRes() = %v, want %v", got, 180.0/4096)
+	}
+
+	px, err := tile.ToPixel(4326, [2]float64{90, 0})
+	if err != nil || px[0] != 2048 || px[1] != 2048 {
+		t.Errorf("ToPixel() = %v, %v, want [2048 2048]", px, err)
+	}
+	pt, err := tile.FromPixel(4326, px)
+	if err != nil || pt != [2]float64{90, 0} {
+		t.Errorf("FromPixel() = %v, %v, want [90 0]", pt, err)
+	}
+
+	children := tile.GetChildren()
+	if len(children) != 4 || children[0].X != 2 || children[0].Y != 0 || children[3].X != 3 || children[3].Y != 1 {
+		t.Fatalf("GetChildren() = %v", children)
+	}
+	for _, c := range children {
+		if p := c.GetParent(); p == nil || p.Z != 0 || p.X != 1 || p.Y != 0 || p.MatrixSet() != WorldCRS84Quad {
+			t.Errorf("%s GetParent() = %v, want 0/1/0", c.ToString(), p)
+		}
+	}
+	if NewTile(0, 0, 0).MatrixSet() != WebMercatorQuad {
+		t.Error("默认瓦片应使用WebMercatorQuad")
+	}
+}
+
+// TestTileMatrixSet_TileRange 测试范围对应的瓦片行列号
+func TestTileMatrixSet_TileRange(t *testing.T) {
+	tests := []struct {
+		z      uint32
+		bounds [4]float64
+		want   [4]uint32
+		ok     bool
+	}{
+		{0, [4]float64{-180, -90, 180, 90}, [4]uint32{0, 0, 1, 0}, true},
+		{1, [4]float64{-10, -10, 10, 10}, [4]uint32{1, 0, 2, 1}, true},
+		// 边界上的最大坐标属于前一个瓦片
+		{1, [4]float64{-90, 0, 0, 90}, [4]uint32{1, 0, 1, 0}, true},
+		{2, [4]float64{-500, -500, 500, 500}, [4]uint32{0, 0, 7, 3}, true},
+		{1, [4]float64{200, 0, 210, 10}, [4]uint32{}, false},
+		{MaxZ + 1, [4]float64{-10, -10, 10, 10}, [4]uint32{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := WorldCRS84Quad.TileRange(tt.z, tt.bounds)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("TileRange(%d, %v) = %v, %v, want %v, %v", tt.z, tt.bounds, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestTileMatrixSet_NotQuadTree 测试非四叉树网格的子瓦片
+func TestTileMatrixSet_NotQuadTree(t *testing.T) {
+	set := &TileMatrixSet{ID: "thirds", SRID: 3857, Matrices: []TileMatrix{
+		{ID: "0", Origin: [2]float64{0, 300}, TileWidth: 300, TileHeight: 300, MatrixWidth: 1, MatrixHeight: 1},
+		{ID: "1", Origin: [2]float64{0, 300}, TileWidth: 100, TileHeight: 100, MatrixWidth: 3, MatrixHeight: 3},
+	}}
+	if set.isQuadTree() || !WorldCRS84Quad.isQuadTree() || !WebMercatorQuad.isQuadTree() {
+		t.Error("isQuadTree() 结果错误")
+	}
+	if children := NewTileInMatrixSet(set, 0, 0, 0, 0, 4096, 0).GetChildren(); len(children) != 9 {
+		t.Errorf("GetChildren() = %d 个, want 9", len(children))
+	}
+	if p := NewTileInMatrixSet(set, 1, 2, 1, 0, 4096, 0).GetParent(); p == nil || p.X != 0 || p.Y != 0 {
+		t.Errorf("GetParent() = %v, want 0/0/0", p)
+	}
+
+	for _, config := range []*Config{
+		{MaxZoom: 1, BottomUp: true},
+		{MaxZoom: 0, OverzoomMaxZoom: 1},
+		{MaxZoom: 2},
+	} {
+		config.Provider = &MockProvider{srid: 3857}
+		config.TileMatrixSet = set
+		if err := NewTiler(config).Tiler(); !errors.Is(err, ErrInvalidTileMatrixSet) {
+			t.Errorf("Tiler() 错误 = %v, want %v", err, ErrInvalidTileMatrixSet)
+		}
+	}
+}
+
+// TestParseTileMatrixSet 测试解析OGC TileMatrixSet JSON
+func TestParseTileMatrixSet(t *testing.T) {
+	// EPSG:4326的原点按纬度、经度排列，矩阵未按级别排序，0级只给出比例尺
+	set, err := ParseTileMatrixSet([]byte(`{
+		"id": "WorldGeodetic",
+		"crs": "http://www.opengis.net/def/crs/EPSG/0/4326",
+		"tileMatrices": [
+			{"id": "1", "cellSize": 0.3515625, "cornerOfOrigin": "topLeft", "pointOfOrigin": [90, -180],
+			 "tileWidth": 256, "tileHeight": 256, "matrixWidth": 4, "matrixHeight": 2},
+			{"id": "0", "scaleDenominator": 279541132.0143589, "pointOfOrigin": [90, -180],
+			 "tileWidth": 256, "tileHeight": 256, "matrixWidth": 2, "matrixHeight": 1}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseTileMatrixSet() 错误 = %v", err)
+	}
+	if set.ID != "WorldGeodetic" || set.SRID != 4326 || set.MaxZoom() != 1 || set.Matrices[0].ID != "0" {
+		t.Fatalf("ParseTileMatrixSet() = %+v", set)
+	}
+	if m := set.Matrices[0]; m.Origin != [2]float64{-180, 90} || math.Abs(m.TileWidth-180) > 1e-6 {
+		t.Errorf("0级矩阵 = %+v, want 原点(-180, 90), 瓦片宽180", m)
+	}
+	if !set.isQuadTree() {
+		t.Error("isQuadTree() = false, want true")
+	}
+
+	// 左下角原点和对象形式的坐标系
+	set, err = ParseTileMatrixSet([]byte(`{
+		"id": "local",
+		"crs": {"uri": "http://www.opengis.net/def/crs/EPSG/0/3857"},
+		"orderedAxes": ["E", "N"],
+		"tileMatrices": [
+			{"id": "a", "cellSize": 10, "cornerOfOrigin": "bottomLeft", "pointOfOrigin": [1000, 2000],
+			 "tileWidth": 100, "tileHeight": 100, "matrixWidth": 3, "matrixHeight": 2}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseTileMatrixSet() 错误 = %v", err)
+	}
+	if got := set.Bounds(); got != [4]float64{1000, 2000, 4000, 4000} {
+		t.Errorf("Bounds() = %v, want [1000 2000 4000 4000]", got)
+	}
+
+	invalid := []struct {
+		json string
+		err  error
+	}{
+		{`{"crs": "EPSG:3857", "tileMatrices": [`, ErrInvalidTileMatrixSet},
+		{`{"crs": "EPSG:3857", "tileMatrices": []}`, ErrInvalidTileMatrixSet},
+		{`{"crs": "http://www.opengis.net/def/crs/OGC/0/Unknown", "tileMatrices": []}`, ErrUnsupportedProjection},
+		{`{"crs": "EPSG:3857", "tileMatrices": [{"id": "0", "cellSize": 1, "tileWidth": 256, "tileHeight": 256,
+			"matrixWidth": 2, "matrixHeight": 1, "variableMatrixWidths": [{"coalesce": 2, "minTileRow": 0, "maxTileRow": 0}]}]}`, ErrInvalidTileMatrixSet},
+	}
+	for _, tt := range invalid {
+		if _, err := ParseTileMatrixSet([]byte(tt.json)); !errors.Is(err, tt.err) {
+			t.Errorf("ParseTileMatrixSet(%.40s) 错误 = %v, want %v", tt.json, err, tt.err)
+		}
+	}
+}
+
+// TestTiler_TileMatrixSet 测试在经纬度网格上生成瓦片
+func TestTiler_TileMatrixSet(t *testing.T) {
+	provider, err := NewMemoryProvider(4326, []*Layer{{Name: "points", Features: []*geom.Feature{
+		{Geometry: basic.Point{100, 45}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := &MockExporter{}
+	tiler := NewTiler(&Config{
+		Provider:      provider,
+		Exporter:      exporter,
+		OutputDir:     t.TempDir(),
+		MaxZoom:       1,
+		Concurrency:   2,
+		TileMatrixSet: WorldCRS84Quad,
+	})
+
+	if minx, miny, maxx, maxy := tiler.TileBounds(0); minx != 0 || miny != 0 || maxx != 1 || maxy != 0 {
+		t.Errorf("TileBounds(0) = %v %v %v %v, want 0 0 1 0", minx, miny, maxx, maxy)
+	}
+	if got := tiler.Count([]uint32{0, 1}); got != 10 {
+		t.Errorf("Count() = %v, want 10", got)
+	}
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	saved := map[string]*Layer{}
+	for _, d := range exporter.GetSavedTiles() {
+		saved[d.Tile.ToString()] = d.Layers[0]
+		if d.Tile.MatrixSet() != WorldCRS84Quad {
+			t.Errorf("瓦片 %s 的矩阵集 = %v", d.Tile.ToString(), d.Tile.MatrixSet().ID)
+		}
+	}
+	if len(saved) != 2 || saved["1.0.0"] == nil || saved["3.0.1"] == nil {
+		t.Fatalf("导出瓦片 = %v, want 1.0.0和3.0.1", saved)
+	}
+	if len(saved["1.0.0"].Features) != 1 || len(saved["3.0.1"].Features) != 1 {
+		t.Errorf("要素数 = %d, %d, want 1, 1", len(saved["1.0.0"].Features), len(saved["3.0.1"].Features))
+	}
+
+	// 重新生成只涉及变化区域所在的瓦片
+	retiler := NewTiler(&Config{Provider: provider, MaxZoom: 1, TileMatrixSet: WorldCRS84Quad})
+	var tasks []string
+	for _, task := range retiler.dirtyTasks([]*[4]float64{{100, 40, 101, 41}}) {
+		tasks = append(tasks, XYZToStringId(task.x, task.y, task.z))
+	}
+	if len(tasks) != 2 || tasks[0] != "1.0.0" || tasks[1] != "3.0.1" {
+		t.Errorf("dirtyTasks() = %v, want [1.0.0 3.0.1]", tasks)
+	}
+}
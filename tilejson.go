@@ -0,0 +1,141 @@
+package tile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TileJSONFileName TileJSON文件名，位于OutputDir下
+const TileJSONFileName = "tiles.json"
+
+// TileJSONVersion 生成的TileJSON规范版本
+const TileJSONVersion = "3.0.0"
+
+// TileJSONOptions TileJSON中无法由配置推导的字段
+type TileJSONOptions struct {
+	Name        string
+	Description string
+	Attribution string
+	// Version 瓦片集版本
+	Version string
+	// Tiles 瓦片URL模板，为空时使用相对路径"{z}/{x}/{y}.扩展名"
+	Tiles []string
+}
+
+// TileJSON TileJSON 3.0.0元数据
+type TileJSON struct {
+	TileJSON     string        `json:"tilejson"`
+	Tiles        []string      `json:"tiles"`
+	VectorLayers []VectorLayer `json:"vector_layers"`
+	Name         string        `json:"name,omitempty"`
+	Description  string        `json:"description,omitempty"`
+	Attribution  string        `json:"attribution,omitempty"`
+	Version      string        `json:"version,omitempty"`
+	Scheme       string        `json:"scheme"`
+	MinZoom      uint32        `json:"minzoom"`
+	MaxZoom      uint32        `json:"maxzoom"`
+	// Bounds 经纬度范围[minLon, minLat, maxLon, maxLat]
+	Bounds [4]float64 `json:"bounds"`
+	// Center 范围中心和最小级别[lon, lat, zoom]
+	Center [3]float64 `json:"center"`
+}
+
+// ReadTileJSON 读取TileJSON文件
+func ReadTileJSON(path string) (*TileJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tj TileJSON
+	if err := json.Unmarshal(data, &tj); err != nil {
+		return nil, fmt.Errorf("解析TileJSON失败: %w", err)
+	}
+	return &tj, nil
+}
+
+// TileJSON 返回瓦片集的TileJSON，vector_layers为已导出瓦片中出现的图层、属性字段及级别范围。
+// 范围为Config.Bound对应的经纬度范围，级别范围包含超级别生成的级别
+func (m *Tiler) TileJSON() *TileJSON {
+	tj := newTileJSON(m.tilesetMetadata(), m.layers.list())
+	if opts := m.config.TileJSON; opts != nil {
+		tj.Name, tj.Description, tj.Attribution, tj.Version = opts.Name, opts.Description, opts.Attribution, opts.Version
+		tj.Tiles = append(tj.Tiles, opts.Tiles...)
+	}
+	if len(tj.Tiles) == 0 {
+		tj.Tiles = []string{"{z}/{x}/{y}." + tileURLExtension(m.exporter())}
+	}
+	return tj
+}
+
+// newTileJSON 由瓦片集元数据生成TileJSON，不包含瓦片URL
+func newTileJSON(meta TilesetMetadata, layers []VectorLayer) *TileJSON {
+	return &TileJSON{
+		TileJSON:     TileJSONVersion,
+		VectorLayers: layers,
+		Scheme:       "xyz",
+		MinZoom:      meta.MinZoom,
+		MaxZoom:      meta.MaxZoom,
+		Bounds:       meta.Bounds,
+		Center: [3]float64{
+			(meta.Bounds[0] + meta.Bounds[2]) / 2,
+			(meta.Bounds[1] + meta.Bounds[3]) / 2,
+			float64(meta.MinZoom),
+		},
+	}
+}
+
+// writeTileJSON 配置了Config.TileJSON时将TileJSON写入OutputDir。
+// 断点续传或增量重新生成时只处理了部分瓦片，先合并已有文件中的vector_layers
+func (m *Tiler) writeTileJSON() error {
+	if m.config.TileJSON == nil {
+		return nil
+	}
+	path := filepath.Join(m.config.OutputDir, TileJSONFileName)
+	if m.config.Resume || m.retiling {
+		old, err := ReadTileJSON(path)
+		switch {
+		case err == nil:
+			m.layers.merge(old.VectorLayers)
+		case !os.IsNotExist(err):
+			return fmt.Errorf("读取%s失败: %w", TileJSONFileName, err)
+		}
+	}
+
+	data, err := json.MarshalIndent(m.TileJSON(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.config.OutputDir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入%s失败: %w", TileJSONFileName, err)
+	}
+	return nil
+}
+
+// tileURLExtension 返回瓦片URL的扩展名，MBTiles和PMTiles中的瓦片为MVT
+func tileURLExtension(exporter Exporter) string {
+	switch e := exporter.(type) {
+	case *SinkExporter:
+		return e.Encoder.Extension()
+	case *MBTilesExporter, *PMTilesExporter:
+		return NewMVTExporter().Extension()
+	case nil:
+		return ""
+	}
+	return strings.TrimPrefix(exporter.Extension(), ".")
+}
+
+// writePartialTileJSON 启用Resume时在生成未完成时写入TileJSON，失败只记录警告
+func (m *Tiler) writePartialTileJSON() {
+	if !m.config.Resume {
+		return
+	}
+	if err := m.writeTileJSON(); err != nil && m.config.Progress != nil {
+		m.config.Progress.Warn("TileJSON未写入: %v", err)
+	}
+}
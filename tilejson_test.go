@@ -0,0 +1,178 @@
+package tile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+
+	"github.com/flywave/go-vector-tiler/basic"
+)
+
+// tileJSONProvider 返回包含城市和道路图层的Provider
+func tileJSONProvider(t *testing.T) Provider {
+	t.Helper()
+	provider, err := NewMemoryProvider(4326, []*Layer{
+		{Name: "cities", Features: []*geom.Feature{
+			{Geometry: basic.Point{116.4, 39.9}, Properties: map[string]interface{}{"name": "北京", "pop": 2189}},
+			{Geometry: basic.Point{116.5, 39.8}, Properties: map[string]interface{}{"name": "通州", "pop": "unknown"}},
+		}},
+		{Name: "roads", Features: []*geom.Feature{
+			{Geometry: basic.Line{{116.3, 39.9}, {116.5, 39.9}}, Properties: map[string]interface{}{"toll": true}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+// TestTiler_TileJSON 测试生成完成后写入的TileJSON
+func TestTiler_TileJSON(t *testing.T) {
+	dir := t.TempDir()
+	tiler := NewTiler(&Config{
+		Provider:     tileJSONProvider(t),
+		Exporter:     &MockExporter{},
+		OutputDir:    dir,
+		MinZoom:      2,
+		MaxZoom:      5,
+		Bound:        &[4]float64{110, 30, 120, 40},
+		LayerOptions: map[string]*LayerOptions{"roads": {MinZoom: 4}},
+		TileJSON:     &TileJSONOptions{Name: "beijing", Attribution: "test"},
+	})
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+
+	tj, err := ReadTileJSON(filepath.Join(dir, TileJSONFileName))
+	if err != nil {
+		t.Fatalf("ReadTileJSON() 错误 = %v", err)
+	}
+	if tj.TileJSON != "3.0.0" || tj.Name != "beijing" || tj.Attribution != "test" || tj.Scheme != "xyz" {
+		t.Errorf("TileJSON = %+v", tj)
+	}
+	if len(tj.Tiles) != 1 || tj.Tiles[0] != "{z}/{x}/{y}.test" {
+		t.Errorf("Tiles = %v", tj.Tiles)
+	}
+	if tj.MinZoom != 2 || tj.MaxZoom != 5 {
+		t.Errorf("级别 = %d-%d, want 2-5", tj.MinZoom, tj.MaxZoom)
+	}
+	if tj.Bounds != [4]float64{110, 30, 120, 40} || tj.Center != [3]float64{115, 35, 2} {
+		t.Errorf("Bounds = %v, Center = %v", tj.Bounds, tj.Center)
+	}
+
+	want := []VectorLayer{
+		{ID: "cities", Fields: map[string]string{"name": "String", "pop": "Mixed"}, MinZoom: 2, MaxZoom: 5},
+		{ID: "roads", Fields: map[string]string{"toll": "Boolean"}, MinZoom: 4, MaxZoom: 5},
+	}
+	if len(tj.VectorLayers) != len(want) {
+		t.Fatalf("VectorLayers = %+v, want %+v", tj.VectorLayers, want)
+	}
+	for i, l := range tj.VectorLayers {
+		w := want[i]
+		if l.ID != w.ID || l.MinZoom != w.MinZoom || l.MaxZoom != w.MaxZoom || len(l.Fields) != len(w.Fields) {
+			t.Errorf("VectorLayers[%d] = %+v, want %+v", i, l, w)
+			continue
+		}
+		for k, typ := range w.Fields {
+			if l.Fields[k] != typ {
+				t.Errorf("%s.%s = %q, want %q", l.ID, k, l.Fields[k], typ)
+			}
+		}
+	}
+}
+
+// TestTiler_TileJSONDefaults 测试未配置TileJSON时不写文件，以及默认URL模板
+func TestTiler_TileJSONDefaults(t *testing.T) {
+	dir := t.TempDir()
+	tiler := NewTiler(&Config{Provider: tileJSONProvider(t), Exporter: &MockExporter{}, OutputDir: dir, MaxZoom: 1})
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, TileJSONFileName)); !os.IsNotExist(err) {
+		t.Errorf("未配置TileJSON时不应写入文件: %v", err)
+	}
+	if tj := tiler.TileJSON(); len(tj.VectorLayers) != 2 || tj.Bounds[1] < -86 || tj.Bounds[3] > 86 {
+		t.Errorf("TileJSON() = %+v", tj)
+	}
+
+	tests := []struct {
+		exporter Exporter
+		want     string
+	}{
+		{NewMVTExporter(), "{z}/{x}/{y}.mvt"},
+		{NewSinkExporter(NewGeoJSONExporter(), NewMemorySink()), "{z}/{x}/{y}.geojson"},
+		{&MBTilesExporter{}, "{z}/{x}/{y}.mvt"},
+	}
+	for _, tt := range tests {
+		tiler := NewTiler(&Config{Exporter: tt.exporter})
+		if got := tiler.TileJSON().Tiles; len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%T Tiles = %v, want %v", tt.exporter, got, tt.want)
+		}
+	}
+}
+
+// TestTiler_TileJSONRetile 测试重新生成时合并已有文件中的图层
+func TestTiler_TileJSONRetile(t *testing.T) {
+	dir := t.TempDir()
+	old := TileJSON{VectorLayers: []VectorLayer{
+		{ID: "water", Fields: map[string]string{"kind": "String"}, MinZoom: 0, MaxZoom: 3},
+		{ID: "cities", Fields: map[string]string{"rank": "Number"}, MinZoom: 1, MaxZoom: 1},
+	}}
+	data, _ := json.Marshal(old)
+	if err := os.WriteFile(filepath.Join(dir, TileJSONFileName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tiler := NewTiler(&Config{
+		Provider:  tileJSONProvider(t),
+		Exporter:  &MockExporter{},
+		OutputDir: dir,
+		MinZoom:   2,
+		MaxZoom:   3,
+		TileJSON:  &TileJSONOptions{Tiles: []string{"https://example.com/{z}/{x}/{y}.pbf"}},
+	})
+	if err := tiler.Retile([]*[4]float64{{116, 39, 117, 40}}); err != nil {
+		t.Fatalf("Retile() 错误 = %v", err)
+	}
+
+	tj, err := ReadTileJSON(filepath.Join(dir, TileJSONFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tj.Tiles) != 1 || tj.Tiles[0] != "https://example.com/{z}/{x}/{y}.pbf" {
+		t.Errorf("Tiles = %v", tj.Tiles)
+	}
+	if len(tj.VectorLayers) != 3 {
+		t.Fatalf("VectorLayers = %+v", tj.VectorLayers)
+	}
+	cities, water := tj.VectorLayers[0], tj.VectorLayers[2]
+	if cities.MinZoom != 1 || cities.MaxZoom != 3 || cities.Fields["rank"] != "Number" || cities.Fields["name"] != "String" {
+		t.Errorf("cities = %+v", cities)
+	}
+	if water.ID != "water" || water.MaxZoom != 3 {
+		t.Errorf("water = %+v", water)
+	}
+}
+
+// TestTiler_TileJSONResume 测试断点续传停止时写入TileJSON
+func TestTiler_TileJSONResume(t *testing.T) {
+	dir := t.TempDir()
+	tiler := NewTiler(&Config{
+		Provider:  tileJSONProvider(t),
+		Exporter:  &MockExporter{},
+		OutputDir: dir,
+		MaxZoom:   2,
+		Resume:    true,
+		TileJSON:  &TileJSONOptions{},
+	})
+	tiler.Stop()
+	if err := tiler.Tiler(); err == nil {
+		t.Fatal("停止后Tiler()应返回错误")
+	}
+	if _, err := ReadTileJSON(filepath.Join(dir, TileJSONFileName)); err != nil {
+		t.Errorf("停止后应写入TileJSON: %v", err)
+	}
+}
@@ -1,21 +1,25 @@
 package tile
 
 import (
-	"os"
 	"path/filepath"
+	"sort"
 
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	geo "github.com/flywave/go-geo"
+	"github.com/flywave/go-geom"
 	gen "github.com/flywave/go-geom/general"
 	vec2d "github.com/flywave/go3d/float64/vec2"
 
 	"github.com/flywave/go-vector-tiler/basic"
 	"github.com/flywave/go-vector-tiler/maths/simplify"
 	"github.com/flywave/go-vector-tiler/maths/validate"
+	"github.com/flywave/go-vector-tiler/maths/webmercator"
 	"github.com/flywave/go-vector-tiler/util"
 )
 
@@ -63,6 +67,16 @@ func NewTiler(config *Config) *Tiler {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
+	var gridBound [4]float64
+	if set := config.TileMatrixSet; set != nil {
+		gridBound = set.Bounds()
+		if srid, ok := srsSRID(config.SRS); ok {
+			if b, err := transformBounds(srid, set.SRID, *config.Bound); err == nil {
+				gridBound = b
+			}
+		}
+	}
+
 	return &Tiler{
 		config:     config,
 		ctx:        ctx,
@@ -76,6 +90,8 @@ func NewTiler(config *Config) *Tiler {
 		totalTasks: 0,
 		grid:       grid,
 		bbox:       bbx,
+		gridBound:  gridBound,
+		report:     newReportCollector(),
 	}
 }
 
@@ -102,6 +118,25 @@ type Tiler struct {
 	// Grid相关字段
 	grid *geo.TileGrid
 	bbox *vec2d.Rect
+	// gridBound 使用TileMatrixSet时Config.Bound在网格坐标系下的范围
+	gridBound [4]float64
+
+	// 断点记录，未启用Resume时为nil
+	checkpoint *checkpoint
+	// 是否为增量重新生成，为true时删除没有数据的旧瓦片
+	retiling bool
+
+	// 非FailFast策略下收集的错误
+	tileErrors   []*TileError
+	tileErrorsMu sync.Mutex
+
+	// 统计数据
+	report *reportCollector
+	// 已导出瓦片中的图层，用于TileJSON的vector_layers
+	layers vectorLayerSet
+
+	// 超级别生成范围，未启用时为nil
+	overzoom *overzoomPlan
 }
 
 // getZoomLevels 获取需要处理的缩放级别列表
@@ -122,6 +157,13 @@ func (m *Tiler) Count(zs []uint32) int {
 	c := 0
 	bbx := m.bbox
 	for _, z := range zs {
+		if m.config.TileMatrixSet != nil {
+			minx, miny, maxx, maxy := m.TileBounds(z)
+			if minx <= maxx && miny <= maxy {
+				c += int(maxx-minx+1) * int(maxy-miny+1)
+			}
+			continue
+		}
 		_, rc, _, _ := m.grid.GetAffectedLevelTiles(*bbx, int(z))
 		c += rc[0] * rc[1]
 	}
@@ -129,7 +171,15 @@ func (m *Tiler) Count(zs []uint32) int {
 }
 
 // TileBounds 获取指定缩放级别的瓦片边界
+// 使用TileMatrixSet且该级别没有瓦片时，返回的最小值大于最大值
 func (m *Tiler) TileBounds(z uint32) (uint32, uint32, uint32, uint32) {
+	if set := m.config.TileMatrixSet; set != nil {
+		r, ok := set.TileRange(z, m.gridBound)
+		if !ok {
+			return 1, 1, 0, 0
+		}
+		return r[0], r[1], r[2], r[3]
+	}
 	bbx := m.bbox
 	_, _, iter, _ := m.grid.GetAffectedLevelTiles(*bbx, int(z))
 	bd := iter.GetTileBound()
@@ -142,7 +192,7 @@ func (m *Tiler) Iterator(z uint32) []*Tile {
 	minx, miny, maxx, maxy := m.TileBounds(z)
 	for y := miny; y <= maxy; y++ {
 		for x := minx; x <= maxx; x++ {
-			ts = append(ts, NewTile(uint32(z), uint32(x), uint32(y)))
+			ts = append(ts, NewTileInMatrixSet(m.config.TileMatrixSet, z, x, y, DefaultTileBuffer, DefaultExtent, DefaultEpislon))
 		}
 	}
 	return ts
@@ -155,47 +205,120 @@ func (m *Tiler) Stop() {
 
 // Tiler 生成指定缩放级别的瓦片
 func (m *Tiler) Tiler() error {
+	// 计算总任务数
+	zooms := m.getZoomLevels()
+	m.overzoom = m.planOverzoom(zooms)
+	if err := m.checkMatrixSet(zooms); err != nil {
+		m.cancel()
+		close(m.errChan)
+		return fmt.Errorf("瓦片生成失败: %w", err)
+	}
+	totalTasks := m.count(zooms) + m.overzoom.total()
+
+	if m.config.BottomUp {
+		return m.tilePyramid(zooms, totalTasks)
+	}
+
+	// 加载断点记录
+	if m.config.Resume {
+		path := filepath.Join(m.config.OutputDir, CheckpointFileName)
+		cp, resumed, err := openCheckpoint(path, m.checkpointFingerprint(zooms))
+		if err != nil {
+			m.cancel()
+			close(m.errChan)
+			return fmt.Errorf("瓦片生成失败: %w", err)
+		}
+		m.checkpoint = cp
+		if resumed {
+			atomic.StoreInt64(&m.processed, int64(cp.Len()))
+		}
+	}
+
+	err := m.run(totalTasks, func() { m.generateTasks(zooms) }, m.processTile)
+
+	// 全部完成后删除断点文件，否则保留以便继续
+	if err == nil && atomic.LoadInt64(&m.processed) >= totalTasks {
+		if err := m.checkpoint.Remove(); err != nil && m.config.Progress != nil {
+			m.config.Progress.Warn("删除断点文件失败: %v", err)
+		}
+	} else {
+		m.checkpoint.Close()
+	}
+	return err
+}
+
+// run 启动工作池，由process处理generate产生的任务，并等待全部完成
+// 导出器实现ExporterLifecycle时，开始前调用Open，结束后调用Close
+func (m *Tiler) run(totalTasks int64, generate func(), process func(*tileTask)) (err error) {
 	defer m.cancel()
 	defer close(m.errChan)
 
-	// 计算总任务数
-	zooms := m.getZoomLevels()
-	totalTasks := m.count(zooms)
+	if lifecycle, ok := m.exporter().(ExporterLifecycle); ok {
+		if err := lifecycle.Open(m.ctx, m.tilesetMetadata()); err != nil {
+			return fmt.Errorf("瓦片生成失败: %w", err)
+		}
+		defer func() {
+			if cerr := lifecycle.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("瓦片生成失败: %w", cerr)
+			}
+		}()
+	}
+
 	atomic.StoreInt64(&m.totalTasks, totalTasks)
 
+	m.report.begin()
+	defer m.report.finish()
+
 	if m.config.Progress != nil {
 		m.config.Progress.Init(int(totalTasks))
+		if n := m.checkpoint.Len(); n > 0 {
+			m.config.Progress.Log("从断点继续，跳过已完成的 %d 个瓦片", n)
+		}
 	}
 
 	// 启动工作池
-	m.startWorkers()
+	m.startWorkers(process)
 
 	// 生成任务
-	go m.generateTasks(zooms)
+	go generate()
 
 	// 等待所有任务完成
 	m.wg.Wait()
 
 	// 检查是否有错误发生
 	if m.firstError != nil {
+		m.writePartialTileJSON()
 		return fmt.Errorf("瓦片生成失败: %w", m.firstError)
 	}
+	if err := m.ctx.Err(); err != nil {
+		m.writePartialTileJSON()
+		return fmt.Errorf("瓦片生成已停止: %w", err)
+	}
+	if err := m.finalizeExporter(); err != nil {
+		return fmt.Errorf("瓦片生成失败: %w", err)
+	}
+	if err := m.writeTileJSON(); err != nil {
+		return fmt.Errorf("瓦片生成失败: %w", err)
+	}
+	if report := m.errorReport(); report != nil {
+		return report
+	}
 	return nil
 }
 
 // startWorkers 启动工作池
-func (m *Tiler) startWorkers() {
+func (m *Tiler) startWorkers(process func(*tileTask)) {
 	for i := 0; i < m.config.Concurrency; i++ {
 		m.wg.Add(1)
 		go func() {
 			defer m.wg.Done()
-			m.worker()
+			m.worker(process)
 		}()
 	}
 }
 
 // worker 工作函数
-func (m *Tiler) worker() {
+func (m *Tiler) worker(process func(*tileTask)) {
 	for {
 		select {
 		case <-m.ctx.Done():
@@ -204,7 +327,7 @@ func (m *Tiler) worker() {
 			if !ok {
 				return
 			}
-			m.processTile(task)
+			process(task)
 		}
 	}
 }
@@ -230,10 +353,14 @@ func (m *Tiler) generateTasks(zooms []int) {
 
 		for y := miny; y <= maxy; y++ {
 			for x := minx; x <= maxx; x++ {
+				task := &tileTask{z: z, x: x, y: y}
+				if m.checkpoint.Done(task) {
+					continue
+				}
 				select {
 				case <-m.ctx.Done():
 					return
-				case m.taskQueue <- &tileTask{z: z, x: x, y: y}:
+				case m.taskQueue <- task:
 				}
 			}
 		}
@@ -243,97 +370,417 @@ func (m *Tiler) generateTasks(zooms []int) {
 // 处理单个瓦片
 func (m *Tiler) processTile(task *tileTask) {
 	// 创建瓦片对象
-	t := NewTile(task.z, task.x, task.y)
+	t := m.newTile(task)
 
 	// 更新进度
+	m.advance(task)
+
+	timer := stageTimer{}
+	defer m.report.addStages(timer)
+
+	resultLayers, ok := m.tileLayers(task, timer)
+	if !ok {
+		return
+	}
+
+	// 超出最大级别的子孙瓦片先于当前瓦片完成，断点记录当前瓦片时子孙瓦片已经生成
+	m.overzoomDescendants(task, resultLayers)
+	m.finishTile(task, t, resultLayers, timer)
+}
+
+// tileLayers 从Provider获取瓦片数据并处理在当前级别输出的要素
+// 返回false表示因错误或取消需要放弃整个瓦片
+func (m *Tiler) tileLayers(task *tileTask, timer stageTimer) ([]*Layer, bool) {
+	return m.streamLayers(task, timer, 1, func(name string) (layerSettings, func(*geom.Feature) bool, bool) {
+		settings, visible := m.layerSettings(name, task.z)
+		featureZoom, filter := m.featureZoomFunc(name), m.featureFilter(name)
+		keep := func(f *geom.Feature) bool {
+			return filter(f) && featureVisible(featureZoom, f, task.z)
+		}
+		return settings, keep, visible
+	})
+}
+
+// advance 更新进度
+func (m *Tiler) advance(task *tileTask) {
 	processed := atomic.AddInt64(&m.processed, 1)
 	if m.config.Progress != nil {
 		m.config.Progress.Update(int(processed), int(atomic.LoadInt64(&m.totalTasks)))
 		m.config.Progress.Log(fmt.Sprintf("处理瓦片 z=%d x=%d y=%d (%d/%d)",
 			task.z, task.x, task.y, processed, atomic.LoadInt64(&m.totalTasks)))
 	}
+}
 
-	// 获取数据
-	layers := m.config.Provider.GetDataByTile(t)
-	if len(layers) == 0 {
+// finishTile 对处理后的图层执行预算处理并导出瓦片，没有图层时按空瓦片处理
+func (m *Tiler) finishTile(task *tileTask, t *Tile, resultLayers []*Layer, timer stageTimer) {
+	// 瓦片预算
+	var budget budgetResult
+	if m.budgetEnabled() && len(resultLayers) > 0 {
+		var err error
+		start := time.Now()
+		resultLayers, budget, err = m.fitBudget(t, resultLayers)
+		timer.since(StageBudget, start)
+		if err != nil {
+			m.reportError(newTileError(task, StageBudget, err))
+			return
+		}
+		if budget.applied() && m.config.Progress != nil {
+			m.config.Progress.Log(budgetMessage(task, budget))
+		}
+	}
+
+	if len(resultLayers) == 0 {
+		m.finishEmptyTile(task, t)
 		return
 	}
 
-	// 处理每个图层的要素
-	var resultLayers []*Layer
-	for _, layer := range layers {
-		newLayer := &Layer{Name: layer.Name}
+	// 导出瓦片
+	start := time.Now()
+	size, err := m.exportTile(resultLayers, t)
+	timer.since(StageExport, start)
+	if err != nil {
+		m.reportError(newTileError(task, StageExport, err))
+		return
+	}
 
-		for _, feature := range layer.Features {
-			geom := feature.Geometry
+	m.report.addTile(task.z, size, resultLayers, budget)
+	m.layers.add(task.z, resultLayers)
+	m.markDone(task)
+}
 
-			// 坐标转换
-			if m.config.Provider.GetSrid() != util.WebMercator {
-				var err error
-				if geom, err = basic.ToWebMercator(m.config.Provider.GetSrid(), geom); err != nil {
-					m.reportError(fmt.Errorf("坐标转换失败 (z=%d, x=%d, y=%d): %w",
-						task.z, task.x, task.y, err))
-					continue
-				}
-			}
+// layerFilter 返回图层的处理参数和要素过滤函数，图层不输出时返回false
+type layerFilter func(name string) (layerSettings, func(*geom.Feature) bool, bool)
+
+// errAbortTile 要素处理出错且策略要求放弃瓦片时中止读取
+var errAbortTile = errors.New("abort tile")
+
+// streamLayers 从Provider逐个读取要素并处理，按图层名汇总为图层
+// bufferScale 为各图层缓冲区的放大倍数，自底向上生成时保留逐级合并需要的缓冲区。
+// 返回false表示因错误或取消需要放弃整个瓦片
+func (m *Tiler) streamLayers(task *tileTask, timer stageTimer, bufferScale uint64, filter layerFilter) ([]*Layer, bool) {
+	b := &layerBuilder{
+		m:           m,
+		task:        task,
+		timer:       timer,
+		filter:      filter,
+		bufferScale: bufferScale,
+		srid:        m.streamingProvider().GetSrid(),
+		grid:        m.gridSRID(),
+		states:      make(map[string]*layerState),
+	}
 
-			// 几何简化
-			if task.z < uint32(m.config.SimplificationMaxZoom) && m.config.SimplifyGeometries {
-				geom = simplify.SimplifyGeometry(geom, t.ZEpislon())
-			}
+	start := time.Now()
+	err := m.streamingProvider().Features(m.ctx, m.queryTile(task, bufferScale), b.add)
+	// Provider耗时不含要素处理的耗时
+	timer[StageProvider] += time.Since(start) - b.busy
+	if err != nil {
+		// 已报告的要素错误和运行取消不再重复报告
+		if !errors.Is(err, errAbortTile) && m.ctx.Err() == nil {
+			m.reportError(newTileError(task, StageProvider, err))
+		}
+		return nil, false
+	}
+	return b.layers(), true
+}
 
-			// 几何预处理
-			geom = PrepareGeo(geom, t.extent, float64(m.config.TileExtent))
+// queryTile 返回查询Provider使用的瓦片
+// 缓冲区取默认配置和各图层选项中占瓦片范围比例最大的缓冲区，保证各图层缓冲区内的要素都被读取，
+// 并放大bufferScale倍
+func (m *Tiler) queryTile(task *tileTask, bufferScale uint64) *Tile {
+	ratio := bufferRatio(m.config.TileBuffer, m.config.TileExtent)
+	for name := range m.config.LayerOptions {
+		settings, _ := m.layerSettings(name, task.z)
+		ratio = max(ratio, bufferRatio(settings.buffer, settings.extent))
+	}
+	ratio *= float64(bufferScale)
+	return NewTileInMatrixSet(m.config.TileMatrixSet, task.z, task.x, task.y, ratio*DefaultExtent, DefaultExtent, DefaultEpislon)
+}
 
-			// 几何裁剪
-			pbb, _ := t.PixelBufferedBounds()
-			clipRegion := gen.NewExtent([]float64{pbb[0], pbb[1]}, []float64{pbb[2], pbb[3]})
-			if cleaned, err := validate.CleanGeometry(m.ctx, geom, clipRegion); err == nil {
-				geom = cleaned
-			}
+// newTile 返回任务对应的默认选项瓦片
+func (m *Tiler) newTile(task *tileTask) *Tile {
+	return NewTileInMatrixSet(m.config.TileMatrixSet, task.z, task.x, task.y, DefaultTileBuffer, DefaultExtent, DefaultEpislon)
+}
+
+// checkMatrixSet 检查TileMatrixSet是否包含生成的所有级别，以及自底向上和超级别生成需要的四叉树结构
+func (m *Tiler) checkMatrixSet(zooms []int) error {
+	set := m.config.TileMatrixSet
+	if set == nil {
+		return nil
+	}
+	maxZoom := 0
+	for _, z := range zooms {
+		maxZoom = max(maxZoom, z)
+	}
+	if m.overzoom != nil {
+		maxZoom = max(maxZoom, int(m.overzoom.max))
+	}
+	return set.check(uint32(maxZoom), m.config.BottomUp || m.overzoom != nil)
+}
+
+// gridSRID 返回瓦片网格的坐标系
+func (m *Tiler) gridSRID() uint64 {
+	if set := m.config.TileMatrixSet; set != nil {
+		return set.SRID
+	}
+	return util.WebMercator
+}
+
+// bufferRatio 返回缓冲区占瓦片范围的比例
+func bufferRatio(buffer, extent uint64) float64 {
+	if extent == 0 {
+		return 0
+	}
+	return float64(buffer) / float64(extent)
+}
+
+// streamingProvider 返回读取数据使用的StreamingProvider
+// 优先使用Config.StreamingProvider，其次使用Provider自身的实现
+func (m *Tiler) streamingProvider() StreamingProvider {
+	if m.config.StreamingProvider != nil {
+		return m.config.StreamingProvider
+	}
+	if sp, ok := m.config.Provider.(StreamingProvider); ok {
+		return sp
+	}
+	return NewStreamingAdapter(m.config.Provider)
+}
+
+// layerBuilder 处理Provider回调的要素并按图层汇总
+type layerBuilder struct {
+	m      *Tiler
+	task   *tileTask
+	timer  stageTimer
+	filter layerFilter
+	// bufferScale 图层缓冲区的放大倍数
+	bufferScale uint64
+	srid        uint64
+	// grid 瓦片网格的坐标系，要素转换到该坐标系后处理
+	grid   uint64
+	states map[string]*layerState
+	order  []*layerState
+	// busy 处理要素的累计耗时
+	busy time.Duration
+}
+
+// layerState 单个图层的处理状态
+type layerState struct {
+	visible  bool
+	settings layerSettings
+	keep     func(*geom.Feature) bool
+	tile     *Tile
+	clip     *gen.Extent
+	layer    *Layer
+	// count 已读取的要素数，用作错误报告中的要素序号
+	count int
+}
+
+// state 返回图层的处理状态，首次出现时创建
+func (b *layerBuilder) state(name string) *layerState {
+	if s, ok := b.states[name]; ok {
+		return s
+	}
 
-			feature.Geometry = geom
-			newLayer.Features = append(newLayer.Features, feature)
+	s := &layerState{layer: &Layer{Name: name}}
+	s.settings, s.keep, s.visible = b.filter(name)
+	s.settings.buffer *= b.bufferScale
+	if s.visible {
+		s.tile = NewTileInMatrixSet(b.m.config.TileMatrixSet, b.task.z, b.task.x, b.task.y,
+			float64(s.settings.buffer), float64(s.settings.extent), s.settings.tolerance)
+		pbb, _ := s.tile.PixelBufferedBounds()
+		s.clip = gen.NewExtent([]float64{pbb[0], pbb[1]}, []float64{pbb[2], pbb[3]})
+		s.layer.Extent = s.settings.extent
+	}
+	b.states[name] = s
+	b.order = append(b.order, s)
+	return s
+}
+
+// add 处理一个要素，作为StreamingProvider.Features的回调
+func (b *layerBuilder) add(name string, feature *geom.Feature) error {
+	start := time.Now()
+	defer func() { b.busy += time.Since(start) }()
+
+	s := b.state(name)
+	index := s.count
+	s.count++
+	// 过滤不需要输出的要素
+	if !s.visible || feature == nil || !s.keep(feature) {
+		return nil
+	}
+
+	m := b.m
+	geom := feature.Geometry
+
+	// 坐标转换
+	if b.srid != b.grid {
+		var err error
+		start := time.Now()
+		geom, err = basic.Reproject(b.srid, b.grid, geom)
+		b.timer.since(StageReproject, start)
+		if err != nil {
+			m.reportError(newFeatureError(b.task, name, index, StageReproject, err))
+			if m.config.ErrorPolicy == ErrorPolicySkipFeature {
+				return nil
+			}
+			return errAbortTile
 		}
+	}
+
+	// 几何简化
+	if b.task.z < uint32(m.config.SimplificationMaxZoom) && s.settings.simplify {
+		start := time.Now()
+		geom = simplify.SimplifyGeometry(geom, s.tile.ZEpislon())
+		b.timer.since(StageSimplify, start)
+	}
+
+	// 几何预处理
+	start = time.Now()
+	geom = PrepareGeo(geom, s.tile.extent, float64(s.settings.extent))
+	b.timer.since(StagePrepare, start)
 
-		if len(newLayer.Features) > 0 {
-			resultLayers = append(resultLayers, newLayer)
+	// 几何裁剪
+	start = time.Now()
+	if cleaned, err := validate.CleanGeometry(m.ctx, geom, s.clip); err == nil {
+		geom = cleaned
+	}
+	b.timer.since(StageClean, start)
+
+	// 复制要素，避免修改Provider持有的原始数据
+	nf := *feature
+	nf.Geometry = geom
+	s.layer.Features = append(s.layer.Features, &nf)
+	return nil
+}
+
+// layers 按图层首次出现的顺序返回有要素的图层
+func (b *layerBuilder) layers() []*Layer {
+	var result []*Layer
+	for _, s := range b.order {
+		if len(s.layer.Features) > 0 {
+			result = append(result, s.layer)
 		}
 	}
+	return result
+}
 
-	// 导出瓦片
-	if len(resultLayers) > 0 {
-		if err := m.exportTile(resultLayers, t); err != nil {
-			m.reportError(fmt.Errorf("导出瓦片失败 (z=%d, x=%d, y=%d): %w",
-				task.z, task.x, task.y, err))
+// finishEmptyTile 完成没有数据的瓦片，重新生成时删除已导出的旧瓦片
+func (m *Tiler) finishEmptyTile(task *tileTask, t *Tile) {
+	if m.retiling {
+		if err := m.removeTile(t); err != nil {
+			m.reportError(newTileError(task, StageRemove, err))
+			return
 		}
 	}
+	m.report.addEmptyTile(task.z)
+	m.markDone(task)
 }
 
-// 导出瓦片
-func (m *Tiler) exportTile(layers []*Layer, t *Tile) error {
-	exporter := m.config.Exporter
-	if exporter == nil {
-		exporter = DefaultExporter
+// markDone 将任务写入断点记录
+func (m *Tiler) markDone(task *tileTask) {
+	if err := m.checkpoint.Mark(task); err != nil && m.config.Progress != nil {
+		m.config.Progress.Warn("记录断点失败 (z=%d, x=%d, y=%d): %v", task.z, task.x, task.y, err)
 	}
+}
 
+// tilePath 返回使用的导出器和瓦片的完整路径，没有导出器时返回nil
+func (m *Tiler) tilePath(t *Tile) (Exporter, string) {
+	exporter := m.exporter()
 	if exporter == nil {
-		return nil // 没有导出器配置
+		return nil, ""
 	}
 
 	path := exporter.RelativeTilePath(int(t.Z), int(t.X), int(t.Y))
-	fullPath := filepath.Join(m.config.OutputDir, path)
+	return exporter, filepath.Join(m.config.OutputDir, path)
+}
+
+// 导出瓦片，目录由导出器创建
+// 返回编码后的字节数，导出器未实现SizedExporter时为0
+func (m *Tiler) exportTile(layers []*Layer, t *Tile) (int64, error) {
+	exporter, fullPath := m.tilePath(t)
+	if exporter == nil {
+		return 0, nil // 没有导出器配置
+	}
+	if sized, ok := exporter.(SizedExporter); ok {
+		return sized.SaveTileSize(layers, t, fullPath)
+	}
+	return 0, exporter.SaveTile(layers, t, fullPath)
+}
+
+// exporter 返回使用的导出器，未配置时使用DefaultExporter
+func (m *Tiler) exporter() Exporter {
+	if m.config.Exporter != nil {
+		return m.config.Exporter
+	}
+	return DefaultExporter
+}
 
-	// 确保目录存在
-	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
-		return fmt.Errorf("创建目录失败: %w", err)
+// tilesetMetadata 由配置生成瓦片集元数据，范围为Config.Bound对应的经纬度范围
+func (m *Tiler) tilesetMetadata() TilesetMetadata {
+	meta := TilesetMetadata{TileMatrixSet: m.config.TileMatrixSet, Incremental: m.config.Resume || m.retiling}
+	if exporter := m.exporter(); exporter != nil {
+		meta.Format = exporter.Extension()
 	}
 
-	return exporter.SaveTile(layers, t, fullPath)
+	zooms := m.getZoomLevels()
+	for i, z := range zooms {
+		if i == 0 || uint32(z) < meta.MinZoom {
+			meta.MinZoom = uint32(z)
+		}
+		meta.MaxZoom = max(meta.MaxZoom, uint32(z))
+	}
+	if m.overzoom != nil {
+		meta.MaxZoom = max(meta.MaxZoom, m.overzoom.max)
+	}
+
+	meta.Bounds = [4]float64{-180, -webmercator.MAX_LATITUDE, 180, webmercator.MAX_LATITUDE}
+	if set := m.config.TileMatrixSet; set != nil {
+		if b, err := transformBounds(set.SRID, util.WGS84, set.Bounds()); err == nil {
+			meta.Bounds = b
+		}
+	}
+	if srid, ok := srsSRID(m.config.SRS); ok {
+		if b, err := transformBounds(srid, util.WGS84, *m.config.Bound); err == nil {
+			meta.Bounds = [4]float64{
+				max(b[0], meta.Bounds[0]), max(b[1], meta.Bounds[1]),
+				min(b[2], meta.Bounds[2]), min(b[3], meta.Bounds[3]),
+			}
+		}
+	}
+	return meta
+}
+
+// finalizeExporter 导出器实现Finalizer时在全部瓦片导出后调用Finalize
+func (m *Tiler) finalizeExporter() error {
+	finalizer, ok := m.exporter().(Finalizer)
+	if !ok {
+		return nil
+	}
+	return finalizer.Finalize()
+}
+
+// removeTile 通过导出器删除已导出的瓦片，导出器不支持删除时忽略
+func (m *Tiler) removeTile(t *Tile) error {
+	exporter, path := m.tilePath(t)
+	remover, ok := exporter.(TileRemover)
+	if !ok {
+		return nil
+	}
+	return remover.RemoveTile(t, path)
 }
 
 // reportError 报告错误
-func (m *Tiler) reportError(err error) {
+// FailFast策略下记录第一个错误并停止生成，其他策略下收集错误继续运行
+func (m *Tiler) reportError(err *TileError) {
+	if m.config.ErrorPolicy != ErrorPolicyFailFast {
+		m.tileErrorsMu.Lock()
+		m.tileErrors = append(m.tileErrors, err)
+		m.tileErrorsMu.Unlock()
+		if m.config.Progress != nil {
+			m.config.Progress.Warn("跳过错误: %v", err)
+		}
+		return
+	}
+
 	select {
 	case m.errChan <- err:
 	default:
@@ -351,3 +798,33 @@ func (m *Tiler) reportError(err error) {
 	default:
 	}
 }
+
+// errorReport 返回收集到的错误报告，没有错误时返回nil
+func (m *Tiler) errorReport() *ErrorReport {
+	m.tileErrorsMu.Lock()
+	defer m.tileErrorsMu.Unlock()
+
+	if len(m.tileErrors) == 0 {
+		return nil
+	}
+
+	errs := make([]*TileError, len(m.tileErrors))
+	copy(errs, m.tileErrors)
+	sort.SliceStable(errs, func(i, j int) bool {
+		a, b := errs[i], errs[j]
+		if a.Z != b.Z {
+			return a.Z < b.Z
+		}
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		if a.Layer != b.Layer {
+			return a.Layer < b.Layer
+		}
+		return a.Feature < b.Feature
+	})
+	return &ErrorReport{Errors: errs}
+}
@@ -468,7 +468,7 @@ func TestTiler_exportTile(t *testing.T) {
 	tile := NewTile(1, 2, 3)
 
 	// 测试导出
-	err := tiler.exportTile([]*Layer{layer}, tile)
+	_, err := tiler.exportTile([]*Layer{layer}, tile)
 	if err != nil {
 		t.Errorf("exportTile() 错误 = %v", err)
 	}
@@ -501,7 +501,7 @@ func TestTiler_exportTile_NoExporter(t *testing.T) {
 	tile := NewTile(0, 0, 0)
 
 	// 无导出器时应该正常返回而不报错
-	err := tiler.exportTile([]*Layer{layer}, tile)
+	_, err := tiler.exportTile([]*Layer{layer}, tile)
 	if err != nil {
 		t.Errorf("无导出器时exportTile() 不应该报错，但得到: %v", err)
 	}
@@ -0,0 +1,240 @@
+package tile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	geom "github.com/flywave/go-geom"
+	"github.com/flywave/go-vector-tiler/util"
+)
+
+// TilesetOptions TilesetProvider选项
+type TilesetOptions struct {
+	// Extension 瓦片文件扩展名(默认mvt)
+	Extension string
+	// Layers 只读取这些图层，为空时读取全部图层
+	Layers []string
+}
+
+// TilesetProvider 以已有的{z}/{x}/{y}.mvt瓦片目录(如MVTExporter的输出)作为数据源
+// 查询瓦片时解码对应的源瓦片，超出源瓦片集最大级别时解码最大级别的祖先瓦片，
+// 带缓冲区的范围超出该源瓦片时同时解码相交的相邻源瓦片，要素坐标为Web墨卡托。多个目录中同名的图层合并为一个图层，可用于改变瓦片范围或缓冲区后
+// 重新生成、筛选图层以及按图层合并多个瓦片集。gzip压缩的瓦片自动解压，只支持Web墨卡托网格
+type TilesetProvider struct {
+	tilesets []*tileset
+	ext      string
+	layers   map[string]bool
+}
+
+// tileset 一个瓦片目录
+type tileset struct {
+	dir     string
+	minZoom uint32
+	maxZoom uint32
+}
+
+// NewTilesetProvider 由一个或多个瓦片目录创建Provider
+func NewTilesetProvider(dirs ...string) (*TilesetProvider, error) {
+	return NewTilesetProviderWithOptions(TilesetOptions{}, dirs...)
+}
+
+// NewTilesetProviderWithOptions 使用自定义选项由瓦片目录创建Provider
+// 各目录的级别范围由其下的数字子目录确定
+func NewTilesetProviderWithOptions(options TilesetOptions, dirs ...string) (*TilesetProvider, error) {
+	if len(dirs) == 0 {
+		return nil, ErrInvalidPath
+	}
+	p := &TilesetProvider{ext: strings.TrimPrefix(options.Extension, ".")}
+	if p.ext == "" {
+		p.ext = NewMVTExporter().Extension()
+	}
+	if len(options.Layers) > 0 {
+		p.layers = make(map[string]bool, len(options.Layers))
+		for _, name := range options.Layers {
+			p.layers[name] = true
+		}
+	}
+
+	for _, dir := range dirs {
+		ts, err := openTileset(dir)
+		if err != nil {
+			return nil, err
+		}
+		p.tilesets = append(p.tilesets, ts)
+	}
+	return p, nil
+}
+
+// openTileset 读取瓦片目录的级别范围
+func openTileset(dir string) (*tileset, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取瓦片目录失败: %w", err)
+	}
+	ts := &tileset{dir: dir}
+	found := false
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		z, err := strconv.ParseUint(e.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		if !found || uint32(z) < ts.minZoom {
+			ts.minZoom = uint32(z)
+		}
+		ts.maxZoom = max(ts.maxZoom, uint32(z))
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrEmptyTileset, dir)
+	}
+	return ts, nil
+}
+
+// GetSrid 返回坐标的空间参考(Web墨卡托)
+func (p *TilesetProvider) GetSrid() uint64 {
+	return util.WebMercator
+}
+
+// ZoomRange 返回所有瓦片目录的最小和最大级别
+func (p *TilesetProvider) ZoomRange() (uint32, uint32) {
+	minZoom, maxZoom := p.tilesets[0].minZoom, p.tilesets[0].maxZoom
+	for _, ts := range p.tilesets[1:] {
+		minZoom, maxZoom = min(minZoom, ts.minZoom), max(maxZoom, ts.maxZoom)
+	}
+	return minZoom, maxZoom
+}
+
+// GetDataByTile 返回瓦片对应的源瓦片中的图层，读取出错时返回nil
+func (p *TilesetProvider) GetDataByTile(t *Tile) []*Layer {
+	layers, _ := CollectLayers(context.Background(), p, t)
+	return layers
+}
+
+// Features 依次回调各目录中源瓦片的要素
+// 级别小于目录最小级别时该目录没有数据，大于最大级别时读取最大级别的祖先瓦片。
+// 相邻源瓦片中只回调与带缓冲区范围相交的要素，跨越源瓦片边界的要素按图层和要素ID去重，
+// 没有ID的要素与包含查询瓦片的源瓦片相交时视为已由该源瓦片给出
+func (p *TilesetProvider) Features(ctx context.Context, t *Tile, fn func(layer string, f *geom.Feature) error) error {
+	if t == nil {
+		return ErrInvalidTile
+	}
+	if t.MatrixSet() != WebMercatorQuad {
+		return ErrNotWebMercator
+	}
+	box, err := tileBoundsInSRID(t, util.WebMercator)
+	if err != nil {
+		return err
+	}
+	for _, ts := range p.tilesets {
+		if t.Z < ts.minZoom {
+			continue
+		}
+		srcs := sourceTiles(t, min(t.Z, ts.maxZoom))
+		ext := srcs[0].GetExtent()
+		inner := [4]float64{min(ext.MinX(), ext.MaxX()), min(ext.MinY(), ext.MaxY()), max(ext.MinX(), ext.MaxX()), max(ext.MinY(), ext.MaxY())}
+
+		type featureKey struct {
+			layer string
+			id    interface{}
+		}
+		seen := make(map[featureKey]bool)
+		for i, src := range srcs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			layers, err := p.readTile(ts, src)
+			if err != nil {
+				return err
+			}
+			for _, layer := range layers {
+				if p.layers != nil && !p.layers[layer.Name] {
+					continue
+				}
+				for _, f := range layer.Features {
+					if f.Geometry == nil {
+						continue
+					}
+					key := featureKey{layer.Name, f.ID}
+					if i > 0 {
+						bounds, ok, err := geometryBounds(f.Geometry)
+						if err != nil {
+							return err
+						}
+						if !ok || !boxesIntersect(bounds, box) || seen[key] || (f.ID == nil && boxesIntersect(bounds, inner)) {
+							continue
+						}
+					}
+					if f.ID != nil {
+						seen[key] = true
+					}
+					if err := fn(layer.Name, f); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// sourceTiles 返回级别z中与带缓冲区的瓦片t相交的源瓦片，第一个为包含t的瓦片
+func sourceTiles(t *Tile, z uint32) []*Tile {
+	shift := t.Z - z
+	srcs := []*Tile{NewTile(z, t.X>>shift, t.Y>>shift)}
+	if t.Extent <= 0 || t.Buffer <= 0 {
+		return srcs
+	}
+
+	// 以级别z的瓦片为单位计算带缓冲区的范围
+	size := 1 / float64(uint64(1)<<shift)
+	margin := size * t.Buffer / t.Extent
+	last := float64(uint64(1)<<z) - 1
+	minX, maxX := max(math.Floor(float64(t.X)*size-margin), 0), min(math.Ceil(float64(t.X+1)*size+margin)-1, last)
+	minY, maxY := max(math.Floor(float64(t.Y)*size-margin), 0), min(math.Ceil(float64(t.Y+1)*size+margin)-1, last)
+	for y := uint32(minY); y <= uint32(maxY); y++ {
+		for x := uint32(minX); x <= uint32(maxX); x++ {
+			if x != srcs[0].X || y != srcs[0].Y {
+				srcs = append(srcs, NewTile(z, x, y))
+			}
+		}
+	}
+	return srcs
+}
+
+// readTile 读取并解码源瓦片，瓦片文件不存在时返回nil
+func (p *TilesetProvider) readTile(ts *tileset, t *Tile) ([]*Layer, error) {
+	path := filepath.Join(ts.dir, strconv.FormatUint(uint64(t.Z), 10), strconv.FormatUint(uint64(t.X), 10),
+		strconv.FormatUint(uint64(t.Y), 10)+"."+p.ext)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取瓦片失败: %w", err)
+	}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("瓦片 %s: %w: %v", path, ErrInvalidMVT, err)
+		}
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("瓦片 %s: %w: %v", path, ErrInvalidMVT, err)
+		}
+	}
+	layers, err := DecodeMVTWithOptions(data, t, MVTDecodeOptions{SRID: util.WebMercator})
+	if err != nil {
+		return nil, fmt.Errorf("瓦片 %s: %w", path, err)
+	}
+	return layers, nil
+}
@@ -0,0 +1,279 @@
+package tile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	geom "github.com/flywave/go-geom"
+)
+
+// writeTestTile 在瓦片目录中写入一个瓦片文件
+func writeTestTile(t *testing.T, dir string, z, x, y uint32, data []byte) {
+	t.Helper()
+	path := filepath.Join(dir, strconv.Itoa(int(z)), strconv.Itoa(int(x)), strconv.Itoa(int(y))+".mvt")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// roadsTile 返回包含一条道路的MVT数据
+func roadsTile(name string) []byte {
+	return mvtTestLayer("roads", 4096, []string{"name"}, [][]byte{pbBuilder(nil).bytes(1, []byte(name))},
+		[]mvtTestFeature{{typ: mvtLineString, parts: [][][2]int64{{{1024, 2048}, {3072, 2048}}}, tags: []uint64{0, 0}}})
+}
+
+// collectTilesetLayers 返回瓦片的图层名及各图层的要素数
+func collectTilesetLayers(t *testing.T, p *TilesetProvider, tile *Tile) map[string]int {
+	t.Helper()
+	layers, err := CollectLayers(context.Background(), p, tile)
+	if err != nil {
+		t.Fatalf("CollectLayers(%s) 错误 = %v", tile.ToString(), err)
+	}
+	got := make(map[string]int)
+	for _, l := range layers {
+		got[l.Name] = len(l.Features)
+	}
+	return got
+}
+
+// TestTilesetProvider 测试读取瓦片目录及超出最大级别时读取祖先瓦片
+func TestTilesetProvider(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTile(t, dir, 0, 0, 0, roadsTile("z0"))
+	writeTestTile(t, dir, 1, 0, 0, roadsTile("z1"))
+
+	p, err := NewTilesetProvider(dir)
+	if err != nil {
+		t.Fatalf("NewTilesetProvider() 错误 = %v", err)
+	}
+	if p.GetSrid() != 3857 {
+		t.Errorf("GetSrid() = %d, want 3857", p.GetSrid())
+	}
+	if minZoom, maxZoom := p.ZoomRange(); minZoom != 0 || maxZoom != 1 {
+		t.Errorf("ZoomRange() = %d-%d, want 0-1", minZoom, maxZoom)
+	}
+
+	layers := p.GetDataByTile(NewTile(1, 0, 0))
+	if len(layers) != 1 || layers[0].Name != "roads" || len(layers[0].Features) != 1 {
+		t.Fatalf("GetDataByTile() = %+v", layers)
+	}
+	f := layers[0].Features[0]
+	if f.Properties["name"] != "z1" {
+		t.Errorf("属性 = %v, want name=z1", f.Properties)
+	}
+	// 瓦片1/0/0位于西北象限
+	line := geometryData(f.Geometry).([][]float64)
+	if len(line) != 2 || line[0][0] >= 0 || line[0][1] <= 0 || line[0][0] >= line[1][0] {
+		t.Errorf("坐标 = %v", line)
+	}
+
+	// 超出最大级别时读取级别1的祖先瓦片
+	child := p.GetDataByTile(NewTile(3, 1, 2))
+	if len(child) != 1 || child[0].Features[0].Properties["name"] != "z1" {
+		t.Fatalf("祖先瓦片 = %+v", child)
+	}
+	if got := geometryData(child[0].Features[0].Geometry); !reflect.DeepEqual(got, line) {
+		t.Errorf("祖先瓦片坐标 = %v, want %v", got, line)
+	}
+
+	// 级别不超过最大级别时不读取祖先瓦片
+	if got := p.GetDataByTile(NewTile(1, 1, 1)); len(got) != 0 {
+		t.Errorf("缺失瓦片 = %+v, want 空", got)
+	}
+	if got := p.GetDataByTile(NewTile(3, 7, 7)); len(got) != 0 {
+		t.Errorf("缺失祖先瓦片 = %+v, want 空", got)
+	}
+}
+
+// TestTilesetProvider_Merge 测试多个瓦片目录按图层合并及图层筛选
+func TestTilesetProvider_Merge(t *testing.T) {
+	roads, water := t.TempDir(), t.TempDir()
+	writeTestTile(t, roads, 0, 0, 0, roadsTile("a"))
+	writeTestTile(t, roads, 1, 0, 0, roadsTile("b"))
+	writeTestTile(t, water, 1, 0, 0, append(roadsTile("c"), mvtTestLayer("water", 4096, nil, nil, []mvtTestFeature{
+		{typ: mvtPolygon, parts: [][][2]int64{{{0, 0}, {4096, 0}, {4096, 4096}, {0, 4096}}}},
+	})...))
+
+	p, err := NewTilesetProvider(roads, water)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minZoom, maxZoom := p.ZoomRange(); minZoom != 0 || maxZoom != 1 {
+		t.Errorf("ZoomRange() = %d-%d, want 0-1", minZoom, maxZoom)
+	}
+	if got, want := collectTilesetLayers(t, p, NewTile(1, 0, 0)), map[string]int{"roads": 2, "water": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("合并图层 = %v, want %v", got, want)
+	}
+	// 级别0低于第二个目录的最小级别
+	if got, want := collectTilesetLayers(t, p, NewTile(0, 0, 0)), map[string]int{"roads": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("级别0图层 = %v, want %v", got, want)
+	}
+	if got, want := collectTilesetLayers(t, p, NewTile(2, 1, 1)), map[string]int{"roads": 2, "water": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("祖先瓦片图层 = %v, want %v", got, want)
+	}
+
+	p, err = NewTilesetProviderWithOptions(TilesetOptions{Layers: []string{"water"}}, roads, water)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := collectTilesetLayers(t, p, NewTile(1, 0, 0)), map[string]int{"water": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("筛选图层 = %v, want %v", got, want)
+	}
+}
+
+// TestTilesetProvider_Neighbors 测试缓冲区超出源瓦片时读取相邻源瓦片
+func TestTilesetProvider_Neighbors(t *testing.T) {
+	type named struct {
+		name  string
+		id    uint64
+		typ   uint64
+		parts [][][2]int64
+	}
+	tile := func(features ...named) []byte {
+		var values [][]byte
+		var fs []mvtTestFeature
+		for i, f := range features {
+			values = append(values, pbBuilder(nil).bytes(1, []byte(f.name)))
+			fs = append(fs, mvtTestFeature{id: f.id, typ: f.typ, parts: f.parts, tags: []uint64{0, uint64(i)}})
+		}
+		return mvtTestLayer("roads", 4096, []string{"name"}, values, fs)
+	}
+	line := func(x0, y0, x1, y1 int64) [][][2]int64 { return [][][2]int64{{{x0, y0}, {x1, y1}}} }
+
+	dir := t.TempDir()
+	// 跨越1/0/0与1/1/0边界的道路在两个源瓦片中都有(带源瓦片缓冲区)
+	writeTestTile(t, dir, 1, 0, 0, tile(named{"cross", 1, mvtLineString, line(3000, 2048, 4200, 2048)}))
+	writeTestTile(t, dir, 1, 1, 0, tile(
+		named{"cross", 1, mvtLineString, line(-1096, 2048, 100, 2048)},
+		named{"near", 2, mvtLineString, line(200, 1000, 300, 1000)},
+		named{"far", 3, mvtLineString, line(3800, 1000, 3900, 1000)},
+		named{"near-noid", 0, mvtPoint, [][][2]int64{{{300, 3000}}}},
+		named{"cross-noid", 0, mvtLineString, line(-100, 3000, 500, 3000)},
+	))
+	writeTestTile(t, dir, 1, 0, 1, tile(named{"below", 4, mvtPoint, [][][2]int64{{{2048, 100}}}}))
+
+	p, err := NewTilesetProvider(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := func(query *Tile) map[string]int {
+		got := make(map[string]int)
+		err := p.Features(context.Background(), query, func(layer string, f *geom.Feature) error {
+			got[f.Properties["name"].(string)]++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Features(%s) 错误 = %v", query.ToString(), err)
+		}
+		return got
+	}
+
+	// 默认缓冲区不超出源瓦片的缓冲区
+	if got, want := names(NewTile(1, 0, 0)), map[string]int{"cross": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("默认缓冲区要素 = %v, want %v", got, want)
+	}
+	// 缓冲区为瓦片的1/4时读取相邻源瓦片，相同ID的要素只回调一次
+	query := NewTile(1, 0, 0)
+	query.SetBuffer(query.Extent / 4)
+	if got, want := names(query), map[string]int{"cross": 1, "near": 1, "near-noid": 1, "below": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("扩大缓冲区要素 = %v, want %v", got, want)
+	}
+	// 超出最大级别时只有位于祖先瓦片边缘的瓦片读取相邻源瓦片
+	child := NewTile(2, 1, 0)
+	child.SetBuffer(child.Extent / 4)
+	if got, want := names(child), map[string]int{"cross": 1, "near": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("祖先瓦片要素 = %v, want %v", got, want)
+	}
+	if got := sourceTiles(NewTile(2, 0, 0), 1); len(got) != 1 {
+		t.Errorf("sourceTiles() = %d 个, want 1", len(got))
+	}
+}
+
+// TestTilesetProvider_Gzip 测试读取gzip压缩的瓦片
+func TestTilesetProvider_Gzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(roadsTile("gz"))
+	zw.Close()
+
+	dir := t.TempDir()
+	writeTestTile(t, dir, 2, 1, 1, buf.Bytes())
+	p, err := NewTilesetProvider(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := p.GetDataByTile(NewTile(2, 1, 1))
+	if len(layers) != 1 || layers[0].Features[0].Properties["name"] != "gz" {
+		t.Errorf("GetDataByTile() = %+v", layers)
+	}
+}
+
+// TestTilesetProvider_Errors 测试无效的瓦片目录和瓦片
+func TestTilesetProvider_Errors(t *testing.T) {
+	if _, err := NewTilesetProvider(); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("无目录错误 = %v, want %v", err, ErrInvalidPath)
+	}
+	if _, err := NewTilesetProvider(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("目录不存在时应返回错误")
+	}
+	if _, err := NewTilesetProvider(t.TempDir()); !errors.Is(err, ErrEmptyTileset) {
+		t.Errorf("空目录错误 = %v, want %v", err, ErrEmptyTileset)
+	}
+
+	dir := t.TempDir()
+	writeTestTile(t, dir, 0, 0, 0, []byte{0x1a, 0xff})
+	p, err := NewTilesetProvider(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Features(context.Background(), NewTile(0, 0, 0), nil); !errors.Is(err, ErrInvalidMVT) {
+		t.Errorf("无效瓦片错误 = %v, want %v", err, ErrInvalidMVT)
+	}
+	if layers := p.GetDataByTile(NewTile(0, 0, 0)); layers != nil {
+		t.Errorf("无效瓦片 GetDataByTile() = %+v, want nil", layers)
+	}
+}
+
+// TestTiler_TilesetProvider 测试以瓦片目录为数据源重新生成瓦片
+func TestTiler_TilesetProvider(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTile(t, dir, 1, 0, 0, roadsTile("src"))
+	p, err := NewTilesetProvider(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exporter := &MockExporter{}
+	tiler := NewTiler(&Config{
+		Provider:  p,
+		Exporter:  exporter,
+		OutputDir: t.TempDir(),
+		MinZoom:   1,
+		MaxZoom:   3,
+		Bound:     &[4]float64{-180, 0, 0, 85},
+	})
+	if err := tiler.Tiler(); err != nil {
+		t.Fatalf("Tiler() 错误 = %v", err)
+	}
+	zooms := make(map[uint32]bool)
+	for _, saved := range exporter.SavedTiles {
+		for _, l := range saved.Layers {
+			if l.Name == "roads" {
+				zooms[saved.Tile.Z] = true
+			}
+		}
+	}
+	if !zooms[1] || !zooms[3] {
+		t.Errorf("包含roads图层的级别 = %v, want 1-3", zooms)
+	}
+}
@@ -0,0 +1,115 @@
+package tile
+
+import (
+	"sort"
+	"sync"
+)
+
+// VectorLayer TileJSON中vector_layers的一项，记录图层的属性字段和出现的级别范围
+type VectorLayer struct {
+	ID string `json:"id"`
+	// Fields 属性名到类型(Number、String、Boolean，类型不一致时为Mixed)的映射
+	Fields  map[string]string `json:"fields"`
+	MinZoom uint32            `json:"minzoom"`
+	MaxZoom uint32            `json:"maxzoom"`
+}
+
+// vectorLayerSet 汇总各瓦片中图层的属性字段和级别范围，可并发使用
+type vectorLayerSet struct {
+	mu     sync.Mutex
+	layers map[string]*VectorLayer
+}
+
+// VectorLayers 返回z级瓦片中图层的属性字段，按图层名排序，没有要素的图层不返回
+func VectorLayers(z uint32, layers []*Layer) []VectorLayer {
+	var s vectorLayerSet
+	s.add(z, layers)
+	return s.list()
+}
+
+// add 记录z级瓦片中的图层，没有要素的图层不记录
+func (s *vectorLayerSet) add(z uint32, layers []*Layer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range layers {
+		if l == nil || len(l.Features) == 0 {
+			continue
+		}
+		v := s.layer(l.Name, z, z)
+		for _, f := range l.Features {
+			if f == nil {
+				continue
+			}
+			for k, val := range f.Properties {
+				if typ := fieldType(val); typ != "" {
+					addField(v.Fields, k, typ)
+				}
+			}
+		}
+	}
+}
+
+// merge 合并已有的图层记录，如断点续传前写入的元数据
+func (s *vectorLayerSet) merge(layers []VectorLayer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range layers {
+		v := s.layer(l.ID, l.MinZoom, l.MaxZoom)
+		for k, typ := range l.Fields {
+			addField(v.Fields, k, typ)
+		}
+	}
+}
+
+// layer 返回id对应的记录并扩展级别范围，调用者需持有锁
+func (s *vectorLayerSet) layer(id string, minZoom, maxZoom uint32) *VectorLayer {
+	if s.layers == nil {
+		s.layers = make(map[string]*VectorLayer)
+	}
+	v, ok := s.layers[id]
+	if !ok {
+		v = &VectorLayer{ID: id, Fields: map[string]string{}, MinZoom: minZoom, MaxZoom: maxZoom}
+		s.layers[id] = v
+	}
+	v.MinZoom = min(v.MinZoom, minZoom)
+	v.MaxZoom = max(v.MaxZoom, maxZoom)
+	return v
+}
+
+// list 返回按图层名排序的记录副本
+func (s *vectorLayerSet) list() []VectorLayer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]VectorLayer, 0, len(s.layers))
+	for _, v := range s.layers {
+		fields := make(map[string]string, len(v.Fields))
+		for k, typ := range v.Fields {
+			fields[k] = typ
+		}
+		res = append(res, VectorLayer{ID: v.ID, Fields: fields, MinZoom: v.MinZoom, MaxZoom: v.MaxZoom})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// addField 记录字段类型，与已有类型不一致时记为Mixed
+func addField(fields map[string]string, name, typ string) {
+	if old, ok := fields[name]; ok && old != typ {
+		typ = "Mixed"
+	}
+	fields[name] = typ
+}
+
+// fieldType 返回属性值在TileJSON中的类型，nil值返回空字符串
+func fieldType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return ""
+	case bool:
+		return "Boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return "Number"
+	default:
+		return "String"
+	}
+}